@@ -0,0 +1,6 @@
+// Package storepb holds the protobuf messages and gRPC stubs for the
+// Store service. Regenerate with `go generate ./api/...` (requires buf,
+// protoc-gen-go and protoc-gen-go-grpc on PATH).
+package storepb
+
+//go:generate buf generate
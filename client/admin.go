@@ -0,0 +1,173 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AdminClient is implemented by the HTTP Client for server
+// administration; it needs an admin token.
+type AdminClient interface {
+	Info(ctx context.Context) (*ServerInfo, error)
+	SlowLog(ctx context.Context, count int) (*SlowLog, error)
+	ResetSlowLog(ctx context.Context) error
+	Monitor(ctx context.Context) (<-chan MonitorEvent, error)
+	Namespaces(ctx context.Context) ([]Namespace, error)
+	SetNamespaceTTL(ctx context.Context, ns string, ttl time.Duration) (*Namespace, error)
+	SetNamespaceQuota(ctx context.Context, ns string, q Quota) (*Namespace, error)
+	FlushNamespace(ctx context.Context, ns string) error
+}
+
+// ServerInfo is the response of GET /v1/admin/info.
+type ServerInfo struct {
+	Server struct {
+		Version       string    `json:"version"`
+		GoVersion     string    `json:"go_version"`
+		StartedAt     time.Time `json:"started_at"`
+		UptimeSeconds int64     `json:"uptime_seconds"`
+	} `json:"server"`
+	Keyspace struct {
+		Keys   int            `json:"keys"`
+		ByType map[string]int `json:"by_type"`
+	} `json:"keyspace"`
+	Memory struct {
+		StoreBytes     int64  `json:"store_bytes"`
+		HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+		SysBytes       uint64 `json:"sys_bytes"`
+	} `json:"memory"`
+	Expiry struct {
+		ExpiredKeys         uint64     `json:"expired_keys"`
+		SweepIntervalMS     int64      `json:"sweep_interval_ms"`
+		Sweeps              uint64     `json:"sweeps"`
+		LastSweepAt         *time.Time `json:"last_sweep_at,omitempty"`
+		LastSweepDurationMS float64    `json:"last_sweep_duration_ms"`
+		LastSweepExpired    int        `json:"last_sweep_expired"`
+	} `json:"expiry"`
+	Subscribers struct {
+		Watchers int `json:"watchers"`
+		Webhooks int `json:"webhooks"`
+		Monitors int `json:"monitors"`
+	} `json:"subscribers"`
+	// Limits on request sizes, to check requests before sending them;
+	// 0 is unlimited.
+	Limits struct {
+		MaxBodyBytes  int64 `json:"max_body_bytes"`
+		MaxKeyLength  int   `json:"max_key_length"`
+		MaxValueBytes int   `json:"max_value_bytes"`
+		MaxPushItems  int   `json:"max_push_items"`
+		MaxListLength int   `json:"max_list_length"`
+	} `json:"limits"`
+	Config map[string]string `json:"config"`
+}
+
+// Info fetches server statistics and settings.
+func (c *Client) Info(ctx context.Context) (*ServerInfo, error) {
+	var info ServerInfo
+	if err := c.doRequest(ctx, http.MethodGet, "/v1/admin/info", nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// SlowLog is the response of GET /v1/admin/slowlog.
+type SlowLog struct {
+	ThresholdMS float64        `json:"threshold_ms"`
+	Capacity    int            `json:"capacity"`
+	Len         int            `json:"len"`
+	Entries     []SlowLogEntry `json:"entries"`
+}
+
+// SlowLogEntry is one store operation that exceeded the threshold.
+type SlowLogEntry struct {
+	ID         uint64    `json:"id"`
+	Time       time.Time `json:"time"`
+	DurationMS float64   `json:"duration_ms"`
+	Command    string    `json:"command"`
+	Namespace  string    `json:"namespace"`
+	Key        string    `json:"key,omitempty"`
+	ArgCount   int       `json:"arg_count"`
+	ArgBytes   int       `json:"arg_bytes"`
+	Identity   string    `json:"identity,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// SlowLog fetches the newest count slow log entries; 0 fetches all.
+func (c *Client) SlowLog(ctx context.Context, count int) (*SlowLog, error) {
+	endpoint := "/v1/admin/slowlog"
+	if count > 0 {
+		endpoint = fmt.Sprintf("%s?count=%d", endpoint, count)
+	}
+	var log SlowLog
+	if err := c.doRequest(ctx, http.MethodGet, endpoint, nil, &log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// ResetSlowLog empties the slow log.
+func (c *Client) ResetSlowLog(ctx context.Context) error {
+	return c.doRequest(ctx, http.MethodDelete, "/v1/admin/slowlog", nil, nil)
+}
+
+// Namespace describes a namespace in /v1/admin/namespaces.
+type Namespace struct {
+	Name              string         `json:"name"`
+	Keys              int            `json:"keys"`
+	ByType            map[string]int `json:"by_type"`
+	Bytes             int64          `json:"bytes"`
+	DefaultTTLSeconds int            `json:"default_ttl_seconds"` // 0 uses the server default
+	Quota             Quota          `json:"quota"`
+}
+
+// Quota limits what a namespace may hold; 0 is unlimited. Writes beyond
+// it fail with HTTP 507.
+type Quota struct {
+	MaxKeys       int   `json:"max_keys"`
+	MaxBytes      int64 `json:"max_bytes"`
+	MaxListLength int   `json:"max_list_length"`
+}
+
+// Namespaces lists the namespaces the token may use that hold keys or
+// settings.
+func (c *Client) Namespaces(ctx context.Context) ([]Namespace, error) {
+	var out []Namespace
+	if err := c.doRequest(ctx, http.MethodGet, "/v1/admin/namespaces", nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetNamespaceTTL sets the default TTL of keys written to ns without
+// one; 0 restores the server default.
+func (c *Client) SetNamespaceTTL(ctx context.Context, ns string, ttl time.Duration) (*Namespace, error) {
+	seconds := int(ttl.Seconds())
+	return c.putNamespace(ctx, ns, namespaceRequest{DefaultTTLSeconds: &seconds})
+}
+
+// SetNamespaceQuota sets the limits of ns; a 0 limit restores the
+// server's default quota. Existing keys are kept even over the limits.
+func (c *Client) SetNamespaceQuota(ctx context.Context, ns string, q Quota) (*Namespace, error) {
+	return c.putNamespace(ctx, ns, namespaceRequest{
+		MaxKeys:       &q.MaxKeys,
+		MaxBytes:      &q.MaxBytes,
+		MaxListLength: &q.MaxListLength,
+	})
+}
+
+func (c *Client) putNamespace(ctx context.Context, ns string, req namespaceRequest) (*Namespace, error) {
+	var out Namespace
+	endpoint := fmt.Sprintf("/v1/admin/namespaces/%s", url.PathEscape(ns))
+	if err := c.doRequest(ctx, http.MethodPut, endpoint, req, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// FlushNamespace deletes every key of ns.
+func (c *Client) FlushNamespace(ctx context.Context, ns string) error {
+	endpoint := fmt.Sprintf("/v1/admin/namespaces/%s/flush", url.PathEscape(ns))
+	return c.doRequest(ctx, http.MethodPost, endpoint, nil, nil)
+}
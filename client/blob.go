@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// BlobClient is implemented by the HTTP Client for binary values, which
+// are sent raw rather than as JSON strings.
+type BlobClient interface {
+	SetBlob(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error
+	GetBlob(ctx context.Context, key string) (*Blob, error)
+	GetBlobRange(ctx context.Context, key string, offset, length int64) (*Blob, error)
+	DeleteBlob(ctx context.Context, key string) error
+}
+
+// Blob is a binary value and its media type.
+type Blob struct {
+	Data        []byte
+	ContentType string
+	ETag        string // changes whenever the value is written
+}
+
+// SetBlob stores data at key with its media type (empty means
+// application/octet-stream) and an optional TTL (0 = server default).
+func (c *Client) SetBlob(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error {
+	endpoint := fmt.Sprintf("/v1/blob/%s", url.PathEscape(key))
+	if ttl > 0 {
+		endpoint += "?ttl_seconds=" + strconv.Itoa(int(ttl.Seconds()))
+	}
+	header := http.Header{}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header.Set("Content-Type", contentType)
+	resp, err := c.sendRaw(ctx, http.MethodPut, endpoint, data, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// GetBlob fetches the whole value at key.
+func (c *Client) GetBlob(ctx context.Context, key string) (*Blob, error) {
+	return c.GetBlobRange(ctx, key, 0, -1)
+}
+
+// GetBlobRange fetches length bytes of the value at key starting at
+// offset; a negative length reads to the end.
+func (c *Client) GetBlobRange(ctx context.Context, key string, offset, length int64) (*Blob, error) {
+	header := http.Header{}
+	switch {
+	case length == 0:
+		return nil, fmt.Errorf("GetBlobRange: length must not be 0")
+	case length > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	case offset > 0:
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	endpoint := fmt.Sprintf("/v1/blob/%s", url.PathEscape(key))
+	resp, err := c.sendRaw(ctx, http.MethodGet, endpoint, nil, header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return &Blob{Data: data, ContentType: resp.Header.Get("Content-Type"), ETag: resp.Header.Get("ETag")}, nil
+}
+
+// DeleteBlob deletes the value at key.
+func (c *Client) DeleteBlob(ctx context.Context, key string) error {
+	endpoint := fmt.Sprintf("/v1/blob/%s", url.PathEscape(key))
+	return c.doRequest(ctx, http.MethodDelete, endpoint, nil, nil)
+}
+
+// sendRaw performs a request with a non-JSON body and returns the
+// response of a successful call for the caller to read and close.
+func (c *Client) sendRaw(ctx context.Context, method, endpoint string, payload []byte, header http.Header) (*http.Response, error) {
+	ref, _ := url.Parse(endpoint)
+	fullURL := c.baseURL.ResolveReference(ref).String()
+	resp, err := c.send(ctx, method, fullURL, payload, header)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkStatus(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp, nil
+}
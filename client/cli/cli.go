@@ -2,12 +2,19 @@ package cli
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"mime"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
 	"data_storage/client"
+	"data_storage/server/audit"
 )
 
 // CLIArgs holds dynamic inputs from flags.
@@ -19,26 +26,74 @@ type CLIArgs struct {
 	TTLOverride time.Duration
 	Interval    time.Duration
 	Timeout     time.Duration
+	Count       int
+	Namespace   string
+	Quota       client.Quota // namespace-quota limits
+
+	// blob transfers
+	Path        string // file to upload or download to; stdout when empty for blob-get
+	ContentType string
+
+	// audit queries
+	AuditFile string
+	Since     time.Time
+	Until     time.Time
 }
 
 // ParseArgs defines and validates flags.
+// keyOptional lists the actions that work without --key.
+var keyOptional = map[string]bool{
+	"audit": true, "info": true, "slowlog": true, "slowlog-reset": true, "monitor": true,
+	"namespaces": true, "namespace-ttl": true, "namespace-quota": true, "namespace-flush": true,
+}
+
+// namespaceRequired lists the actions that act on --namespace.
+var namespaceRequired = map[string]bool{"namespace-ttl": true, "namespace-quota": true, "namespace-flush": true}
+
+// streaming lists the actions that run until interrupted instead of
+// until --timeout.
+var streaming = map[string]bool{"monitor": true}
+
 func ParseArgs(defaultTTL time.Duration) (*CLIArgs, error) {
-	action := flag.String("action", "", "one of: set|get|del|lpush|rpop")
-	key := flag.String("key", "", "key to operate on")
+	action := flag.String("action", "", "one of: set|get|del|lpush|rpop|audit|info|slowlog|slowlog-reset|monitor|namespaces|namespace-ttl|namespace-quota|namespace-flush|blob-put|blob-get")
+	key := flag.String("key", "", "key to operate on (optional filter for audit)")
 	value := flag.String("value", "", "value for set or single lpush")
 	values := flag.String("values", "", "comma-separated values for lpush")
 	ttl := flag.Duration("ttl", 0, "override TTL (e.g. 30s); omit to use default")
 	interval := flag.Duration("interval", 0, "cleanup interval for background tasks (e.g. 30s)")
 	timeout := flag.Duration("timeout", defaultTTL+5*time.Second, "request timeout")
+	count := flag.Int("count", 0, "slowlog: newest entries to show (0 for all)")
+	namespace := flag.String("namespace", "", "namespace to act on (defaults to STORE_NAMESPACE, else the token's own)")
+	maxKeys := flag.Int("max-keys", 0, "namespace-quota: key limit (0 for the server default)")
+	maxBytes := flag.Int64("max-bytes", 0, "namespace-quota: memory limit in bytes (0 for the server default)")
+	maxListLength := flag.Int("max-list-length", 0, "namespace-quota: list length limit (0 for the server default)")
+	path := flag.String("path", "", "blob-put: file to upload; blob-get: file to write (stdout when omitted)")
+	contentType := flag.String("content-type", "", "blob-put: media type (guessed from --path when omitted)")
+	auditFile := flag.String("file", "", "audit log to query (defaults to AUDIT_FILE)")
+	since := flag.String("since", "", "audit: only records at or after this RFC 3339 time")
+	until := flag.String("until", "", "audit: only records before this RFC 3339 time")
 	flag.Parse()
 
 	if *action == "" {
 		return nil, fmt.Errorf("--action is required")
 	}
-	if *key == "" {
+	if *key == "" && !keyOptional[*action] {
 		return nil, fmt.Errorf("--key is required")
 	}
 
+	var sinceT, untilT time.Time
+	var err error
+	if *since != "" {
+		if sinceT, err = time.Parse(time.RFC3339, *since); err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if *until != "" {
+		if untilT, err = time.Parse(time.RFC3339, *until); err != nil {
+			return nil, fmt.Errorf("invalid --until: %w", err)
+		}
+	}
+
 	var vals []string
 	if *values != "" {
 		vals = strings.Split(*values, ",")
@@ -52,6 +107,14 @@ func ParseArgs(defaultTTL time.Duration) (*CLIArgs, error) {
 		TTLOverride: *ttl,
 		Interval:    *interval,
 		Timeout:     *timeout,
+		Count:       *count,
+		Namespace:   *namespace,
+		Quota:       client.Quota{MaxKeys: *maxKeys, MaxBytes: *maxBytes, MaxListLength: *maxListLength},
+		Path:        *path,
+		ContentType: *contentType,
+		AuditFile:   *auditFile,
+		Since:       sinceT,
+		Until:       untilT,
 	}, nil
 }
 
@@ -72,18 +135,37 @@ type commandFunc func(ctx context.Context, args *CLIArgs) error
 // Run dispatches to the appropriate commandFunc.
 func (cli *CLI) Run(args *CLIArgs) error {
 	cmds := map[string]commandFunc{
-		"set":   cli.runSet,
-		"get":   cli.runGet,
-		"del":   cli.runDelete,
-		"lpush": cli.runLPush,
-		"rpop":  cli.runRPop,
+		"set":             cli.runSet,
+		"get":             cli.runGet,
+		"del":             cli.runDelete,
+		"lpush":           cli.runLPush,
+		"rpop":            cli.runRPop,
+		"audit":           cli.runAudit,
+		"info":            cli.runInfo,
+		"slowlog":         cli.runSlowLog,
+		"slowlog-reset":   cli.runSlowLogReset,
+		"monitor":         cli.runMonitor,
+		"namespaces":      cli.runNamespaces,
+		"namespace-ttl":   cli.runNamespaceTTL,
+		"namespace-quota": cli.runNamespaceQuota,
+		"namespace-flush": cli.runNamespaceFlush,
+		"blob-put":        cli.runBlobPut,
+		"blob-get":        cli.runBlobGet,
 	}
 
 	fn, ok := cmds[args.Action]
+	if ok && namespaceRequired[args.Action] && args.Namespace == "" {
+		return fmt.Errorf("%s needs --namespace or STORE_NAMESPACE", args.Action)
+	}
 	if !ok {
-		return fmt.Errorf("unknown action %q; use set|get|del|lpush|rpop", args.Action)
+		return fmt.Errorf("unknown action %q; use set|get|del|lpush|rpop|audit|info|slowlog|slowlog-reset|monitor|namespaces|namespace-ttl|namespace-quota|namespace-flush|blob-put|blob-get", args.Action)
 	}
 
+	if streaming[args.Action] {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return fn(ctx, args)
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), args.Timeout)
 	defer cancel()
 	return fn(ctx, args)
@@ -128,3 +210,194 @@ func (cli *CLI) runRPop(ctx context.Context, args *CLIArgs) error {
 	fmt.Println(v)
 	return nil
 }
+
+// runBlobPut uploads the file at --path as is, with --content-type or
+// the type its extension suggests.
+func (cli *CLI) runBlobPut(ctx context.Context, args *CLIArgs) error {
+	if args.Path == "" {
+		return fmt.Errorf("--path is required for blob-put")
+	}
+	blobs, err := cli.blobs(args)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(args.Path)
+	if err != nil {
+		return err
+	}
+	contentType := args.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(args.Path))
+	}
+	ttl := args.TTLOverride
+	if ttl == 0 {
+		ttl = cli.defaultTTL
+	}
+	return blobs.SetBlob(ctx, args.Key, data, contentType, ttl)
+}
+
+// runBlobGet writes the value at --key to --path, or to stdout.
+func (cli *CLI) runBlobGet(ctx context.Context, args *CLIArgs) error {
+	blobs, err := cli.blobs(args)
+	if err != nil {
+		return err
+	}
+	blob, err := blobs.GetBlob(ctx, args.Key)
+	if err != nil {
+		return err
+	}
+	if args.Path == "" {
+		_, err = os.Stdout.Write(blob.Data)
+		return err
+	}
+	return os.WriteFile(args.Path, blob.Data, 0o644)
+}
+
+// runAudit prints matching audit records, one JSON object per line. It
+// reads the server's audit files directly, so it runs on the server host.
+func (cli *CLI) runAudit(ctx context.Context, args *CLIArgs) error {
+	if args.AuditFile == "" {
+		return fmt.Errorf("--file (or AUDIT_FILE) is required for audit")
+	}
+	records, err := audit.Query(args.AuditFile, audit.Filter{Key: args.Key, Since: args.Since, Until: args.Until})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, rec := range records {
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cli *CLI) runInfo(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	info, err := admin.Info(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(info)
+}
+
+func (cli *CLI) runSlowLog(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	log, err := admin.SlowLog(ctx, args.Count)
+	if err != nil {
+		return err
+	}
+	return printJSON(log)
+}
+
+func (cli *CLI) runSlowLogReset(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	return admin.ResetSlowLog(ctx)
+}
+
+// runMonitor prints every command the server executes, one JSON object
+// per line, until interrupted.
+func (cli *CLI) runMonitor(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	events, err := admin.Monitor(ctx)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for ev := range events {
+		if ev.Dropped > 0 {
+			fmt.Fprintf(os.Stderr, "monitor: %d commands dropped\n", ev.Dropped)
+		}
+		if err := enc.Encode(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cli *CLI) runNamespaces(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	namespaces, err := admin.Namespaces(ctx)
+	if err != nil {
+		return err
+	}
+	return printJSON(namespaces)
+}
+
+// runNamespaceTTL sets the namespace's default TTL to --ttl; omitting
+// --ttl restores the server default.
+func (cli *CLI) runNamespaceTTL(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	ns, err := admin.SetNamespaceTTL(ctx, args.Namespace, args.TTLOverride)
+	if err != nil {
+		return err
+	}
+	return printJSON(ns)
+}
+
+// runNamespaceQuota sets the namespace's limits to --max-keys,
+// --max-bytes and --max-list-length; omitted limits restore the server
+// default.
+func (cli *CLI) runNamespaceQuota(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	ns, err := admin.SetNamespaceQuota(ctx, args.Namespace, args.Quota)
+	if err != nil {
+		return err
+	}
+	return printJSON(ns)
+}
+
+func (cli *CLI) runNamespaceFlush(ctx context.Context, args *CLIArgs) error {
+	admin, err := cli.admin(args)
+	if err != nil {
+		return err
+	}
+	return admin.FlushNamespace(ctx, args.Namespace)
+}
+
+// admin returns the store as an AdminClient, which only the HTTP client
+// implements.
+func (cli *CLI) admin(args *CLIArgs) (client.AdminClient, error) {
+	admin, ok := cli.store.(client.AdminClient)
+	if !ok {
+		return nil, fmt.Errorf("%s is only available over HTTP", args.Action)
+	}
+	return admin, nil
+}
+
+// blobs returns the store as a BlobClient, which only the HTTP client
+// implements.
+func (cli *CLI) blobs(args *CLIArgs) (client.BlobClient, error) {
+	blobs, ok := cli.store.(client.BlobClient)
+	if !ok {
+		return nil, fmt.Errorf("%s is only available over HTTP", args.Action)
+	}
+	return blobs, nil
+}
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -6,11 +6,14 @@ import (
 	"flag"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
+	"data_storage/client"
 	"data_storage/client/cli"
+	"data_storage/server/audit"
 )
 
 // stubStoreClient implements client.StoreClient for testing.
@@ -27,6 +30,12 @@ type stubStoreClient struct {
 	lpushItems  []string
 	rpopValue   string
 	rpopCalled  bool
+	slowCount   int
+	slowReset   bool
+	nsTTL       time.Duration
+	nsQuota     client.Quota
+	nsFlushed   string
+	blob        client.Blob
 }
 
 func (s *stubStoreClient) SetString(ctx context.Context, key, value string, ttl time.Duration) error {
@@ -59,6 +68,70 @@ func (s *stubStoreClient) RPop(ctx context.Context, key string) (string, error)
 	return s.rpopValue, nil
 }
 
+func (s *stubStoreClient) Watch(ctx context.Context, pattern string) (<-chan client.Event, error) {
+	return nil, nil
+}
+
+func (s *stubStoreClient) Info(ctx context.Context) (*client.ServerInfo, error) {
+	info := &client.ServerInfo{}
+	info.Server.Version = "v1.2.3"
+	info.Keyspace.Keys = 7
+	return info, nil
+}
+
+func (s *stubStoreClient) SlowLog(ctx context.Context, count int) (*client.SlowLog, error) {
+	s.slowCount = count
+	return &client.SlowLog{Len: 1, Entries: []client.SlowLogEntry{{ID: 1, Command: "LPush", Key: "big", ArgCount: 5000}}}, nil
+}
+
+func (s *stubStoreClient) ResetSlowLog(ctx context.Context) error {
+	s.slowReset = true
+	return nil
+}
+
+func (s *stubStoreClient) Monitor(ctx context.Context) (<-chan client.MonitorEvent, error) {
+	out := make(chan client.MonitorEvent, 1)
+	out <- client.MonitorEvent{Command: "SetString", Key: "foo", Args: []string{"bar"}, ArgCount: 1}
+	close(out)
+	return out, nil
+}
+
+func (s *stubStoreClient) Namespaces(ctx context.Context) ([]client.Namespace, error) {
+	return []client.Namespace{{Name: "team-a", Keys: 3}}, nil
+}
+
+func (s *stubStoreClient) SetNamespaceTTL(ctx context.Context, ns string, ttl time.Duration) (*client.Namespace, error) {
+	s.nsTTL = ttl
+	return &client.Namespace{Name: ns, DefaultTTLSeconds: int(ttl.Seconds())}, nil
+}
+
+func (s *stubStoreClient) SetNamespaceQuota(ctx context.Context, ns string, q client.Quota) (*client.Namespace, error) {
+	s.nsQuota = q
+	return &client.Namespace{Name: ns, Quota: q}, nil
+}
+
+func (s *stubStoreClient) FlushNamespace(ctx context.Context, ns string) error {
+	s.nsFlushed = ns
+	return nil
+}
+
+func (s *stubStoreClient) SetBlob(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error {
+	s.blob = client.Blob{Data: data, ContentType: contentType}
+	return nil
+}
+
+func (s *stubStoreClient) GetBlob(ctx context.Context, key string) (*client.Blob, error) {
+	return &s.blob, nil
+}
+
+func (s *stubStoreClient) GetBlobRange(ctx context.Context, key string, offset, length int64) (*client.Blob, error) {
+	return &s.blob, nil
+}
+
+func (s *stubStoreClient) DeleteBlob(ctx context.Context, key string) error {
+	return nil
+}
+
 func TestCLI_Run_SetGetDeleteLPushRPop(t *testing.T) {
 	defaultTTL := 30 * time.Second
 	stub := &stubStoreClient{getValue: "hello", rpopValue: "world"}
@@ -152,6 +225,79 @@ func TestCLI_Run_SetGetDeleteLPushRPop(t *testing.T) {
 		t.Errorf("expected rpop to print 'world', got %q", rpopOutput)
 	}
 
+	// 6) Test audit query by key and time range
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	f, _ := os.Create(auditPath)
+	logger := audit.NewLogger(f, false)
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	logger.Log(audit.Record{Time: base, Identity: "ops", Operation: "set", Key: "foo", Value: "secret", Status: 200})
+	logger.Log(audit.Record{Time: base.Add(time.Hour), Identity: "ops", Operation: "del", Key: "foo", Status: 200})
+	logger.Log(audit.Record{Time: base.Add(time.Hour), Identity: "ops", Operation: "set", Key: "bar", Status: 200})
+	f.Close()
+
+	auditOutput := run([]string{"--action=audit", "--file=" + auditPath, "--key=foo", "--since=2024-05-01T12:30:00Z"})
+	lines := strings.Split(auditOutput, "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"operation":"del"`) {
+		t.Errorf("expected only the later foo record, got %q", auditOutput)
+	}
+	allFoo := run([]string{"--action=audit", "--file=" + auditPath, "--key=foo"})
+	if strings.Contains(allFoo, "secret") || !strings.Contains(allFoo, audit.Redacted) {
+		t.Errorf("expected redacted values, got %q", allFoo)
+	}
+
+	// 7) Test info without a key
+	infoOutput := run([]string{"--action=info"})
+	if !strings.Contains(infoOutput, `"version": "v1.2.3"`) || !strings.Contains(infoOutput, `"keys": 7`) {
+		t.Errorf("unexpected info output %q", infoOutput)
+	}
+
+	// 8) Test slowlog get and reset
+	slowOutput := run([]string{"--action=slowlog", "--count=5"})
+	if stub.slowCount != 5 || !strings.Contains(slowOutput, `"command": "LPush"`) {
+		t.Errorf("unexpected slowlog call count=%d output %q", stub.slowCount, slowOutput)
+	}
+	run([]string{"--action=slowlog-reset"})
+	if !stub.slowReset {
+		t.Errorf("expected slowlog-reset to reset the log")
+	}
+
+	// 9) Test monitor until the stream ends
+	monitorOutput := run([]string{"--action=monitor"})
+	if !strings.Contains(monitorOutput, `"command":"SetString"`) || !strings.Contains(monitorOutput, `"key":"foo"`) {
+		t.Errorf("unexpected monitor output %q", monitorOutput)
+	}
+
+	// 10) Test namespace listing, default TTL, quota and flush
+	nsOutput := run([]string{"--action=namespaces"})
+	if !strings.Contains(nsOutput, `"name": "team-a"`) {
+		t.Errorf("unexpected namespaces output %q", nsOutput)
+	}
+	ttlOutput := run([]string{"--action=namespace-ttl", "--namespace=team-a", "--ttl=5m"})
+	if stub.nsTTL != 5*time.Minute || !strings.Contains(ttlOutput, `"default_ttl_seconds": 300`) {
+		t.Errorf("unexpected namespace-ttl call ttl=%v output %q", stub.nsTTL, ttlOutput)
+	}
+	quotaOutput := run([]string{"--action=namespace-quota", "--namespace=team-a", "--max-keys=100", "--max-list-length=10"})
+	if stub.nsQuota != (client.Quota{MaxKeys: 100, MaxListLength: 10}) || !strings.Contains(quotaOutput, `"max_keys": 100`) {
+		t.Errorf("unexpected namespace-quota call %+v output %q", stub.nsQuota, quotaOutput)
+	}
+	run([]string{"--action=namespace-flush", "--namespace=team-a"})
+	if stub.nsFlushed != "team-a" {
+		t.Errorf("expected team-a to be flushed, got %q", stub.nsFlushed)
+	}
+
+	// 11) Test blob upload and download through files
+	dir := t.TempDir()
+	png := []byte("\x89PNG\r\n\x1a\n\x00binary")
+	os.WriteFile(filepath.Join(dir, "logo.png"), png, 0o600)
+	run([]string{"--action=blob-put", "--key=logo", "--path=" + filepath.Join(dir, "logo.png")})
+	if !bytes.Equal(stub.blob.Data, png) || stub.blob.ContentType != "image/png" {
+		t.Errorf("unexpected blob-put upload %q as %q", stub.blob.Data, stub.blob.ContentType)
+	}
+	run([]string{"--action=blob-get", "--key=logo", "--path=" + filepath.Join(dir, "copy.png")})
+	if got, _ := os.ReadFile(filepath.Join(dir, "copy.png")); !bytes.Equal(got, png) {
+		t.Errorf("expected blob-get to write the blob, got %q", got)
+	}
+
 	// Restore stdout
 	w.Close()
 	os.Stdout = origStdout
@@ -4,11 +4,14 @@ package client
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"os"
 	"strings"
 	"time"
 )
@@ -21,6 +24,8 @@ type StoreClient interface {
 
 	LPush(ctx context.Context, key string, items ...string) error
 	RPop(ctx context.Context, key string) (string, error)
+
+	Watch(ctx context.Context, pattern string) (<-chan Event, error)
 }
 
 // Client implements StoreClient over HTTP.
@@ -28,16 +33,114 @@ type Client struct {
 	baseURL    *url.URL
 	httpClient *http.Client
 	token      string
+
+	// signing replaces the bearer token with per-request HMAC signatures
+	signKeyID, signSecret string
+
+	namespace string // sent as X-Namespace; empty uses the token's home namespace
+
+	traceparent func(ctx context.Context) string
+}
+
+// Option configures a Client.
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	caFile            string
+	certFile, keyFile string
+	serverName        string
+
+	signKeyID, signSecret string
+
+	namespace string
+
+	traceparent func(ctx context.Context) string
+}
+
+// namespaceHeader selects the namespace of every request.
+const namespaceHeader = "X-Namespace"
+
+// WithNamespace makes every request act on keys of namespace ns instead
+// of the token's home namespace.
+func WithNamespace(ns string) Option {
+	return func(o *clientOptions) { o.namespace = ns }
+}
+
+// WithCAFile trusts the PEM certificates in path, in addition to the
+// system roots, when verifying an https server.
+func WithCAFile(path string) Option {
+	return func(o *clientOptions) { o.caFile = path }
+}
+
+// WithClientCert presents the given certificate for mutual TLS.
+func WithClientCert(certFile, keyFile string) Option {
+	return func(o *clientOptions) { o.certFile, o.keyFile = certFile, keyFile }
+}
+
+// WithServerName overrides the host name the server certificate is
+// verified against, e.g. when connecting by IP address.
+func WithServerName(name string) Option {
+	return func(o *clientOptions) { o.serverName = name }
 }
 
 // NewClient creates a new Client with the given base URL.
-// e.g. "http://localhost:8080"
-func NewClient(rawBaseURL string, token string) (StoreClient, error) {
+// e.g. "http://localhost:8080" or "https://store.internal:8443"
+func NewClient(rawBaseURL string, token string, opts ...Option) (StoreClient, error) {
 	u, err := url.Parse(rawBaseURL)
 	if err != nil {
 		return nil, fmt.Errorf("invalid base URL %q: %w", rawBaseURL, err)
 	}
-	return &Client{baseURL: u, httpClient: http.DefaultClient, token: token}, nil
+
+	o := clientOptions{traceparent: traceparentFromContext}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	httpClient := http.DefaultClient
+	if o.caFile != "" || o.certFile != "" || o.keyFile != "" || o.serverName != "" {
+		tlsCfg, err := o.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsCfg
+		httpClient = &http.Client{Transport: transport}
+	}
+	return &Client{
+		baseURL:     u,
+		httpClient:  httpClient,
+		token:       token,
+		signKeyID:   o.signKeyID,
+		signSecret:  o.signSecret,
+		namespace:   o.namespace,
+		traceparent: o.traceparent,
+	}, nil
+}
+
+// tlsConfig builds the client TLS settings from the options.
+func (o clientOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: o.serverName, MinVersion: tls.VersionTLS12}
+	if o.caFile != "" {
+		pem, err := os.ReadFile(o.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in CA file %q", o.caFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if o.certFile != "" || o.keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.certFile, o.keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
 }
 
 // doRequest builds the full URL, performs the HTTP call,
@@ -53,31 +156,37 @@ func (c *Client) doRequest(
 	fullURL := c.baseURL.ResolveReference(ref).String()
 
 	// Marshal request body if present
-	var body io.Reader
+	var payload []byte
 	if reqObj != nil {
 		buf := new(bytes.Buffer)
 		if err := json.NewEncoder(buf).Encode(reqObj); err != nil {
 			return fmt.Errorf("encode request: %w", err)
 		}
-		body = buf
+		payload = buf.Bytes()
 	}
 
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, method, fullURL, body)
+	// Do request, waiting out 429 responses as told by Retry-After
+	resp, err := c.send(ctx, method, fullURL, payload, nil)
 	if err != nil {
-		return fmt.Errorf("new request: %w", err)
+		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	defer resp.Body.Close()
 
-	// Do request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request error: %w", err)
+	if err := checkStatus(resp); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
 
-	// Handle HTTP error status
+	// Decode successful response if needed
+	if outObj != nil {
+		if err := json.NewDecoder(resp.Body).Decode(outObj); err != nil {
+			return fmt.Errorf("decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// checkStatus returns an HTTPError for status >= 400.
+func checkStatus(resp *http.Response) error {
 	if resp.StatusCode >= 400 {
 		// Attempt to decode structured JSON error
 		var he HTTPError
@@ -93,13 +202,6 @@ func (c *Client) doRequest(
 		}
 		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, msg)
 	}
-
-	// Decode successful response if needed
-	if outObj != nil {
-		if err := json.NewDecoder(resp.Body).Decode(outObj); err != nil {
-			return fmt.Errorf("decode response: %w", err)
-		}
-	}
 	return nil
 }
 
@@ -142,3 +244,10 @@ func (c *Client) RPop(ctx context.Context, key string) (string, error) {
 	}
 	return resp.Value, nil
 }
+
+// selectNamespace adds the namespace header, if a namespace was chosen.
+func (c *Client) selectNamespace(req *http.Request) {
+	if c.namespace != "" {
+		req.Header.Set(namespaceHeader, c.namespace)
+	}
+}
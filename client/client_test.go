@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestClient_StringOps(t *testing.T) {
@@ -90,3 +91,31 @@ func TestClient_ListOps(t *testing.T) {
 		t.Errorf("RPop returned %q, want two", v)
 	}
 }
+
+func TestClient_HonoursRetryAfter(t *testing.T) {
+	var calls int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"code":429,"message":"rate limit exceeded"}`))
+			return
+		}
+		w.Write([]byte(`{"value":"bar"}`))
+	}))
+	defer ts.Close()
+
+	cli, _ := NewClient(ts.URL, "my-secret-token")
+	start := time.Now()
+	val, err := cli.GetString(context.Background(), "foo")
+	if err != nil || val != "bar" {
+		t.Fatalf("GetString = %q, %v", val, err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected one retry, got %d calls", calls)
+	}
+	if waited := time.Since(start); waited < time.Second {
+		t.Fatalf("retried after %v, before Retry-After elapsed", waited)
+	}
+}
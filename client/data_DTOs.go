@@ -16,3 +16,12 @@ type listRequest struct {
 type stringResponse struct {
 	Value string `json:"value"`
 }
+
+// namespaceRequest matches the server's PUT /v1/admin/namespaces/{ns} body.
+// Omitted fields are left unchanged.
+type namespaceRequest struct {
+	DefaultTTLSeconds *int   `json:"default_ttl_seconds,omitempty"`
+	MaxKeys           *int   `json:"max_keys,omitempty"`
+	MaxBytes          *int64 `json:"max_bytes,omitempty"`
+	MaxListLength     *int   `json:"max_list_length,omitempty"`
+}
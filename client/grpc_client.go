@@ -0,0 +1,279 @@
+package client
+
+import (
+	"context"
+	"data_storage/api/storepb"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCClient implements StoreClient over gRPC. Errors are converted to
+// *HTTPError with the equivalent HTTP status, so callers can swap
+// transports without changing their error handling.
+type GRPCClient struct {
+	conn  *grpc.ClientConn
+	store storepb.StoreClient
+}
+
+var (
+	_ StoreClient = (*GRPCClient)(nil)
+	_ BlobClient  = (*GRPCClient)(nil)
+)
+
+// bearerToken attaches "authorization: Bearer <token>" to every call.
+type bearerToken struct {
+	token string
+}
+
+func (b bearerToken) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + b.token}, nil
+}
+
+func (b bearerToken) RequireTransportSecurity() bool { return false }
+
+// namespaceMetadata attaches "x-namespace: <ns>" to every call.
+type namespaceMetadata struct {
+	ns string
+}
+
+func (n namespaceMetadata) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	return map[string]string{"x-namespace": n.ns}, nil
+}
+
+func (n namespaceMetadata) RequireTransportSecurity() bool { return false }
+
+// GRPCNamespace makes every call of a GRPCClient act on namespace ns, as
+// WithNamespace does for the HTTP client.
+func GRPCNamespace(ns string) grpc.DialOption {
+	return grpc.WithPerRPCCredentials(namespaceMetadata{ns: ns})
+}
+
+// NewGRPCClient dials target (e.g. "localhost:9090"). Without extra dial
+// options the connection is plaintext; pass grpc.WithTransportCredentials
+// to use TLS.
+func NewGRPCClient(target string, token string, opts ...grpc.DialOption) (*GRPCClient, error) {
+	dialOpts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(bearerToken{token: token}),
+	}
+	dialOpts = append(dialOpts, opts...)
+
+	conn, err := grpc.NewClient(target, dialOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gRPC target %q: %w", target, err)
+	}
+	return &GRPCClient{conn: conn, store: storepb.NewStoreClient(conn)}, nil
+}
+
+// Close releases the underlying connection.
+func (c *GRPCClient) Close() error {
+	return c.conn.Close()
+}
+
+// SetString sets a string value with an optional TTL (0 = server default).
+func (c *GRPCClient) SetString(ctx context.Context, key, value string, ttl time.Duration) error {
+	_, err := c.store.SetString(ctx, &storepb.SetStringRequest{Key: key, Value: value, TtlMs: ttl.Milliseconds()})
+	return fromStatus(err)
+}
+
+// GetString retrieves a string value by key.
+func (c *GRPCClient) GetString(ctx context.Context, key string) (string, error) {
+	resp, err := c.store.GetString(ctx, &storepb.GetStringRequest{Key: key})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return resp.GetValue(), nil
+}
+
+// DeleteString deletes a string value by key.
+func (c *GRPCClient) DeleteString(ctx context.Context, key string) error {
+	_, err := c.store.DeleteString(ctx, &storepb.DeleteStringRequest{Key: key})
+	return fromStatus(err)
+}
+
+// SetBlob stores data at key with its media type (empty means
+// application/octet-stream) and an optional TTL (0 = server default).
+func (c *GRPCClient) SetBlob(ctx context.Context, key string, data []byte, contentType string, ttl time.Duration) error {
+	_, err := c.store.SetBlob(ctx, &storepb.SetBlobRequest{Key: key, Data: data, ContentType: contentType, TtlMs: ttl.Milliseconds()})
+	return fromStatus(err)
+}
+
+// GetBlob fetches the value at key with its media type.
+func (c *GRPCClient) GetBlob(ctx context.Context, key string) (*Blob, error) {
+	return c.GetBlobRange(ctx, key, 0, -1)
+}
+
+// GetBlobRange fetches length bytes of the value at key starting at
+// offset; a negative length reads to the end.
+func (c *GRPCClient) GetBlobRange(ctx context.Context, key string, offset, length int64) (*Blob, error) {
+	switch {
+	case length == 0:
+		return nil, fmt.Errorf("GetBlobRange: length must not be 0")
+	case length < 0:
+		length = 0 // to the end
+	}
+	resp, err := c.store.GetBlob(ctx, &storepb.GetBlobRequest{Key: key, Offset: offset, Length: length})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	// quoted like the HTTP ETag header
+	etag := `"` + strconv.FormatUint(resp.GetCas(), 10) + `"`
+	return &Blob{Data: resp.GetData(), ContentType: resp.GetContentType(), ETag: etag}, nil
+}
+
+// DeleteBlob deletes the value at key.
+func (c *GRPCClient) DeleteBlob(ctx context.Context, key string) error {
+	return c.DeleteString(ctx, key)
+}
+
+// LPush pushes items onto the head of the list at key.
+func (c *GRPCClient) LPush(ctx context.Context, key string, items ...string) error {
+	_, err := c.store.LPush(ctx, &storepb.LPushRequest{Key: key, Items: items})
+	return fromStatus(err)
+}
+
+// RPop pops an item from the tail of the list at key.
+func (c *GRPCClient) RPop(ctx context.Context, key string) (string, error) {
+	resp, err := c.store.RPop(ctx, &storepb.RPopRequest{Key: key})
+	if err != nil {
+		return "", fromStatus(err)
+	}
+	return resp.GetValue(), nil
+}
+
+// Incr adds delta to the counter at key and returns its new value.
+func (c *GRPCClient) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	resp, err := c.store.Incr(ctx, &storepb.IncrRequest{Key: key, Delta: delta})
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return resp.GetValue(), nil
+}
+
+// Decr subtracts delta from the counter at key, stopping at zero, and
+// returns its new value.
+func (c *GRPCClient) Decr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	resp, err := c.store.Decr(ctx, &storepb.DecrRequest{Key: key, Delta: delta})
+	if err != nil {
+		return 0, fromStatus(err)
+	}
+	return resp.GetValue(), nil
+}
+
+// Touch gives the existing key a new TTL; ttl <= 0 makes it persistent.
+func (c *GRPCClient) Touch(ctx context.Context, key string, ttl time.Duration) error {
+	_, err := c.store.Touch(ctx, &storepb.TouchRequest{Key: key, TtlMs: ttl.Milliseconds()})
+	return fromStatus(err)
+}
+
+// FlushAll removes every key of the namespace. It needs an admin token.
+func (c *GRPCClient) FlushAll(ctx context.Context) error {
+	_, err := c.store.FlushAll(ctx, &storepb.FlushAllRequest{})
+	return fromStatus(err)
+}
+
+// Watch streams events for keys matching pattern, reconnecting and
+// resuming from the last delivered event ID until ctx is done.
+func (c *GRPCClient) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	stream, err := c.store.Watch(ctx, &storepb.WatchRequest{Pattern: pattern})
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	// the server sends headers once subscribed; auth and validation
+	// errors surface here instead of on the first Recv
+	md, err := stream.Header()
+	if err != nil {
+		return nil, fromStatus(err)
+	}
+	if md == nil {
+		// trailers-only response: the stream already ended with a status
+		_, err := stream.Recv()
+		return nil, fromStatus(err)
+	}
+
+	out := make(chan Event, 64)
+	go func() {
+		defer close(out)
+
+		var lastID uint64
+		send := func(ev *storepb.Event) bool {
+			select {
+			case out <- Event{ID: ev.GetId(), Type: ev.GetType(), Namespace: ev.GetNamespace(), Key: ev.GetKey(), Time: time.Unix(0, ev.GetTimeUnixNano())}:
+				lastID = ev.GetId()
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		backoff := watchMinBackoff
+		for {
+			for stream != nil {
+				ev, err := stream.Recv()
+				if err != nil {
+					break
+				}
+				if !send(ev) {
+					return
+				}
+				backoff = watchMinBackoff
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > watchMaxBackoff {
+				backoff = watchMaxBackoff
+			}
+
+			stream, err = c.store.Watch(ctx, &storepb.WatchRequest{Pattern: pattern, AfterId: lastID})
+			if err != nil {
+				stream = nil
+			}
+		}
+	}()
+	return out, nil
+}
+
+// fromStatus converts a gRPC status into the HTTPError the HTTP client
+// would have returned for the same failure.
+func fromStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return fmt.Errorf("request error: %w", err)
+	}
+
+	code := http.StatusInternalServerError
+	switch st.Code() {
+	case codes.InvalidArgument, codes.NotFound, codes.FailedPrecondition:
+		code = http.StatusBadRequest
+	case codes.Unauthenticated:
+		code = http.StatusUnauthorized
+	case codes.PermissionDenied:
+		code = http.StatusForbidden
+	case codes.ResourceExhausted:
+		code = http.StatusTooManyRequests
+	case codes.OutOfRange:
+		code = http.StatusRequestedRangeNotSatisfiable
+	case codes.Unavailable:
+		code = http.StatusServiceUnavailable
+	case codes.DeadlineExceeded, codes.Canceled:
+		return fmt.Errorf("request error: %w", err)
+	}
+	return &HTTPError{Code: code, Message: st.Message()}
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// MonitorEvent is one command executed by the server, with values
+// truncated.
+type MonitorEvent struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Namespace  string    `json:"namespace"`
+	Key        string    `json:"key,omitempty"`
+	Args       []string  `json:"args,omitempty"`
+	ArgCount   int       `json:"arg_count"`
+	Identity   string    `json:"identity,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+	// Dropped is how many events the server dropped right before this
+	// one because the stream fell behind.
+	Dropped uint64 `json:"dropped,omitempty"`
+}
+
+// Monitor streams every command the server executes until ctx is done
+// or the connection ends, which closes the channel. Unlike Watch it does
+// not reconnect: commands executed while disconnected are gone.
+func (c *Client) Monitor(ctx context.Context) (<-chan MonitorEvent, error) {
+	resp, err := c.openStream(ctx, "/v1/admin/monitor", nil, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan MonitorEvent, 64)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+
+		var (
+			event   string
+			data    strings.Builder
+			dropped uint64
+		)
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				name := event
+				event = ""
+				if data.Len() == 0 {
+					continue
+				}
+				var ev MonitorEvent
+				err := json.Unmarshal([]byte(data.String()), &ev)
+				data.Reset()
+				if err != nil {
+					continue
+				}
+				if name == "dropped" {
+					dropped += ev.Dropped
+					continue
+				}
+				ev.Dropped, dropped = dropped, 0
+				select {
+				case out <- ev:
+				case <-ctx.Done():
+					return
+				}
+			case strings.HasPrefix(line, "event:"):
+				event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+			case strings.HasPrefix(line, "data:"):
+				data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+			}
+		}
+	}()
+	return out, nil
+}
@@ -0,0 +1,81 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// maxRateLimitRetries bounds how often one call waits out a 429.
+	maxRateLimitRetries = 3
+	// maxRetryAfter is the longest Retry-After the client sleeps through;
+	// longer throttling is returned to the caller as an HTTPError.
+	maxRetryAfter = 30 * time.Second
+)
+
+// send performs the request, retrying after 429 Too Many Requests
+// responses for as long as the server's Retry-After asks, within limits.
+// Each attempt is authenticated afresh so signed requests get a new nonce.
+// header adds to or overrides the JSON defaults.
+func (c *Client) send(ctx context.Context, method, fullURL string, payload []byte, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, fullURL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("new request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		c.propagate(req)
+		c.selectNamespace(req)
+		c.authenticate(req, payload) // last: it signs the headers above
+
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("request error: %w", err)
+		}
+		if resp.StatusCode != http.StatusTooManyRequests || attempt == maxRateLimitRetries {
+			return resp, nil
+		}
+		wait, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if !ok || wait > maxRetryAfter {
+			return resp, nil
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("request error: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// retryAfter parses a Retry-After value in seconds or as an HTTP date.
+func retryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		if d := at.Sub(now); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
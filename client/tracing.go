@@ -0,0 +1,38 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// traceparentHeader is the W3C trace context header.
+const traceparentHeader = "traceparent"
+
+type traceparentKey struct{}
+
+// ContextWithTraceparent attaches a W3C traceparent value, e.g.
+// "00-<trace id>-<span id>-01", to ctx. Requests made with ctx send it
+// so the server's spans join the caller's trace.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	return context.WithValue(ctx, traceparentKey{}, traceparent)
+}
+
+// WithTraceparentFunc derives the traceparent of each request from its
+// context, e.g. with an OpenTelemetry propagator; an empty result sends
+// none. It replaces the ContextWithTraceparent lookup.
+func WithTraceparentFunc(fn func(ctx context.Context) string) Option {
+	return func(o *clientOptions) { o.traceparent = fn }
+}
+
+// traceparentFromContext is the default traceparent source.
+func traceparentFromContext(ctx context.Context) string {
+	tp, _ := ctx.Value(traceparentKey{}).(string)
+	return tp
+}
+
+// propagate adds the request context's traceparent header, if any.
+func (c *Client) propagate(req *http.Request) {
+	if tp := c.traceparent(req.Context()); tp != "" {
+		req.Header.Set(traceparentHeader, tp)
+	}
+}
@@ -0,0 +1,153 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Event is a keyspace change delivered by Watch.
+type Event struct {
+	ID        uint64    `json:"id"`
+	Type      string    `json:"type"` // set | del | expired
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Time      time.Time `json:"time"`
+}
+
+const (
+	watchMinBackoff = 100 * time.Millisecond
+	watchMaxBackoff = 5 * time.Second
+)
+
+// Watch streams events for keys matching the glob pattern (a plain key
+// watches only that key; "" watches everything). The first connection
+// is made synchronously so auth and validation errors surface here.
+// Afterwards the stream reconnects on its own, resuming from the last
+// event ID it delivered. The channel is closed once ctx is done.
+func (c *Client) Watch(ctx context.Context, pattern string) (<-chan Event, error) {
+	resp, err := c.openWatch(ctx, pattern, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan Event, 64)
+	go func() {
+		defer close(out)
+
+		var lastID uint64
+		backoff := watchMinBackoff
+		for {
+			if resp != nil {
+				n, id := readEvents(ctx, resp, out)
+				if id > 0 {
+					lastID = id
+				}
+				if n > 0 {
+					backoff = watchMinBackoff
+				}
+			}
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return
+			}
+			if backoff *= 2; backoff > watchMaxBackoff {
+				backoff = watchMaxBackoff
+			}
+
+			resp, _ = c.openWatch(ctx, pattern, lastID)
+		}
+	}()
+	return out, nil
+}
+
+// openWatch issues the streaming GET, resuming after lastID when non-zero.
+func (c *Client) openWatch(ctx context.Context, pattern string, lastID uint64) (*http.Response, error) {
+	q := url.Values{}
+	if pattern != "" {
+		q.Set("pattern", pattern)
+	}
+	return c.openStream(ctx, "/v1/watch", q, lastID)
+}
+
+// openStream issues a Server-Sent Events GET to endpoint.
+func (c *Client) openStream(ctx context.Context, endpoint string, q url.Values, lastID uint64) (*http.Response, error) {
+	ref, _ := url.Parse(endpoint)
+	u := c.baseURL.ResolveReference(ref)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	c.propagate(req)
+	c.selectNamespace(req)
+	if lastID > 0 {
+		req.Header.Set("Last-Event-ID", strconv.FormatUint(lastID, 10))
+	}
+	c.authenticate(req, nil)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request error: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		he := HTTPError{Code: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&he); err != nil || he.Message == "" {
+			he.Message = http.StatusText(resp.StatusCode)
+		}
+		return nil, &he
+	}
+	return resp, nil
+}
+
+// readEvents parses a Server-Sent Events body into out until the stream
+// ends. It returns the number of events delivered and the last event ID.
+func readEvents(ctx context.Context, resp *http.Response, out chan<- Event) (int, uint64) {
+	defer resp.Body.Close()
+
+	var (
+		n      int
+		lastID uint64
+		data   strings.Builder
+	)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if data.Len() == 0 {
+				continue
+			}
+			var ev Event
+			err := json.Unmarshal([]byte(data.String()), &ev)
+			data.Reset()
+			if err != nil {
+				continue
+			}
+			select {
+			case out <- ev:
+				n++
+				lastID = ev.ID
+			case <-ctx.Done():
+				return n, lastID
+			}
+		case strings.HasPrefix(line, "data:"):
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	return n, lastID
+}
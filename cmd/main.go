@@ -9,7 +9,7 @@ import (
 
 func main() {
 	// 1) Load .env / environment config
-	cfg, err := config.Load()
+	cfg, err := config.LoadClient()
 	if err != nil {
 		log.Fatalf("configuration error: %v", err)
 	}
@@ -20,8 +20,31 @@ func main() {
 		log.Fatalf("argument error: %v", err)
 	}
 
+	if args.AuditFile == "" {
+		args.AuditFile = cfg.AuditFile
+	}
+	if args.Namespace == "" {
+		args.Namespace = cfg.Namespace
+	}
+
 	// 3) Create the HTTP SDK client
-	sdk, err := client.NewClient(cfg.StoreServerURL, cfg.APIToken)
+	var opts []client.Option
+	if cfg.CAFile != "" {
+		opts = append(opts, client.WithCAFile(cfg.CAFile))
+	}
+	if cfg.ClientCertFile != "" {
+		opts = append(opts, client.WithClientCert(cfg.ClientCertFile, cfg.ClientKeyFile))
+	}
+	if cfg.ServerName != "" {
+		opts = append(opts, client.WithServerName(cfg.ServerName))
+	}
+	if args.Namespace != "" {
+		opts = append(opts, client.WithNamespace(args.Namespace))
+	}
+	if cfg.SigningKeyID != "" {
+		opts = append(opts, client.WithHMACSigning(cfg.SigningKeyID, cfg.APIToken))
+	}
+	sdk, err := client.NewClient(cfg.StoreServerURL, cfg.APIToken, opts...)
 	if err != nil {
 		log.Fatalf("client init error: %v", err)
 	}
@@ -1,40 +1,126 @@
 package main
 
 import (
+	"context"
 	"data_storage/config"
-	"data_storage/server/adapters"
-	"data_storage/server/storage"
-	"data_storage/server/store_service"
-	"fmt"
-	"log"
-	"net/http"
+	"data_storage/server"
+	"data_storage/server/logging"
+	"data_storage/server/tracing"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
+// configPollInterval is how often the config and ACL files are checked
+// for changes.
+const configPollInterval = 2 * time.Second
+
 func main() {
+	configFile := flag.String("config", os.Getenv("CONFIG_FILE"), "YAML config file; environment variables override it")
+	flag.Parse()
 
 	// load configs
-	cfg, err := config.Load()
+	cfg, err := config.LoadServer(*configFile)
 	if err != nil {
-		log.Fatalf("configuration error: %v", err)
+		fatal("configuration error", err)
 	}
 
-	fmt.Sprintln("config", cfg.CleanUpInterval)
+	// structured logs; the level is a LevelVar so it can change at runtime
+	var logLevel slog.LevelVar
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("configuration error", err)
+	}
+	logLevel.Set(level)
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, &logLevel)
+	if err != nil {
+		fatal("configuration error", err)
+	}
+	slog.SetDefault(logger)
 
-	// 2) Wire up repository, service, and handlers
-	repo := storage.NewDataRepo(cfg.CleanUpInterval)
+	// spans from the HTTP middleware down to storage lock waits
+	var spans *tracing.WriterExporter
+	switch cfg.TraceExporter {
+	case "stdout":
+		tracing.SetExporter(tracing.NewWriterExporter(os.Stdout))
+	case "file":
+		spans, err = tracing.OpenFileExporter(cfg.TraceFile)
+		if err != nil {
+			fatal("configuration error", err)
+		}
+		tracing.SetExporter(spans)
+	}
 
-	//Ensure invalidator stops on exit
-	defer repo.ShutDownInvalidation()
+	// 2) Wire up the store and every configured listener
+	srv, err := server.New(cfg, version, server.WithLogLevel(&logLevel))
+	if err != nil {
+		fatal("configuration error", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if err := srv.Start(); err != nil {
+		fatal("server error", err)
+	}
 
-	svc := store_service.NewStoreService(repo, cfg.DefaultTTL)
+	// 3) Serve until SIGINT/SIGTERM or a listener fails, reloading safe
+	// settings on SIGHUP or when the config or ACL file changes
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	changed := config.WatchFiles(ctx, configPollInterval, *configFile, cfg.ACLFile)
+	exit := 0
+serve:
+	for {
+		select {
+		case <-hup:
+			reload(srv, *configFile)
+		case <-changed:
+			reload(srv, *configFile)
+		case <-ctx.Done():
+			slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+			break serve
+		case err := <-srv.Err():
+			slog.Error("server error", "err", err)
+			exit = 1
+			break serve
+		}
+	}
+	stop() // a second signal kills the process
 
-	// 3) Build the router+middleware
-	handler := adapters.NewHandler(svc, cfg.APIToken)
+	// 4) Drain in-flight requests for up to SHUTDOWN_TIMEOUT
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	err = srv.Shutdown(shutdownCtx)
+	cancel()
+	if err != nil {
+		slog.Error("shutdown incomplete", "err", err)
+		exit = 1
+	}
+	if spans != nil {
+		spans.Close()
+	}
+	slog.Info("stopped")
+	os.Exit(exit)
+}
 
-	// 4) Start HTTP server
-	log.Printf("listening on :8080")
-	if err = http.ListenAndServe(":8080", handler); err != nil {
-		log.Fatalf("server error: %v", err)
+// reload re-reads the configuration and applies what can change while
+// serving; an invalid configuration is logged and ignored.
+func reload(srv *server.Server, path string) {
+	next, err := config.LoadServer(path)
+	if err != nil {
+		slog.Error("config reload rejected", "err", err)
+		return
 	}
+	if err := srv.Reload(next); err != nil {
+		slog.Error("config reload failed", "err", err)
+	}
+}
 
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// clientSettings lists the CLI's environment variables; the CLI reads
+// no config file.
+var clientSettings = []setting{
+	{"", "STORE_SERVER"},
+	{"", "STORE_DEFAULT_TTL"},
+	{"", "STORE_API_TOKEN"},
+	{"", "STORE_SIGNING_KEY_ID"},
+	{"", "STORE_CA_FILE"},
+	{"", "STORE_CLIENT_CERT"},
+	{"", "STORE_CLIENT_KEY"},
+	{"", "STORE_SERVER_NAME"},
+	{"", "STORE_NAMESPACE"},
+	{"", "AUDIT_FILE"},
+}
+
+// Client holds the CLI configuration.
+type Client struct {
+	StoreServerURL string        // STORE_SERVER, e.g. "http://localhost:8080"
+	DefaultTTL     time.Duration // STORE_DEFAULT_TTL, default 60s
+	APIToken       string        // STORE_API_TOKEN
+
+	// SigningKeyID makes the CLI sign requests with STORE_API_TOKEN as
+	// the shared secret instead of sending it (STORE_SIGNING_KEY_ID,
+	// the token's name).
+	SigningKeyID string
+
+	// Client side of TLS.
+	CAFile         string // STORE_CA_FILE, extra roots for the server certificate
+	ClientCertFile string // STORE_CLIENT_CERT, certificate for mutual TLS
+	ClientKeyFile  string // STORE_CLIENT_KEY
+	ServerName     string // STORE_SERVER_NAME, overrides the verified host name
+
+	Namespace string // STORE_NAMESPACE, the token's own namespace when empty
+
+	AuditFile string // AUDIT_FILE, read by the audit action
+}
+
+// LoadClient reads .env (if present) and then environment variables.
+// Every invalid setting is reported in the returned error.
+func LoadClient() (*Client, error) {
+	_ = godotenv.Load()
+	v := newValues(clientSettings, nil)
+
+	cfg := &Client{
+		StoreServerURL: v.str("STORE_SERVER", ""),
+		DefaultTTL:     v.duration("STORE_DEFAULT_TTL", "60s", 0),
+		APIToken:       v.str("STORE_API_TOKEN", ""),
+		SigningKeyID:   v.str("STORE_SIGNING_KEY_ID", ""),
+		CAFile:         v.str("STORE_CA_FILE", ""),
+		ClientCertFile: v.str("STORE_CLIENT_CERT", ""),
+		ClientKeyFile:  v.str("STORE_CLIENT_KEY", ""),
+		ServerName:     v.str("STORE_SERVER_NAME", ""),
+		Namespace:      v.str("STORE_NAMESPACE", ""),
+		AuditFile:      v.str("AUDIT_FILE", ""),
+	}
+
+	if cfg.StoreServerURL == "" {
+		v.errorf("STORE_SERVER is required")
+	}
+	// a client certificate can stand in for the token
+	if cfg.APIToken == "" && cfg.ClientCertFile == "" {
+		v.errorf("STORE_API_TOKEN is required unless STORE_CLIENT_CERT is set")
+	}
+	if cfg.SigningKeyID != "" && cfg.APIToken == "" {
+		v.errorf("STORE_SIGNING_KEY_ID needs STORE_API_TOKEN as the signing secret")
+	}
+	if (cfg.ClientCertFile == "") != (cfg.ClientKeyFile == "") {
+		v.errorf("STORE_CLIENT_CERT and STORE_CLIENT_KEY must be set together")
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -1,62 +1,214 @@
+// Package config loads the server configuration from an optional YAML
+// file overridden by environment variables, and the CLI configuration
+// from the environment alone.
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
-// Config holds all static configuration for the CLI.
-type Config struct {
-	StoreServerURL  string        // e.g. "http://localhost:8080"
-	DefaultTTL      time.Duration // parsed from STORE_DEFAULT_TTL
-	CleanUpInterval time.Duration
-	APIToken        string
+// RateLimit is a token bucket: PerSecond requests on average with bursts
+// of up to Burst. A zero PerSecond means unlimited.
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
 }
 
-// Load reads .env (if present) and then environment variables,
-// parses them, and returns a Config.
-func Load() (*Config, error) {
-	// Load .env into the process environment (no-op if none)
-	_ = godotenv.Load()
+// setting maps a config file key onto the environment variable that
+// overrides it.
+type setting struct {
+	key string // dotted path in the file, e.g. "tls.cert_file"
+	env string
+}
+
+// values resolves settings and collects every validation error, so a
+// bad configuration is reported in one go instead of one fix at a time.
+type values struct {
+	raw    map[string]string // by environment variable name
+	labels map[string]string // "key (ENV)" for settings the file knows
+	errs   []error
+}
 
-	// Required: STORE_SERVER
-	url := os.Getenv("STORE_SERVER")
-	if url == "" {
-		return nil, fmt.Errorf("STORE_SERVER environment variable is required")
+// newValues reads the environment, on top of the file's values when
+// file is non-nil.
+func newValues(settings []setting, file map[string]string) *values {
+	v := &values{raw: make(map[string]string), labels: make(map[string]string)}
+	for _, s := range settings {
+		if s.key != "" {
+			v.labels[s.env] = s.key + " (" + s.env + ")"
+		}
+		if val, ok := file[s.key]; ok && s.key != "" {
+			v.raw[s.env] = val
+		}
+		if val := os.Getenv(s.env); val != "" {
+			v.raw[s.env] = val
+		}
 	}
+	return v
+}
 
-	// Optional: STORE_DEFAULT_TTL (default to "60s")
-	ttlStr := os.Getenv("STORE_DEFAULT_TTL")
-	if ttlStr == "" {
-		ttlStr = "60s"
+// label names a setting in errors.
+func (v *values) label(env string) string {
+	if l, ok := v.labels[env]; ok {
+		return l
 	}
-	defaultTTL, err := time.ParseDuration(ttlStr)
+	return env
+}
+
+func (v *values) errorf(format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// err joins every error found so far, or returns nil.
+func (v *values) err() error {
+	return errors.Join(v.errs...)
+}
+
+// str returns the setting, or def when unset.
+func (v *values) str(env, def string) string {
+	if s := v.raw[env]; s != "" {
+		return s
+	}
+	return def
+}
+
+// choice returns the lowercased setting, which must be one of allowed.
+func (v *values) choice(env, def string, allowed ...string) string {
+	s := strings.ToLower(v.str(env, def))
+	for _, a := range allowed {
+		if s == a {
+			return s
+		}
+	}
+	v.errorf("invalid %s %q: want %s", v.label(env), s, strings.Join(allowed, ", "))
+	return def
+}
+
+// duration parses a duration no smaller than min.
+func (v *values) duration(env, def string, min time.Duration) time.Duration {
+	s := v.str(env, def)
+	d, err := time.ParseDuration(s)
 	if err != nil {
-		return nil, fmt.Errorf("invalid STORE_DEFAULT_TTL %q: %w", ttlStr, err)
+		v.errorf("invalid %s %q: %w", v.label(env), s, err)
+		return 0
+	}
+	if d < min {
+		v.errorf("invalid %s %q: must be at least %v", v.label(env), s, min)
+		return 0
 	}
+	return d
+}
 
-	interval := os.Getenv("CLEANUP_INTERVAL")
-	if interval == "" {
-		interval = "300s"
+// integer parses an integer no smaller than min.
+func (v *values) integer(env string, def, min int) int {
+	s := v.str(env, "")
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < min {
+		v.errorf("invalid %s %q: want an integer of at least %d", v.label(env), s, min)
+		return def
 	}
+	return n
+}
 
-	cleanUpInterval, err := time.ParseDuration(interval)
+// boolean parses a boolean; unset is false.
+func (v *values) boolean(env string) bool {
+	s := v.str(env, "")
+	if s == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(s)
 	if err != nil {
-		return nil, fmt.Errorf("invalid CLEANUP_INTERVAL %q: %w", interval, err)
+		v.errorf("invalid %s %q: %w", v.label(env), s, err)
 	}
+	return b
+}
 
-	token := os.Getenv("STORE_API_TOKEN")
-	if token == "" {
-		return nil, fmt.Errorf("STORE_API_TOKEN is required for token auth")
+// list splits a comma separated setting, dropping empty items; unset
+// is nil.
+func (v *values) list(env string) []string {
+	var items []string
+	for _, item := range strings.Split(v.str(env, ""), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
 	}
+	return items
+}
 
-	return &Config{
-		StoreServerURL:  url,
-		DefaultTTL:      defaultTTL,
-		APIToken:        token,
-		CleanUpInterval: cleanUpInterval,
-	}, nil
+// rateLimit reads "rate[:burst]", def when unset; an empty def means
+// unlimited. The burst defaults to the rate.
+func (v *values) rateLimit(env, def string) RateLimit {
+	s := v.str(env, def)
+	if s == "" {
+		return RateLimit{}
+	}
+	rateStr, burstStr, hasBurst := strings.Cut(s, ":")
+	rate, err := strconv.ParseFloat(rateStr, 64)
+	if err != nil || rate < 0 {
+		v.errorf("invalid %s %q: want rate[:burst]", v.label(env), s)
+		return RateLimit{}
+	}
+	limit := RateLimit{PerSecond: rate}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burstStr); err != nil || limit.Burst < 1 {
+			v.errorf("invalid %s %q: burst must be a positive integer", v.label(env), s)
+			return RateLimit{}
+		}
+	}
+	return limit
+}
+
+// readFile parses a YAML config file into dotted keys, e.g.
+// "tls.cert_file". Keys that no setting knows are errors, so typos do
+// not go unnoticed.
+func readFile(path string, settings []setting) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool, len(settings))
+	for _, s := range settings {
+		known[s.key] = true
+	}
+	out := make(map[string]string)
+	var errs []error
+	var walk func(prefix string, node map[string]interface{})
+	walk = func(prefix string, node map[string]interface{}) {
+		for k, val := range node {
+			key := prefix + k
+			switch val := val.(type) {
+			case map[string]interface{}:
+				walk(key+".", val)
+			case []interface{}:
+				errs = append(errs, fmt.Errorf("config file %s: %s: lists are not supported", path, key))
+			case nil:
+				// "key:" with no value leaves the default
+			default:
+				if !known[key] {
+					errs = append(errs, fmt.Errorf("config file %s: unknown setting %q", path, key))
+					continue
+				}
+				out[key] = fmt.Sprint(val)
+			}
+		}
+	}
+	walk("", doc)
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return out, errors.Join(errs...)
 }
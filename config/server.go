@@ -0,0 +1,404 @@
+package config
+
+import (
+	"net"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// serverSettings lists every server setting under its config file key.
+// Environment variables override the file.
+var serverSettings = []setting{
+	{"listen.http", "HTTP_ADDR"},
+	{"listen.resp", "RESP_ADDR"},
+	{"listen.grpc", "GRPC_ADDR"},
+	{"listen.memcache", "MEMCACHE_ADDR"},
+	{"listen.metrics", "METRICS_ADDR"},
+
+	{"websocket.allowed_origins", "WS_ALLOWED_ORIGINS"},
+
+	{"memcache.identity", "MEMCACHE_IDENTITY"},
+	{"memcache.namespace", "MEMCACHE_NAMESPACE"},
+
+	{"tls.cert_file", "TLS_CERT_FILE"},
+	{"tls.key_file", "TLS_KEY_FILE"},
+	{"tls.min_version", "TLS_MIN_VERSION"},
+	{"tls.client_ca_file", "TLS_CLIENT_CA_FILE"},
+	{"tls.require_client_cert", "TLS_REQUIRE_CLIENT_CERT"},
+
+	{"auth.mode", "AUTH_MODE"},
+	{"auth.token", "STORE_API_TOKEN"},
+	{"auth.acl_file", "ACL_FILE"},
+	{"auth.jwks_file", "JWT_JWKS_FILE"},
+	{"auth.jwt_issuer", "JWT_ISSUER"},
+	{"auth.jwt_audience", "JWT_AUDIENCE"},
+	{"auth.jwt_leeway", "JWT_LEEWAY"},
+	{"auth.request_signing", "REQUEST_SIGNING"},
+	{"auth.signature_skew", "SIGNATURE_SKEW"},
+
+	{"limits.reads", "RATE_LIMIT_READS"},
+	{"limits.writes", "RATE_LIMIT_WRITES"},
+	{"limits.auth_failures", "RATE_LIMIT_AUTH_FAILURES"},
+	{"limits.max_body_bytes", "MAX_BODY_BYTES"},
+	{"limits.max_key_length", "MAX_KEY_LENGTH"},
+	{"limits.max_value_bytes", "MAX_VALUE_BYTES"},
+	{"limits.max_push_items", "MAX_PUSH_ITEMS"},
+	{"limits.max_list_length", "MAX_LIST_LENGTH"},
+
+	{"persistence.snapshot_file", "SNAPSHOT_FILE"},
+
+	{"eviction.default_ttl", "STORE_DEFAULT_TTL"},
+	{"eviction.cleanup_interval", "CLEANUP_INTERVAL"},
+
+	{"quotas.max_keys", "QUOTA_MAX_KEYS"},
+	{"quotas.max_bytes", "QUOTA_MAX_BYTES"},
+	{"quotas.max_list_length", "QUOTA_MAX_LIST_LENGTH"},
+
+	{"compression.min_bytes", "COMPRESSION_MIN_BYTES"},
+	{"compression.level", "COMPRESSION_LEVEL"},
+
+	{"shutdown.timeout", "SHUTDOWN_TIMEOUT"},
+
+	{"logging.format", "LOG_FORMAT"},
+	{"logging.level", "LOG_LEVEL"},
+
+	{"tracing.exporter", "TRACE_EXPORTER"},
+	{"tracing.file", "TRACE_FILE"},
+
+	{"audit.file", "AUDIT_FILE"},
+	{"audit.max_size_mb", "AUDIT_MAX_SIZE_MB"},
+	{"audit.max_age", "AUDIT_MAX_AGE"},
+	{"audit.max_backups", "AUDIT_MAX_BACKUPS"},
+	{"audit.include_values", "AUDIT_INCLUDE_VALUES"},
+
+	{"slowlog.threshold", "SLOWLOG_THRESHOLD"},
+	{"slowlog.max_len", "SLOWLOG_MAX_LEN"},
+
+	{"webhooks.max_attempts", "WEBHOOK_MAX_ATTEMPTS"},
+	{"webhooks.backoff", "WEBHOOK_BACKOFF"},
+}
+
+// reloadable lists the settings Server.Reload applies without a restart.
+var reloadable = map[string]bool{
+	"STORE_API_TOKEN":   true,
+	"ACL_FILE":          true,
+	"RATE_LIMIT_READS":  true,
+	"RATE_LIMIT_WRITES": true,
+
+	"RATE_LIMIT_AUTH_FAILURES": true,
+	"LOG_LEVEL":                true,
+	"SLOWLOG_THRESHOLD":        true,
+
+	"QUOTA_MAX_KEYS":        true,
+	"QUOTA_MAX_BYTES":       true,
+	"QUOTA_MAX_LIST_LENGTH": true,
+
+	"COMPRESSION_MIN_BYTES": true,
+	"COMPRESSION_LEVEL":     true,
+}
+
+// Server holds the server configuration.
+type Server struct {
+	HTTPAddr string // HTTP_ADDR, default ":8080"
+
+	DefaultTTL      time.Duration // STORE_DEFAULT_TTL, default 60s
+	CleanUpInterval time.Duration // CLEANUP_INTERVAL, expiry sweep period, default 300s
+
+	// Default quota of every namespace that does not set its own through
+	// the admin API; 0 (the default) is unlimited.
+	QuotaMaxKeys       int   // QUOTA_MAX_KEYS
+	QuotaMaxBytes      int64 // QUOTA_MAX_BYTES, approximate memory of keys and values
+	QuotaMaxListLength int   // QUOTA_MAX_LIST_LENGTH
+
+	// Values of at least COMPRESSION_MIN_BYTES are stored gzip-compressed
+	// at COMPRESSION_LEVEL (1 fastest, the default, to 9); 0 (the
+	// default) stores every value as is.
+	CompressionMinBytes int
+	CompressionLevel    int
+
+	// APIToken is a full-access "default" token (STORE_API_TOKEN).
+	// Required unless ACLFile or JWTs provide the callers.
+	APIToken string
+	// ACLFile is a JSON file of named tokens with per-token key prefixes
+	// and operation classes (ACL_FILE).
+	ACLFile string
+
+	// AuthMode selects how bearer credentials are checked (AUTH_MODE):
+	// "token" (default) uses static tokens, "jwt" only accepts JWTs
+	// signed by keys in JWKSFile, and "both" accepts either.
+	AuthMode    string
+	JWKSFile    string        // JWT_JWKS_FILE, re-read when it changes
+	JWTIssuer   string        // JWT_ISSUER, required "iss" claim if set
+	JWTAudience string        // JWT_AUDIENCE, required "aud" entry if set
+	JWTLeeway   time.Duration // JWT_LEEWAY, clock skew for exp/nbf, default 30s
+
+	// Server TLS; both files set switches the HTTP and gRPC listeners to
+	// TLS. The pair is reloaded when the files change.
+	TLSCertFile          string // TLS_CERT_FILE
+	TLSKeyFile           string // TLS_KEY_FILE
+	TLSMinVersion        string // TLS_MIN_VERSION, "1.2" (default) or "1.3"
+	TLSClientCAFile      string // TLS_CLIENT_CA_FILE, verifies client certificates
+	TLSRequireClientCert bool   // TLS_REQUIRE_CLIENT_CERT, reject clients without one
+
+	// RequestSigning accepts HMAC-signed requests (REQUEST_SIGNING):
+	// "off" (default), "optional" alongside bearer tokens, or "required".
+	RequestSigning string
+	SignatureSkew  time.Duration // SIGNATURE_SKEW, allowed clock skew, default 5m
+
+	// Per-caller request limits, as "rate[:burst]" requests per second
+	// (RATE_LIMIT_READS, RATE_LIMIT_WRITES); writes include deletes.
+	ReadRateLimit  RateLimit
+	WriteRateLimit RateLimit
+	// Failed authentications per client IP (RATE_LIMIT_AUTH_FAILURES,
+	// default "1:20"); once spent, the IP gets 429 until it refills.
+	AuthFailureRateLimit RateLimit
+
+	// Size guardrails; oversize requests get 413 and 0 is unlimited.
+	MaxBodyBytes  int64 // MAX_BODY_BYTES, HTTP body or gRPC message, default 2 MiB
+	MaxKeyLength  int   // MAX_KEY_LENGTH, default 1024 bytes
+	MaxValueBytes int   // MAX_VALUE_BYTES, per string or list item, default 1 MiB
+	MaxPushItems  int   // MAX_PUSH_ITEMS, items per list push, default 10000
+	MaxListLength int   // MAX_LIST_LENGTH, default 1000000
+
+	// Audit log of mutating requests (AUDIT_FILE; empty disables it),
+	// rotated by size (AUDIT_MAX_SIZE_MB, default 100) and age
+	// (AUDIT_MAX_AGE, default 24h), keeping AUDIT_MAX_BACKUPS rotated
+	// files (default 0 keeps all). Values are redacted unless
+	// AUDIT_INCLUDE_VALUES=true.
+	AuditFile          string
+	AuditMaxSize       int64
+	AuditMaxAge        time.Duration
+	AuditMaxBackups    int
+	AuditIncludeValues bool
+
+	WebhookMaxAttempts int           // WEBHOOK_MAX_ATTEMPTS, default 5
+	WebhookBackoff     time.Duration // WEBHOOK_BACKOFF, first retry delay, default 500ms
+
+	// Server logging: LOG_FORMAT "text" (default) or "json", LOG_LEVEL
+	// "debug", "info" (default), "warn" or "error".
+	LogFormat string
+	LogLevel  string
+
+	// Tracing spans go to TRACE_EXPORTER: "off" (default), "stdout", or
+	// "file", which appends JSON lines to TRACE_FILE.
+	TraceExporter string
+	TraceFile     string
+
+	// Slow log of service calls taking at least SLOWLOG_THRESHOLD
+	// (default 10ms), keeping the last SLOWLOG_MAX_LEN (default 128).
+	SlowLogThreshold time.Duration
+	SlowLogMaxLen    int
+
+	// METRICS_ADDR, e.g. "127.0.0.1:9100", serves /metrics there without
+	// authentication; empty serves it on the API port to admin tokens.
+	MetricsAddr string
+
+	RESPAddr string // RESP_ADDR, e.g. ":6379"; empty disables the Redis listener
+	GRPCAddr string // GRPC_ADDR, e.g. ":9090"; empty disables the gRPC API
+
+	// MEMCACHE_ADDR, e.g. "127.0.0.1:11211"; empty disables the memcached
+	// listener. The protocol is unauthenticated, so without
+	// MEMCACHE_IDENTITY the address must be a loopback one, and a bare
+	// ":port" binds 127.0.0.1.
+	MemcacheAddr string
+	// WS_ALLOWED_ORIGINS, comma separated (e.g.
+	// "https://app.example.com"; "*" allows any), are the browser origins
+	// besides the API's own that may open /v1/ws.
+	WSAllowedOrigins []string
+
+	// MEMCACHE_IDENTITY names the token whose ACL every memcached command
+	// runs under, in MEMCACHE_NAMESPACE (default: the token's home
+	// namespace). Unset, clients have full access to the default
+	// namespace.
+	MemcacheIdentity  string
+	MemcacheNamespace string
+
+	// SHUTDOWN_TIMEOUT (default 30s) bounds how long shutdown waits for
+	// in-flight requests before closing connections.
+	ShutdownTimeout time.Duration
+
+	// SNAPSHOT_FILE is loaded on start and written on shutdown; empty
+	// keeps the store purely in memory.
+	SnapshotFile string
+
+	raw map[string]string // resolved settings by variable, for RestartRequired
+}
+
+// LoadServer reads .env (if present), the YAML file at path (if not
+// empty) and then environment variables, which take precedence. Every
+// invalid setting is reported in the returned error.
+func LoadServer(path string) (*Server, error) {
+	_ = godotenv.Load()
+
+	var file map[string]string
+	if path != "" {
+		var err error
+		if file, err = readFile(path, serverSettings); err != nil {
+			return nil, err
+		}
+	}
+	v := newValues(serverSettings, file)
+
+	cfg := &Server{
+		HTTPAddr:        v.str("HTTP_ADDR", ":8080"),
+		DefaultTTL:      v.duration("STORE_DEFAULT_TTL", "60s", 0),
+		CleanUpInterval: v.duration("CLEANUP_INTERVAL", "300s", time.Millisecond),
+
+		QuotaMaxKeys:       v.integer("QUOTA_MAX_KEYS", 0, 0),
+		QuotaMaxBytes:      int64(v.integer("QUOTA_MAX_BYTES", 0, 0)),
+		QuotaMaxListLength: v.integer("QUOTA_MAX_LIST_LENGTH", 0, 0),
+
+		CompressionMinBytes: v.integer("COMPRESSION_MIN_BYTES", 0, 0),
+		CompressionLevel:    v.integer("COMPRESSION_LEVEL", 1, 1),
+
+		APIToken: v.str("STORE_API_TOKEN", ""),
+		ACLFile:  v.str("ACL_FILE", ""),
+
+		AuthMode:    v.choice("AUTH_MODE", "token", "token", "jwt", "both"),
+		JWKSFile:    v.str("JWT_JWKS_FILE", ""),
+		JWTIssuer:   v.str("JWT_ISSUER", ""),
+		JWTAudience: v.str("JWT_AUDIENCE", ""),
+		JWTLeeway:   v.duration("JWT_LEEWAY", "30s", 0),
+
+		TLSCertFile:          v.str("TLS_CERT_FILE", ""),
+		TLSKeyFile:           v.str("TLS_KEY_FILE", ""),
+		TLSMinVersion:        v.choice("TLS_MIN_VERSION", "1.2", "1.2", "1.3"),
+		TLSClientCAFile:      v.str("TLS_CLIENT_CA_FILE", ""),
+		TLSRequireClientCert: v.boolean("TLS_REQUIRE_CLIENT_CERT"),
+
+		RequestSigning: v.choice("REQUEST_SIGNING", "off", "off", "optional", "required"),
+		SignatureSkew:  v.duration("SIGNATURE_SKEW", "5m", time.Nanosecond),
+
+		ReadRateLimit:        v.rateLimit("RATE_LIMIT_READS", ""),
+		WriteRateLimit:       v.rateLimit("RATE_LIMIT_WRITES", ""),
+		AuthFailureRateLimit: v.rateLimit("RATE_LIMIT_AUTH_FAILURES", "1:20"),
+
+		MaxBodyBytes:  int64(v.integer("MAX_BODY_BYTES", 2<<20, 0)),
+		MaxKeyLength:  v.integer("MAX_KEY_LENGTH", 1024, 0),
+		MaxValueBytes: v.integer("MAX_VALUE_BYTES", 1<<20, 0),
+		MaxPushItems:  v.integer("MAX_PUSH_ITEMS", 10000, 0),
+		MaxListLength: v.integer("MAX_LIST_LENGTH", 1000000, 0),
+
+		AuditFile:          v.str("AUDIT_FILE", ""),
+		AuditMaxSize:       int64(v.integer("AUDIT_MAX_SIZE_MB", 100, 0)) << 20,
+		AuditMaxAge:        v.duration("AUDIT_MAX_AGE", "24h", 0),
+		AuditMaxBackups:    v.integer("AUDIT_MAX_BACKUPS", 0, 0),
+		AuditIncludeValues: v.boolean("AUDIT_INCLUDE_VALUES"),
+
+		WebhookMaxAttempts: v.integer("WEBHOOK_MAX_ATTEMPTS", 5, 1),
+		WebhookBackoff:     v.duration("WEBHOOK_BACKOFF", "500ms", 0),
+
+		LogFormat: v.choice("LOG_FORMAT", "text", "text", "json"),
+		LogLevel:  v.choice("LOG_LEVEL", "info", "debug", "info", "warn", "error"),
+
+		TraceExporter: v.choice("TRACE_EXPORTER", "off", "off", "stdout", "file"),
+		TraceFile:     v.str("TRACE_FILE", ""),
+
+		SlowLogThreshold: v.duration("SLOWLOG_THRESHOLD", "10ms", 0),
+		SlowLogMaxLen:    v.integer("SLOWLOG_MAX_LEN", 128, 0),
+
+		MetricsAddr:  v.str("METRICS_ADDR", ""),
+		RESPAddr:     v.str("RESP_ADDR", ""),
+		GRPCAddr:     v.str("GRPC_ADDR", ""),
+		MemcacheAddr: v.str("MEMCACHE_ADDR", ""),
+
+		WSAllowedOrigins: v.list("WS_ALLOWED_ORIGINS"),
+
+		MemcacheIdentity:  v.str("MEMCACHE_IDENTITY", ""),
+		MemcacheNamespace: v.str("MEMCACHE_NAMESPACE", ""),
+
+		ShutdownTimeout: v.duration("SHUTDOWN_TIMEOUT", "30s", time.Nanosecond),
+		SnapshotFile:    v.str("SNAPSHOT_FILE", ""),
+
+		raw: v.raw,
+	}
+
+	// settings that only make sense together
+	if cfg.AuthMode == "token" && cfg.APIToken == "" && cfg.ACLFile == "" {
+		v.errorf("%s is required unless %s is set or %s is jwt or both",
+			v.label("STORE_API_TOKEN"), v.label("ACL_FILE"), v.label("AUTH_MODE"))
+	}
+	if cfg.AuthMode != "token" && cfg.JWKSFile == "" {
+		v.errorf("%s is required when AUTH_MODE=%s", v.label("JWT_JWKS_FILE"), cfg.AuthMode)
+	}
+	if (cfg.TLSCertFile == "") != (cfg.TLSKeyFile == "") {
+		v.errorf("%s and %s must be set together", v.label("TLS_CERT_FILE"), v.label("TLS_KEY_FILE"))
+	}
+	if cfg.CompressionLevel > 9 {
+		v.errorf("%s must be between 1 and 9", v.label("COMPRESSION_LEVEL"))
+	}
+	if cfg.MemcacheAddr != "" && cfg.MemcacheIdentity == "" {
+		host, port, err := net.SplitHostPort(cfg.MemcacheAddr)
+		switch {
+		case err != nil:
+			v.errorf("%s: %v", v.label("MEMCACHE_ADDR"), err)
+		case host == "":
+			cfg.MemcacheAddr = net.JoinHostPort("127.0.0.1", port)
+		case !loopback(host):
+			v.errorf("%s must be a loopback address unless %s is set", v.label("MEMCACHE_ADDR"), v.label("MEMCACHE_IDENTITY"))
+		}
+	}
+	if cfg.MemcacheNamespace != "" && cfg.MemcacheIdentity == "" {
+		v.errorf("%s requires %s", v.label("MEMCACHE_NAMESPACE"), v.label("MEMCACHE_IDENTITY"))
+	}
+	if cfg.TraceExporter == "file" && cfg.TraceFile == "" {
+		v.errorf("%s is required when TRACE_EXPORTER=file", v.label("TRACE_FILE"))
+	}
+
+	if err := v.err(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loopback reports whether host names the local machine only.
+func loopback(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// RestartRequired lists the environment variable names of settings that
+// differ in next but only take effect after a restart.
+func (c *Server) RestartRequired(next *Server) []string {
+	var names []string
+	for _, s := range serverSettings {
+		if !reloadable[s.env] && c.raw[s.env] != next.raw[s.env] {
+			names = append(names, s.env)
+		}
+	}
+	return names
+}
+
+// Reloaded returns a copy of c with the reloadable settings of next:
+// the configuration in effect once Server.Reload has applied next.
+func (c *Server) Reloaded(next *Server) *Server {
+	merged := *c
+	merged.APIToken, merged.ACLFile = next.APIToken, next.ACLFile
+	merged.ReadRateLimit = next.ReadRateLimit
+	merged.WriteRateLimit = next.WriteRateLimit
+	merged.AuthFailureRateLimit = next.AuthFailureRateLimit
+	merged.LogLevel = next.LogLevel
+	merged.SlowLogThreshold = next.SlowLogThreshold
+	merged.QuotaMaxKeys = next.QuotaMaxKeys
+	merged.QuotaMaxBytes = next.QuotaMaxBytes
+	merged.QuotaMaxListLength = next.QuotaMaxListLength
+	merged.CompressionMinBytes = next.CompressionMinBytes
+	merged.CompressionLevel = next.CompressionLevel
+
+	merged.raw = make(map[string]string, len(c.raw))
+	for env, v := range c.raw {
+		merged.raw[env] = v
+	}
+	for env := range reloadable {
+		if v, ok := next.raw[env]; ok {
+			merged.raw[env] = v
+		} else {
+			delete(merged.raw, env)
+		}
+	}
+	return &merged
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// WatchFiles polls paths every interval and signals on the returned
+// channel when any of them is created, removed or modified. Empty paths
+// are ignored. Signals are coalesced: one pending signal covers any
+// number of changes.
+func WatchFiles(ctx context.Context, interval time.Duration, paths ...string) <-chan struct{} {
+	changed := make(chan struct{}, 1)
+
+	type stamp struct {
+		mod  time.Time
+		size int64
+		ok   bool
+	}
+	stat := func() []stamp {
+		out := make([]stamp, 0, len(paths))
+		for _, p := range paths {
+			if p == "" {
+				continue
+			}
+			if info, err := os.Stat(p); err == nil {
+				out = append(out, stamp{mod: info.ModTime(), size: info.Size(), ok: true})
+			} else {
+				out = append(out, stamp{})
+			}
+		}
+		return out
+	}
+
+	last := stat()
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				now := stat()
+				for i := range now {
+					if now[i] != last[i] {
+						select {
+						case changed <- struct{}{}:
+						default:
+						}
+						break
+					}
+				}
+				last = now
+			}
+		}
+	}()
+	return changed
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"data_storage/server/adapters"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_TokenACLs(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	aclFile := filepath.Join(t.TempDir(), "acl.json")
+	os.WriteFile(aclFile, []byte(`{"tokens":[
+		{"name":"billing-reader","token":"reader-token","prefixes":["billing:*"],"classes":["read"]}
+	]}`), 0o600)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	if err := tokens.LoadFile(aclFile); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token", adapters.WithTokenRegistry(tokens)))
+	defer ts.Close()
+
+	do := func(token, method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+	expect := func(resp *http.Response, want int) {
+		t.Helper()
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s %s: status %d, want %d", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, want)
+		}
+	}
+
+	// 1) The default token keeps full access
+	expect(do("my-secret-token", http.MethodPost, "/v1/string/billing:1", `{"value":"paid"}`), http.StatusOK)
+	expect(do("my-secret-token", http.MethodPost, "/v1/string/users:1", `{"value":"alice"}`), http.StatusOK)
+
+	// 2) The reader may read inside its prefix only
+	expect(do("reader-token", http.MethodGet, "/v1/string/billing:1", ""), http.StatusOK)
+
+	resp := do("reader-token", http.MethodGet, "/v1/string/users:1", "")
+	var denial struct {
+		Code      int    `json:"code"`
+		Identity  string `json:"identity"`
+		Operation string `json:"operation"`
+		Key       string `json:"key"`
+	}
+	json.NewDecoder(resp.Body).Decode(&denial)
+	expect(resp, http.StatusForbidden)
+	if denial.Code != http.StatusForbidden || denial.Identity != "billing-reader" || denial.Operation != "read" || denial.Key != "users:1" {
+		t.Fatalf("unexpected denial body %+v", denial)
+	}
+
+	expect(do("reader-token", http.MethodPost, "/v1/string/billing:1", `{"value":"x"}`), http.StatusForbidden)
+	expect(do("reader-token", http.MethodDelete, "/v1/string/billing:1", ""), http.StatusForbidden)
+	expect(do("reader-token", http.MethodGet, "/v1/watch?pattern=*", ""), http.StatusForbidden)
+	expect(do("reader-token", http.MethodGet, "/v1/admin/tokens", ""), http.StatusForbidden)
+
+	// 3) Admins create a writer token; the secret is returned once
+	resp = do("my-secret-token", http.MethodPost, "/v1/admin/tokens",
+		`{"name":"billing-writer","prefixes":["billing:"],"classes":["read","write"]}`)
+	var created auth.Token
+	json.NewDecoder(resp.Body).Decode(&created)
+	expect(resp, http.StatusCreated)
+	if created.Token == "" {
+		t.Fatal("expected a generated token")
+	}
+	expect(do(created.Token, http.MethodPost, "/v1/string/billing:2", `{"value":"due"}`), http.StatusOK)
+	expect(do(created.Token, http.MethodPost, "/v1/string/users:2", `{"value":"bob"}`), http.StatusForbidden)
+
+	// 4) Invalid definitions are rejected
+	expect(do("my-secret-token", http.MethodPost, "/v1/admin/tokens",
+		`{"name":"bad","prefixes":["*"],"classes":["everything"]}`), http.StatusBadRequest)
+
+	// 4b) The built-in token can be neither replaced nor revoked
+	expect(do("my-secret-token", http.MethodPost, "/v1/admin/tokens",
+		`{"name":"default","token":"hijacked","prefixes":["*"],"classes":["read"]}`), http.StatusConflict)
+	expect(do("my-secret-token", http.MethodDelete, "/v1/admin/tokens/default", ""), http.StatusConflict)
+	expect(do("hijacked", http.MethodGet, "/v1/string/billing:2", ""), http.StatusUnauthorized)
+	expect(do("my-secret-token", http.MethodGet, "/v1/string/billing:2", ""), http.StatusOK)
+
+	// 5) Changes are written back to the ACL file, without secrets leaking
+	// into the listing
+	saved, _ := os.ReadFile(aclFile)
+	if !strings.Contains(string(saved), "billing-writer") || strings.Contains(string(saved), "my-secret-token") {
+		t.Fatalf("unexpected ACL file contents: %s", saved)
+	}
+	resp = do("my-secret-token", http.MethodGet, "/v1/admin/tokens", "")
+	var list struct {
+		Tokens []auth.Token `json:"tokens"`
+	}
+	json.NewDecoder(resp.Body).Decode(&list)
+	expect(resp, http.StatusOK)
+	if len(list.Tokens) != 3 {
+		t.Fatalf("expected 3 tokens, got %+v", list.Tokens)
+	}
+	for _, tok := range list.Tokens {
+		if tok.Token != "" {
+			t.Fatalf("token %q secret leaked in listing", tok.Name)
+		}
+	}
+
+	// 6) Revoked tokens are rejected
+	expect(do("my-secret-token", http.MethodDelete, "/v1/admin/tokens/billing-writer", ""), http.StatusOK)
+	expect(do(created.Token, http.MethodGet, "/v1/string/billing:2", ""), http.StatusUnauthorized)
+	expect(do("my-secret-token", http.MethodDelete, "/v1/admin/tokens/billing-writer", ""), http.StatusNotFound)
+}
+
+func TestRegistry_ConcurrentSaves(t *testing.T) {
+	dir := t.TempDir()
+	aclFile := filepath.Join(dir, "acl.json")
+	os.WriteFile(aclFile, []byte(`{"tokens":[]}`), 0o600)
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	if err := tokens.LoadFile(aclFile); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := tokens.Put(auth.Token{Name: "svc-" + strconv.Itoa(i), Prefixes: []string{"*"}, Classes: []auth.OpClass{auth.OpRead}})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	// the file holds every token, and no temporary file is left behind
+	reloaded := auth.NewRegistry()
+	if err := reloaded.LoadFile(aclFile); err != nil {
+		t.Fatalf("reloading the saved file: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		if _, err := reloaded.Lookup("svc-" + strconv.Itoa(i)); err != nil {
+			t.Errorf("svc-%d missing from the saved file", i)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the ACL file in %s, got %d entries", dir, len(entries))
+	}
+}
@@ -0,0 +1,133 @@
+package adapters
+
+import (
+	"data_storage/server/auth"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// Route names used to classify requests for authorization.
+const (
+	routeStringSet    = "string.set"
+	routeStringGet    = "string.get"
+	routeStringDelete = "string.delete"
+	routeBlobPut      = "blob.put"
+	routeBlobGet      = "blob.get"
+	routeBlobDelete   = "blob.delete"
+	routeListPush     = "list.push"
+	routeListPop      = "list.pop"
+	routeWatch        = "watch"
+	routeWebSocket    = "ws"
+	routeAdmin        = "admin"
+)
+
+// classifyRoute maps a routed request to the operation class and key it
+// needs. WebSocket frames are authorized one by one, so the upgrade
+// itself only needs a valid token.
+func classifyRoute(r *http.Request) (auth.OpClass, string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", "", false
+	}
+	key := mux.Vars(r)["key"]
+
+	switch route.GetName() {
+	case routeStringGet, routeBlobGet:
+		return auth.OpRead, key, true
+	case routeStringSet, routeBlobPut, routeListPush, routeListPop:
+		return auth.OpWrite, key, true
+	case routeStringDelete, routeBlobDelete:
+		return auth.OpDelete, key, true
+	case routeWatch:
+		return auth.OpRead, watchPrefix(r.URL.Query().Get("key"), r.URL.Query().Get("pattern")), true
+	case routeAdmin:
+		return auth.OpAdmin, "", true
+	case routeWebSocket:
+		return "", "", false
+	}
+	// unnamed routes are denied rather than silently left open
+	return auth.OpAdmin, "", true
+}
+
+// watchPrefix returns the key prefix shared by every key a watch on key
+// or pattern can deliver.
+func watchPrefix(key, pattern string) string {
+	if key != "" {
+		return key
+	}
+	return auth.PatternPrefix(pattern)
+}
+
+// wsOpClass returns the class a WebSocket op needs; ok is false for ops
+// that touch no data.
+func wsOpClass(op string) (auth.OpClass, bool) {
+	switch op {
+	case "get", "get_entry", "llen", "ttl", "watch":
+		return auth.OpRead, true
+	case "set", "store", "incr", "decr", "lpush", "rpop", "expire", "touch":
+		return auth.OpWrite, true
+	case "del":
+		return auth.OpDelete, true
+	case "flush_all":
+		return auth.OpAdmin, true
+	}
+	return "", false
+}
+
+// describeRoute names the mutation a routed request performs, for the
+// audit log; reads and the WebSocket upgrade are not audited here.
+func describeRoute(r *http.Request) (string, string, bool) {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return "", "", false
+	}
+	key := mux.Vars(r)["key"]
+
+	switch route.GetName() {
+	case routeStringSet, routeBlobPut:
+		return "set", key, true
+	case routeStringDelete, routeBlobDelete:
+		return "del", key, true
+	case routeListPush:
+		return "lpush", key, true
+	case routeListPop:
+		return "rpop", key, true
+	case routeAdmin:
+		if r.Method == http.MethodGet {
+			return "", "", false
+		}
+		return "admin " + r.Method + " " + r.URL.Path, "", true
+	}
+	return "", "", false
+}
+
+// wsMutates reports whether a WebSocket op changes data and is audited.
+func wsMutates(op string) bool {
+	class, ok := wsOpClass(op)
+	return ok && class != auth.OpRead
+}
+
+// routeNamespace returns the namespace named in the path, e.g.
+// /v1/ns/{ns}/string/{key}; empty if the route names none.
+func routeNamespace(r *http.Request) string {
+	return mux.Vars(r)["ns"]
+}
+
+// routeTemplate labels request metrics with the matched path template,
+// e.g. "/v1/string/{key}", which keeps the label set bounded.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return "unknown"
+}
+
+// routeName names a routed request's handler for tracing.
+func routeName(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil && route.GetName() != "" {
+		return route.GetName()
+	}
+	return "unknown"
+}
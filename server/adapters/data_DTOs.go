@@ -1,5 +1,12 @@
 package adapters
 
+import (
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/slowlog"
+	"time"
+)
+
 // stringRequest is the JSON body for POST /v1/string/{key}.
 type stringRequest struct {
 	Value      string `json:"value"`
@@ -10,3 +17,156 @@ type stringRequest struct {
 type listRequest struct {
 	Items []string `json:"items"`
 }
+
+// webhookRequest is the JSON body for POST /v1/webhooks.
+type webhookRequest struct {
+	Pattern string             `json:"pattern"`
+	Events  []domain.Operation `json:"events"`
+	URL     string             `json:"url"`
+	Secret  string             `json:"secret"`
+}
+
+// tokenRequest is the JSON body for POST /v1/admin/tokens. An empty
+// Token asks the server to generate one.
+type tokenRequest struct {
+	Name     string         `json:"name"`
+	Token    string         `json:"token"`
+	Prefixes []string       `json:"prefixes"`
+	Classes  []auth.OpClass `json:"classes"`
+	// Namespaces binds the token to namespaces; "*" allows all and none
+	// only the default namespace.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// CertSubjects lists client certificate names that identify callers
+	// as this token.
+	CertSubjects []string `json:"cert_subjects,omitempty"`
+}
+
+// wsRequest is a command frame received on /v1/ws.
+type wsRequest struct {
+	ID         string   `json:"id"`
+	Op         string   `json:"op"`
+	Key        string   `json:"key,omitempty"`
+	Value      string   `json:"value,omitempty"`
+	Items      []string `json:"items,omitempty"`
+	TTLSeconds int      `json:"ttl_seconds,omitempty"`
+	Delta      uint64   `json:"delta,omitempty"`
+	Flags      uint32   `json:"flags,omitempty"`
+	CAS        uint64   `json:"cas,omitempty"`
+	Mode       string   `json:"mode,omitempty"` // store: set | add | replace | cas
+	Pattern    string   `json:"pattern,omitempty"`
+	AfterID    uint64   `json:"after_id,omitempty"`
+	// Subscription names the watch to cancel with "unwatch".
+	Subscription string `json:"subscription,omitempty"`
+}
+
+// wsResponse answers the wsRequest with the same ID.
+type wsResponse struct {
+	ID     string      `json:"id"`
+	OK     bool        `json:"ok"`
+	Result interface{} `json:"result,omitempty"`
+	Error  *wsError    `json:"error,omitempty"`
+}
+
+// wsError mirrors the JSON body written by writeErrorJSON.
+type wsError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// wsPush carries an unsolicited event for a watch subscription.
+type wsPush struct {
+	Type         string       `json:"type"` // always "event"
+	Subscription string       `json:"subscription"`
+	Event        domain.Event `json:"event"`
+}
+
+// infoResponse is the JSON body of GET /v1/admin/info.
+type infoResponse struct {
+	Server      infoServer        `json:"server"`
+	Keyspace    infoKeyspace      `json:"keyspace"`
+	Memory      infoMemory        `json:"memory"`
+	Expiry      infoExpiry        `json:"expiry"`
+	Subscribers infoSubscribers   `json:"subscribers"`
+	Limits      infoLimits        `json:"limits"`
+	Config      map[string]string `json:"config"`
+}
+
+// infoLimits lets clients check requests before sending them; 0 is
+// unlimited.
+type infoLimits struct {
+	MaxBodyBytes  int64 `json:"max_body_bytes"`
+	MaxKeyLength  int   `json:"max_key_length"`
+	MaxValueBytes int   `json:"max_value_bytes"`
+	MaxPushItems  int   `json:"max_push_items"`
+	MaxListLength int   `json:"max_list_length"`
+}
+
+type infoServer struct {
+	Version       string    `json:"version"`
+	GoVersion     string    `json:"go_version"`
+	StartedAt     time.Time `json:"started_at"`
+	UptimeSeconds int64     `json:"uptime_seconds"`
+}
+
+type infoKeyspace struct {
+	Keys   int            `json:"keys"`
+	ByType map[string]int `json:"by_type"`
+}
+
+type infoMemory struct {
+	StoreBytes     int64  `json:"store_bytes"` // approximate keys and values
+	HeapAllocBytes uint64 `json:"heap_alloc_bytes"`
+	SysBytes       uint64 `json:"sys_bytes"`
+}
+
+type infoExpiry struct {
+	ExpiredKeys         uint64     `json:"expired_keys"`
+	SweepIntervalMS     int64      `json:"sweep_interval_ms"`
+	Sweeps              uint64     `json:"sweeps"`
+	LastSweepAt         *time.Time `json:"last_sweep_at,omitempty"`
+	LastSweepDurationMS float64    `json:"last_sweep_duration_ms"`
+	LastSweepExpired    int        `json:"last_sweep_expired"`
+}
+
+type infoSubscribers struct {
+	Watchers int `json:"watchers"` // SSE, WebSocket and gRPC watches
+	Webhooks int `json:"webhooks"`
+	Monitors int `json:"monitors"`
+}
+
+// namespaceResponse describes a namespace in /v1/admin/namespaces.
+type namespaceResponse struct {
+	Name   string         `json:"name"`
+	Keys   int            `json:"keys"`
+	ByType map[string]int `json:"by_type"`
+	Bytes  int64          `json:"bytes"`
+	// DefaultTTLSeconds overrides the server's default TTL; 0 uses it.
+	DefaultTTLSeconds int           `json:"default_ttl_seconds"`
+	Quota             quotaResponse `json:"quota"`
+}
+
+// quotaResponse holds a namespace's effective limits, the server's
+// default quota included; 0 is unlimited.
+type quotaResponse struct {
+	MaxKeys       int   `json:"max_keys"`
+	MaxBytes      int64 `json:"max_bytes"`
+	MaxListLength int   `json:"max_list_length"`
+}
+
+// namespaceRequest is the JSON body for PUT /v1/admin/namespaces/{ns}.
+// Omitted fields are left unchanged; a 0 limit falls back to the
+// server's default quota.
+type namespaceRequest struct {
+	DefaultTTLSeconds *int   `json:"default_ttl_seconds"`
+	MaxKeys           *int   `json:"max_keys"`
+	MaxBytes          *int64 `json:"max_bytes"`
+	MaxListLength     *int   `json:"max_list_length"`
+}
+
+// slowLogResponse is the JSON body of GET /v1/admin/slowlog.
+type slowLogResponse struct {
+	ThresholdMS float64         `json:"threshold_ms"`
+	Capacity    int             `json:"capacity"`
+	Len         int             `json:"len"`
+	Entries     []slowlog.Entry `json:"entries"`
+}
@@ -1,16 +1,38 @@
 package adapters
 
 import (
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/logging"
 	"encoding/json"
 	"net/http"
 )
 
-// writeErrorJSON writes a JSON error response.
+// writeErrorJSON writes a JSON error response, including the request ID
+// that LoggingMiddleWare set on the response so callers can quote it.
 func writeErrorJSON(w http.ResponseWriter, status int, msg string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(map[string]interface{}{
+	body := map[string]interface{}{
 		"code":    status,
 		"message": msg,
-	})
+	}
+	if id := w.Header().Get(logging.RequestIDHeader); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// decodeJSON decodes the request body into v. It replies 413 if the body
+// is over the size limit or 400 if it is not JSON, and returns false.
+func decodeJSON(w http.ResponseWriter, req *http.Request, v interface{}) bool {
+	err := json.NewDecoder(req.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	if msg, ok := middleware.TooLarge(err); ok {
+		writeErrorJSON(w, http.StatusRequestEntityTooLarge, msg)
+	} else {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid JSON")
+	}
+	return false
 }
@@ -3,6 +3,7 @@ package adapters
 import (
 	"data_storage/server/domain"
 	"errors"
+	"net/http"
 )
 
 func isClientError(err error) bool {
@@ -12,8 +13,44 @@ func isClientError(err error) bool {
 		errors.Is(err, domain.ErrNotFound),
 		errors.Is(err, domain.ErrWrongType),
 		errors.Is(err, domain.ErrEmptyEntry),
-		errors.Is(err, domain.ErrExpiredEntry):
+		errors.Is(err, domain.ErrExpiredEntry),
+		errors.Is(err, domain.ErrInvalidPattern),
+		errors.Is(err, domain.ErrInvalidWebhook),
+		errors.Is(err, domain.ErrKeyExists),
+		errors.Is(err, domain.ErrCASMismatch),
+		errors.Is(err, domain.ErrNotInteger),
+		errors.Is(err, domain.ErrInvalidMode),
+		errors.Is(err, domain.ErrInvalidContentType),
+		errors.Is(err, domain.ErrInvalidEncoding):
 		return true
 	}
 	return false
 }
+
+// errorStatus maps a service error to its HTTP status: 413 for oversize
+// input, 415 for an unknown content encoding, 507 for a namespace over
+// quota, 429 for a socket with too many subscriptions, 400 for other
+// caller mistakes and 500 otherwise.
+func errorStatus(err error) int {
+	switch {
+	case isTooLarge(err):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, domain.ErrUnsupportedEncoding):
+		return http.StatusUnsupportedMediaType
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		return http.StatusInsufficientStorage
+	case errors.Is(err, errTooManySubs):
+		return http.StatusTooManyRequests
+	case isClientError(err):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// isTooLarge reports whether err rejects input over domain.Limits.
+func isTooLarge(err error) bool {
+	return errors.Is(err, domain.ErrKeyTooLong) ||
+		errors.Is(err, domain.ErrValueTooLarge) ||
+		errors.Is(err, domain.ErrTooManyItems) ||
+		errors.Is(err, domain.ErrListTooLong)
+}
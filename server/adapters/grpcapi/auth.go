@@ -0,0 +1,211 @@
+package grpcapi
+
+import (
+	"context"
+	"data_storage/api/storepb"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// methodClass maps unary methods onto ACL operation classes.
+var methodClass = map[string]auth.OpClass{
+	storepb.Store_GetString_FullMethodName:    auth.OpRead,
+	storepb.Store_GetBlob_FullMethodName:      auth.OpRead,
+	storepb.Store_LLen_FullMethodName:         auth.OpRead,
+	storepb.Store_TTL_FullMethodName:          auth.OpRead,
+	storepb.Store_SetString_FullMethodName:    auth.OpWrite,
+	storepb.Store_SetBlob_FullMethodName:      auth.OpWrite,
+	storepb.Store_LPush_FullMethodName:        auth.OpWrite,
+	storepb.Store_RPop_FullMethodName:         auth.OpWrite,
+	storepb.Store_Expire_FullMethodName:       auth.OpWrite,
+	storepb.Store_Incr_FullMethodName:         auth.OpWrite,
+	storepb.Store_Decr_FullMethodName:         auth.OpWrite,
+	storepb.Store_Touch_FullMethodName:        auth.OpWrite,
+	storepb.Store_DeleteString_FullMethodName: auth.OpDelete,
+	storepb.Store_FlushAll_FullMethodName:     auth.OpAdmin,
+}
+
+// keyedRequest is implemented by every request message that names a key.
+type keyedRequest interface {
+	GetKey() string
+}
+
+// UnaryTokenAuth resolves the caller's client certificate, given
+// WithClientCerts, or "authorization: Bearer <token>" metadata with
+// authn, mirroring middleware.ClientCertAuth and BearerAuth, and checks
+// the method's class and key against the caller's ACL.
+func UnaryTokenAuth(authn auth.Authenticator, opts ...Option) grpc.UnaryServerInterceptor {
+	return unaryAuth(authn, newOptions(opts))
+}
+
+func unaryAuth(authn auth.Authenticator, o *options) grpc.UnaryServerInterceptor {
+	rl := o.limiter
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id, err := authenticate(ctx, authn, o)
+		if err != nil {
+			return nil, err
+		}
+		if ctx, err = withNamespace(withCaller(ctx, id), id); err != nil {
+			return nil, err
+		}
+		if class, ok := methodClass[info.FullMethod]; ok {
+			var key string
+			if r, ok := req.(keyedRequest); ok {
+				key = r.GetKey()
+			}
+			if err := authorize(ctx, class, key); err != nil {
+				return nil, err
+			}
+			if err := throttle(ctx, rl, class); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamTokenAuth is the streaming counterpart of UnaryTokenAuth. The
+// request message is not known yet, so stream handlers call authorize.
+// Opening a stream takes one read token.
+func StreamTokenAuth(authn auth.Authenticator, opts ...Option) grpc.StreamServerInterceptor {
+	return streamAuth(authn, newOptions(opts))
+}
+
+func streamAuth(authn auth.Authenticator, o *options) grpc.StreamServerInterceptor {
+	rl := o.limiter
+	return func(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id, err := authenticate(ss.Context(), authn, o)
+		if err != nil {
+			return err
+		}
+		ctx, err := withNamespace(withCaller(ss.Context(), id), id)
+		if err != nil {
+			return err
+		}
+		if err := throttle(ctx, rl, auth.OpRead); err != nil {
+			return err
+		}
+		return handler(srv, &identityStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// withCaller stores id and the peer's address in ctx.
+func withCaller(ctx context.Context, id *auth.Identity) context.Context {
+	if addr := peerAddr(ctx); addr != "" {
+		ctx = audit.WithRemoteAddr(ctx, addr)
+	}
+	return auth.WithIdentity(ctx, id)
+}
+
+// NamespaceMetadata selects the namespace of a call, like the HTTP
+// X-Namespace header; without it calls use the caller's home namespace.
+const NamespaceMetadata = "x-namespace"
+
+// withNamespace stores the namespace selected by the call's metadata in
+// ctx, provided id may use it.
+func withNamespace(ctx context.Context, id *auth.Identity) (context.Context, error) {
+	ns := id.HomeNamespace()
+	md, _ := metadata.FromIncomingContext(ctx)
+	if v := md.Get(NamespaceMetadata); len(v) > 0 {
+		ns = v[0]
+	}
+	if !domain.ValidNamespace(ns) {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid namespace %q", ns)
+	}
+	if !id.CanUseNamespace(ns) {
+		return nil, status.Errorf(codes.PermissionDenied, "forbidden: token %q may not use namespace %q", id.Name, ns)
+	}
+	return domain.WithNamespace(ctx, ns), nil
+}
+
+// identityStream overrides the stream context with one carrying the
+// caller's identity.
+type identityStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *identityStream) Context() context.Context { return s.ctx }
+
+// authenticate resolves the caller: a verified client certificate bound
+// to a token wins, as over HTTP, then bearer metadata. With a rate
+// limiter, every failure takes a token from the peer IP's AuthFailures
+// bucket, and once it is empty calls are refused before any credential
+// is checked.
+func authenticate(ctx context.Context, authn auth.Authenticator, o *options) (*auth.Identity, error) {
+	rl := o.limiter
+	addr := peerAddr(ctx)
+	if rl != nil {
+		if blocked, retryAfter := rl.AuthBlocked(addr); blocked {
+			return nil, status.Errorf(codes.ResourceExhausted, "too many failed authentication attempts, retry in %s", retryAfter.Round(time.Millisecond))
+		}
+	}
+	if o.certs != nil {
+		if p, ok := peer.FromContext(ctx); ok {
+			if info, ok := p.AuthInfo.(credentials.TLSInfo); ok && len(info.State.VerifiedChains) > 0 {
+				if id, err := o.certs.LookupCert(auth.CertNames(info.State.VerifiedChains[0][0])); err == nil {
+					return id, nil
+				}
+			}
+		}
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, header := range md.Get("authorization") {
+		// must be "Bearer <token>"
+		parts := strings.SplitN(header, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			continue
+		}
+		if id, err := authn.Authenticate(parts[1]); err == nil {
+			return id, nil
+		}
+	}
+	if rl != nil {
+		rl.AuthFailed(addr)
+	}
+	return nil, status.Error(codes.Unauthenticated, "unauthorized")
+}
+
+// throttle takes a token in class for the caller in ctx.
+func throttle(ctx context.Context, rl *middleware.RateLimiter, class auth.OpClass) error {
+	if rl == nil {
+		return nil
+	}
+	id, _ := auth.FromContext(ctx)
+	if ok, retryAfter := rl.Allow(class, middleware.Caller(id, peerAddr(ctx))); !ok {
+		return status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", retryAfter.Round(time.Millisecond))
+	}
+	return nil
+}
+
+// peerAddr is the caller's address, or "" if unknown.
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// authorize checks the identity in ctx against class and key.
+func authorize(ctx context.Context, class auth.OpClass, key string) error {
+	id, _ := auth.FromContext(ctx)
+	if id.Allows(class, key) {
+		return nil
+	}
+	name := ""
+	if id != nil {
+		name = id.Name
+	}
+	return status.Errorf(codes.PermissionDenied, "forbidden: token %q may not %s key %q", name, class, key)
+}
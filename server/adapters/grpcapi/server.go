@@ -0,0 +1,261 @@
+// Package grpcapi exposes the StoreService over gRPC, alongside the
+// HTTP/JSON router in package adapters.
+package grpcapi
+
+import (
+	"context"
+	"data_storage/api/storepb"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/store_service"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Server implements storepb.StoreServer on top of the StoreService.
+type Server struct {
+	storepb.UnimplementedStoreServer
+	storeService store_service.StoreServiceRepo
+}
+
+// Option configures a server built by NewServer, and its interceptors.
+type Option func(*options)
+
+type options struct {
+	certs   *auth.Registry
+	limiter *middleware.RateLimiter
+	server  []grpc.ServerOption
+}
+
+// WithClientCerts authenticates callers that present a verified client
+// certificate as the token in reg bound to it, as the HTTP API does.
+func WithClientCerts(reg *auth.Registry) Option {
+	return func(o *options) {
+		o.certs = reg
+	}
+}
+
+// WithRateLimiter throttles calls per caller and failed authentication
+// per client IP with the HTTP API's buckets.
+func WithRateLimiter(rl *middleware.RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = rl
+	}
+}
+
+// WithServerOptions passes extra options (e.g. TLS credentials) to
+// grpc.NewServer, after the interceptors.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) {
+		o.server = append(o.server, opts...)
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// NewServer builds a *grpc.Server with interceptors authenticating
+// callers by bearer token with authn, or by client certificate, and
+// the Store service registered.
+func NewServer(s store_service.StoreServiceRepo, authn auth.Authenticator, opts ...Option) *grpc.Server {
+	o := newOptions(opts)
+	srv := grpc.NewServer(append([]grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(unaryAuth(authn, o)),
+		grpc.ChainStreamInterceptor(streamAuth(authn, o)),
+	}, o.server...)...)
+	storepb.RegisterStoreServer(srv, &Server{storeService: s})
+	return srv
+}
+
+func (s *Server) SetString(ctx context.Context, req *storepb.SetStringRequest) (*storepb.SetStringResponse, error) {
+	ttl := time.Duration(req.GetTtlMs()) * time.Millisecond
+	if err := s.storeService.SetString(ctx, req.GetKey(), req.GetValue(), ttl); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.SetStringResponse{}, nil
+}
+
+func (s *Server) GetString(ctx context.Context, req *storepb.GetStringRequest) (*storepb.GetStringResponse, error) {
+	value, err := s.storeService.GetString(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.GetStringResponse{Value: value}, nil
+}
+
+func (s *Server) DeleteString(ctx context.Context, req *storepb.DeleteStringRequest) (*storepb.DeleteStringResponse, error) {
+	if err := s.storeService.DeleteString(ctx, req.GetKey()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.DeleteStringResponse{}, nil
+}
+
+func (s *Server) SetBlob(ctx context.Context, req *storepb.SetBlobRequest) (*storepb.SetBlobResponse, error) {
+	ttl := time.Duration(req.GetTtlMs()) * time.Millisecond
+	if err := s.storeService.SetBlob(ctx, req.GetKey(), req.GetData(), req.GetContentType(), "", ttl); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.SetBlobResponse{}, nil
+}
+
+func (s *Server) GetBlob(ctx context.Context, req *storepb.GetBlobRequest) (*storepb.GetBlobResponse, error) {
+	entry, err := s.storeService.GetBlob(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	data := entry.Str
+	offset, length := req.GetOffset(), req.GetLength()
+	if offset < 0 || length < 0 || offset >= int64(len(data)) {
+		return nil, status.Errorf(codes.OutOfRange, "range %d+%d outside a %d byte value", offset, length, len(data))
+	}
+	data = data[offset:]
+	if length > 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+	return &storepb.GetBlobResponse{Data: []byte(data), ContentType: entry.ContentType, Cas: entry.CAS}, nil
+}
+
+func (s *Server) LPush(ctx context.Context, req *storepb.LPushRequest) (*storepb.LPushResponse, error) {
+	if err := s.storeService.LPush(ctx, req.GetKey(), req.GetItems()...); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.LPushResponse{}, nil
+}
+
+func (s *Server) RPop(ctx context.Context, req *storepb.RPopRequest) (*storepb.RPopResponse, error) {
+	value, err := s.storeService.RPop(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.RPopResponse{Value: value}, nil
+}
+
+func (s *Server) LLen(ctx context.Context, req *storepb.LLenRequest) (*storepb.LLenResponse, error) {
+	n, err := s.storeService.LLen(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.LLenResponse{Length: int64(n)}, nil
+}
+
+func (s *Server) Incr(ctx context.Context, req *storepb.IncrRequest) (*storepb.IncrResponse, error) {
+	n, err := s.storeService.Incr(ctx, req.GetKey(), req.GetDelta())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.IncrResponse{Value: n}, nil
+}
+
+func (s *Server) Decr(ctx context.Context, req *storepb.DecrRequest) (*storepb.DecrResponse, error) {
+	n, err := s.storeService.Decr(ctx, req.GetKey(), req.GetDelta())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.DecrResponse{Value: n}, nil
+}
+
+func (s *Server) Expire(ctx context.Context, req *storepb.ExpireRequest) (*storepb.ExpireResponse, error) {
+	ttl := time.Duration(req.GetTtlMs()) * time.Millisecond
+	if err := s.storeService.Expire(ctx, req.GetKey(), ttl); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.ExpireResponse{}, nil
+}
+
+func (s *Server) TTL(ctx context.Context, req *storepb.TTLRequest) (*storepb.TTLResponse, error) {
+	left, err := s.storeService.TTL(ctx, req.GetKey())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if left < 0 {
+		return &storepb.TTLResponse{TtlMs: -1}, nil
+	}
+	return &storepb.TTLResponse{TtlMs: left.Milliseconds()}, nil
+}
+
+func (s *Server) Touch(ctx context.Context, req *storepb.TouchRequest) (*storepb.TouchResponse, error) {
+	var expiry time.Time
+	if ttl := time.Duration(req.GetTtlMs()) * time.Millisecond; ttl > 0 {
+		expiry = time.Now().Add(ttl)
+	}
+	if err := s.storeService.Touch(ctx, req.GetKey(), expiry); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.TouchResponse{}, nil
+}
+
+func (s *Server) FlushAll(ctx context.Context, _ *storepb.FlushAllRequest) (*storepb.FlushAllResponse, error) {
+	if err := s.storeService.FlushAll(ctx); err != nil {
+		return nil, toStatus(err)
+	}
+	return &storepb.FlushAllResponse{}, nil
+}
+
+// Watch streams keyspace events until the client cancels.
+func (s *Server) Watch(req *storepb.WatchRequest, stream storepb.Store_WatchServer) error {
+	if err := authorize(stream.Context(), auth.OpRead, auth.PatternPrefix(req.GetPattern())); err != nil {
+		return err
+	}
+	events, err := s.storeService.Watch(stream.Context(), req.GetPattern(), req.GetAfterId())
+	if err != nil {
+		return toStatus(err)
+	}
+	// flush headers so the client knows the subscription is live
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+	for ev := range events {
+		err := stream.Send(&storepb.Event{
+			Id:           ev.ID,
+			Type:         string(ev.Type),
+			Key:          ev.Key,
+			TimeUnixNano: ev.Time.UnixNano(),
+			Namespace:    ev.Namespace,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if err := stream.Context().Err(); err != nil {
+		return status.FromContextError(err).Err()
+	}
+	// the hub dropped a slow watcher; the client resumes by ID
+	return status.Error(codes.Unavailable, "watch stream fell behind")
+}
+
+// toStatus maps domain errors onto gRPC status codes.
+func toStatus(err error) error {
+	code := codes.Internal
+	switch {
+	case errors.Is(err, domain.ErrNotFound),
+		errors.Is(err, domain.ErrExpiredEntry):
+		code = codes.NotFound
+	case errors.Is(err, domain.ErrWrongType),
+		errors.Is(err, domain.ErrEmptyEntry),
+		errors.Is(err, domain.ErrNotInteger):
+		code = codes.FailedPrecondition
+	case errors.Is(err, domain.ErrEmptyKey),
+		errors.Is(err, domain.ErrEmptyValue),
+		errors.Is(err, domain.ErrInvalidPattern),
+		errors.Is(err, domain.ErrInvalidContentType),
+		errors.Is(err, domain.ErrKeyTooLong),
+		errors.Is(err, domain.ErrValueTooLarge),
+		errors.Is(err, domain.ErrTooManyItems),
+		errors.Is(err, domain.ErrListTooLong):
+		code = codes.InvalidArgument
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		code = codes.ResourceExhausted
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,126 @@
+package adapters
+
+import (
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/domain"
+	"github.com/gorilla/mux"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// putBlob handles PUT /v1/blob/{key}?ttl_seconds=N. The body is stored
+// as is, along with its Content-Type; a gzip Content-Encoding is kept
+// too, so the value is stored compressed without recompressing it.
+func (h *Handlers) putBlob(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	key := mux.Vars(req)["key"]
+	if key == "" {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid key")
+		return
+	}
+
+	var ttl time.Duration
+	if raw := req.URL.Query().Get("ttl_seconds"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid ttl_seconds")
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if msg, ok := middleware.TooLarge(err); ok {
+		writeErrorJSON(w, http.StatusRequestEntityTooLarge, msg)
+		return
+	}
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, "read body")
+		return
+	}
+
+	if err := h.storeService.SetBlob(req.Context(), key, data, req.Header.Get("Content-Type"), req.Header.Get("Content-Encoding"), ttl); err != nil {
+		writeErrorJSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// getBlob handles GET and HEAD /v1/blob/{key}, answering with the stored
+// Content-Type and the entry's CAS as its ETag. Callers accepting gzip
+// get values stored compressed as they are, with Content-Encoding: gzip.
+// Range and conditional requests are served by http.ServeContent.
+func (h *Handlers) getBlob(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	key := mux.Vars(req)["key"]
+	if key == "" {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid key")
+		return
+	}
+
+	var entry *domain.Entry
+	var err error
+	if acceptsGzip(req.Header.Get("Accept-Encoding")) {
+		entry, err = h.storeService.GetBlobEncoded(req.Context(), key)
+	} else {
+		entry, err = h.storeService.GetBlob(req.Context(), key)
+	}
+	if err != nil {
+		writeErrorJSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	etag := strconv.FormatUint(entry.CAS, 10)
+	if entry.Gzipped() {
+		// ranges and validators apply to the encoded bytes
+		w.Header().Set("Content-Encoding", domain.EncodingGzip)
+		etag += "-gzip"
+	}
+	w.Header().Set("Vary", "Accept-Encoding")
+	w.Header().Set("Content-Type", entry.ContentType)
+	w.Header().Set("ETag", `"`+etag+`"`)
+	http.ServeContent(w, req, "", time.Time{}, strings.NewReader(entry.Str))
+}
+
+// acceptsGzip reports whether an Accept-Encoding header allows gzip.
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q, ok := strings.CutPrefix(strings.TrimSpace(params), "q=")
+		if !ok {
+			return true
+		}
+		if v, err := strconv.ParseFloat(q, 64); err == nil && v > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteBlob handles DELETE /v1/blob/{key}.
+func (h *Handlers) deleteBlob(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	key := mux.Vars(req)["key"]
+	if key == "" {
+		writeErrorJSON(w, http.StatusBadRequest, "invalid key")
+		return
+	}
+
+	if err := h.storeService.DeleteString(req.Context(), key); err != nil {
+		writeErrorJSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,77 @@
+package adapters
+
+import (
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// ServerInfo is what /v1/admin/info reports besides live statistics.
+type ServerInfo struct {
+	Version string
+	Started time.Time
+	Repo    *storage.Data
+	// Config lists the effective, non-secret settings, which change
+	// when the configuration is reloaded.
+	Config func() map[string]string
+}
+
+// getInfo handles GET /v1/admin/info, a JSON take on Redis INFO.
+func (h *Handlers) getInfo(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	info := h.info
+	now := time.Now()
+	resp := infoResponse{
+		Server: infoServer{
+			Version:       info.Version,
+			GoVersion:     runtime.Version(),
+			StartedAt:     info.Started.UTC(),
+			UptimeSeconds: int64(now.Sub(info.Started).Seconds()),
+		},
+		Keyspace:    infoKeyspace{ByType: map[string]int{}},
+		Subscribers: infoSubscribers{Watchers: info.Repo.Events().Subscribers()},
+		Limits: infoLimits{
+			MaxBodyBytes:  h.maxBody,
+			MaxKeyLength:  h.limits.MaxKeyLength,
+			MaxValueBytes: h.limits.MaxValueBytes,
+			MaxPushItems:  h.limits.MaxPushItems,
+			MaxListLength: h.limits.MaxListLength,
+		},
+		Config: info.Config(),
+	}
+
+	stats := info.Repo.Stats()
+	for _, typ := range []domain.ValueType{domain.TypeString, domain.TypeList} {
+		resp.Keyspace.ByType[typ.String()] = stats.Keys[typ]
+		resp.Keyspace.Keys += stats.Keys[typ]
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	resp.Memory = infoMemory{StoreBytes: stats.Bytes, HeapAllocBytes: ms.HeapAlloc, SysBytes: ms.Sys}
+
+	resp.Expiry = infoExpiry{
+		ExpiredKeys:         stats.Expired,
+		SweepIntervalMS:     stats.Sweep.Interval.Milliseconds(),
+		Sweeps:              stats.Sweep.Runs,
+		LastSweepDurationMS: float64(stats.Sweep.LastDuration.Microseconds()) / 1000,
+		LastSweepExpired:    stats.Sweep.LastExpired,
+	}
+	if !stats.Sweep.LastRun.IsZero() {
+		last := stats.Sweep.LastRun.UTC()
+		resp.Expiry.LastSweepAt = &last
+	}
+	if h.webhooks != nil {
+		resp.Subscribers.Webhooks = len(h.webhooks.List())
+	}
+	if h.monitor != nil {
+		resp.Subscribers.Monitors = h.monitor.Subscribers()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
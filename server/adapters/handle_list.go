@@ -18,17 +18,12 @@ func (h *Handlers) pushList(w http.ResponseWriter, req *http.Request) {
 	}
 
 	var body listRequest
-	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid JSON")
+	if !decodeJSON(w, req, &body) {
 		return
 	}
 
 	if err := h.storeService.LPush(req.Context(), key, body.Items...); err != nil {
-		status := http.StatusBadRequest
-		if !isClientError(err) {
-			status = http.StatusInternalServerError
-		}
-		writeErrorJSON(w, status, err.Error())
+		writeErrorJSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -47,11 +42,7 @@ func (h *Handlers) popList(w http.ResponseWriter, req *http.Request) {
 
 	value, err := h.storeService.RPop(req.Context(), key)
 	if err != nil {
-		status := http.StatusBadRequest
-		if !isClientError(err) {
-			status = http.StatusInternalServerError
-		}
-		writeErrorJSON(w, status, err.Error())
+		writeErrorJSON(w, errorStatus(err), err.Error())
 		return
 	}
 
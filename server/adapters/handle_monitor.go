@@ -0,0 +1,66 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// streamMonitor handles GET /v1/admin/monitor. Every executed command is
+// streamed as a Server-Sent Event "command". When the stream falls
+// behind, events are dropped rather than slowing the server, and a
+// "dropped" event reports how many were lost.
+func (h *Handlers) streamMonitor(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorJSON(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	sub := h.monitor.Subscribe(req.Context())
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+
+	var reported uint64
+	for {
+		select {
+		case ev, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if dropped := sub.Dropped(); dropped > reported {
+				if _, err := fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped-reported); err != nil {
+					return
+				}
+				reported = dropped
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "event: command\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		case <-h.draining:
+			return
+		}
+	}
+}
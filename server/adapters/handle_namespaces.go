@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// listNamespaces handles GET /v1/admin/namespaces: every namespace the
+// caller may use that holds keys or settings.
+func (h *Handlers) listNamespaces(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	id, _ := auth.FromContext(req.Context())
+	out := []namespaceResponse{}
+	for _, ns := range h.namespaces.Namespaces() {
+		if id.CanUseNamespace(ns.Name) {
+			out = append(out, toNamespaceResponse(ns))
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(out)
+}
+
+// getNamespace handles GET /v1/admin/namespaces/{ns}; an unused
+// namespace reports no keys.
+func (h *Handlers) getNamespace(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	ns := domain.NamespaceFrom(req.Context())
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toNamespaceResponse(h.namespaces.Namespace(ns)))
+}
+
+// putNamespace handles PUT /v1/admin/namespaces/{ns}, which sets the
+// default TTL of keys written without one (0 restores the server's) and
+// the namespace's quota (a 0 limit restores the server's default).
+func (h *Handlers) putNamespace(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var body namespaceRequest
+	if !decodeJSON(w, req, &body) {
+		return
+	}
+	if negative(body.DefaultTTLSeconds) || negative(body.MaxKeys) || negative(body.MaxListLength) ||
+		(body.MaxBytes != nil && *body.MaxBytes < 0) {
+		writeErrorJSON(w, http.StatusBadRequest, "default_ttl_seconds and quota limits must not be negative")
+		return
+	}
+
+	ns := domain.NamespaceFrom(req.Context())
+	if body.DefaultTTLSeconds != nil {
+		h.namespaces.SetDefaultTTL(ns, time.Duration(*body.DefaultTTLSeconds)*time.Second)
+	}
+	if body.MaxKeys != nil || body.MaxBytes != nil || body.MaxListLength != nil {
+		q := h.namespaces.QuotaOverride(ns)
+		if body.MaxKeys != nil {
+			q.MaxKeys = *body.MaxKeys
+		}
+		if body.MaxBytes != nil {
+			q.MaxBytes = *body.MaxBytes
+		}
+		if body.MaxListLength != nil {
+			q.MaxListLength = *body.MaxListLength
+		}
+		h.namespaces.SetQuota(ns, q)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(toNamespaceResponse(h.namespaces.Namespace(ns)))
+}
+
+func negative(v *int) bool {
+	return v != nil && *v < 0
+}
+
+// flushNamespace handles POST /v1/admin/namespaces/{ns}/flush, which
+// deletes every key of the namespace and keeps its settings.
+func (h *Handlers) flushNamespace(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	if err := h.storeService.FlushAll(req.Context()); err != nil {
+		writeErrorJSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func toNamespaceResponse(ns storage.NamespaceStats) namespaceResponse {
+	resp := namespaceResponse{
+		Name:              ns.Name,
+		ByType:            map[string]int{},
+		Bytes:             ns.Bytes,
+		DefaultTTLSeconds: int(ns.DefaultTTL / time.Second),
+		Quota: quotaResponse{
+			MaxKeys:       ns.Quota.MaxKeys,
+			MaxBytes:      ns.Quota.MaxBytes,
+			MaxListLength: ns.Quota.MaxListLength,
+		},
+	}
+	for _, typ := range []domain.ValueType{domain.TypeString, domain.TypeList} {
+		resp.ByType[typ.String()] = ns.Keys[typ]
+		resp.Keys += ns.Keys[typ]
+	}
+	return resp
+}
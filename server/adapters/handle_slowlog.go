@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// getSlowLog handles GET /v1/admin/slowlog?count=N, newest first; all
+// entries without count.
+func (h *Handlers) getSlowLog(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	count := 0
+	if v := req.URL.Query().Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeErrorJSON(w, http.StatusBadRequest, "count must be a non-negative integer")
+			return
+		}
+		count = n
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(slowLogResponse{
+		ThresholdMS: float64(h.slowlog.Threshold().Microseconds()) / 1000,
+		Capacity:    h.slowlog.Capacity(),
+		Len:         h.slowlog.Len(),
+		Entries:     h.slowlog.Entries(count),
+	})
+}
+
+// resetSlowLog handles DELETE /v1/admin/slowlog.
+func (h *Handlers) resetSlowLog(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+	h.slowlog.Reset()
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -4,7 +4,7 @@ package adapters
 import (
 	"encoding/json"
 	"github.com/gorilla/mux"
-	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
@@ -20,20 +20,15 @@ func (h *Handlers) setString(w http.ResponseWriter, req *http.Request) {
 	}
 
 	var body stringRequest
-	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
-		writeErrorJSON(w, http.StatusBadRequest, "invalid JSON")
+	if !decodeJSON(w, req, &body) {
 		return
 	}
 
-	log.Printf("[Handler] raw TTLSeconds=%d (from JSON)", body.TTLSeconds)
+	slog.DebugContext(req.Context(), "set string", "key", key, "ttl_seconds", body.TTLSeconds)
 	ttl := time.Duration(body.TTLSeconds) * time.Second
 
 	if err := h.storeService.SetString(req.Context(), key, body.Value, ttl); err != nil {
-		status := http.StatusBadRequest
-		if !isClientError(err) {
-			status = http.StatusInternalServerError
-		}
-		writeErrorJSON(w, status, err.Error())
+		writeErrorJSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -52,11 +47,7 @@ func (h *Handlers) getString(w http.ResponseWriter, req *http.Request) {
 
 	value, err := h.storeService.GetString(req.Context(), key)
 	if err != nil {
-		status := http.StatusBadRequest
-		if !isClientError(err) {
-			status = http.StatusInternalServerError
-		}
-		writeErrorJSON(w, status, err.Error())
+		writeErrorJSON(w, errorStatus(err), err.Error())
 		return
 	}
 
@@ -76,11 +67,7 @@ func (h *Handlers) deleteString(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if err := h.storeService.DeleteString(req.Context(), key); err != nil {
-		status := http.StatusBadRequest
-		if !isClientError(err) {
-			status = http.StatusInternalServerError
-		}
-		writeErrorJSON(w, status, err.Error())
+		writeErrorJSON(w, errorStatus(err), err.Error())
 		return
 	}
 
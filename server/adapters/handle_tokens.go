@@ -0,0 +1,72 @@
+package adapters
+
+import (
+	"data_storage/server/auth"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// putToken handles POST /v1/admin/tokens. It creates or replaces the
+// named token and returns its secret; this is the only response that
+// ever includes it.
+func (h *Handlers) putToken(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var body tokenRequest
+	if !decodeJSON(w, req, &body) {
+		return
+	}
+
+	token, err := h.tokens.Put(auth.Token{
+		Name:         body.Name,
+		Token:        body.Token,
+		Prefixes:     body.Prefixes,
+		Classes:      body.Classes,
+		Namespaces:   body.Namespaces,
+		CertSubjects: body.CertSubjects,
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrInvalidToken) {
+			status = http.StatusBadRequest
+		} else if errors.Is(err, auth.ErrBuiltinToken) {
+			status = http.StatusConflict
+		}
+		writeErrorJSON(w, status, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(token)
+}
+
+// listTokens handles GET /v1/admin/tokens. Secrets are never echoed back.
+func (h *Handlers) listTokens(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]auth.Token{"tokens": h.tokens.List()})
+}
+
+// deleteToken handles DELETE /v1/admin/tokens/{name}.
+func (h *Handlers) deleteToken(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	name := mux.Vars(req)["name"]
+	if err := h.tokens.Remove(name); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrTokenNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, auth.ErrBuiltinToken) {
+			status = http.StatusConflict
+		}
+		writeErrorJSON(w, status, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
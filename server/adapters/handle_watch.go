@@ -0,0 +1,87 @@
+package adapters
+
+import (
+	"data_storage/server/events"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// watchHeartbeat keeps idle watch streams alive through proxies.
+const watchHeartbeat = 15 * time.Second
+
+// watch handles GET /v1/watch?key=<key> or ?pattern=<glob>.
+// Events are streamed as Server-Sent Events; a reconnecting client
+// resumes by sending the last seen ID in the Last-Event-ID header.
+func (h *Handlers) watch(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeErrorJSON(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	q := req.URL.Query()
+	pattern := q.Get("pattern")
+	if key := q.Get("key"); key != "" {
+		pattern = events.Escape(key)
+	}
+
+	var afterID uint64
+	lastID := req.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = q.Get("last_event_id")
+	}
+	if lastID != "" {
+		id, err := strconv.ParseUint(lastID, 10, 64)
+		if err != nil {
+			writeErrorJSON(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		afterID = id
+	}
+
+	stream, err := h.storeService.Watch(req.Context(), pattern, afterID)
+	if err != nil {
+		writeErrorJSON(w, errorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(watchHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case ev, ok := <-stream:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(ev)
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", ev.ID, ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			return
+		case <-h.draining:
+			return
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package adapters
+
+import (
+	"data_storage/server/domain"
+	"data_storage/server/webhooks"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// createWebhook handles POST /v1/webhooks.
+func (h *Handlers) createWebhook(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	var body webhookRequest
+	if !decodeJSON(w, req, &body) {
+		return
+	}
+
+	hook, err := h.webhooks.Register(webhooks.Webhook{
+		Pattern: body.Pattern,
+		Events:  body.Events,
+		URL:     body.URL,
+		Secret:  body.Secret,
+	})
+	if err != nil {
+		writeErrorJSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	hook.Secret = ""
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(hook)
+}
+
+// listWebhooks handles GET /v1/webhooks. Secrets are never echoed back.
+func (h *Handlers) listWebhooks(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	hooks := h.webhooks.List()
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string][]webhooks.Webhook{"webhooks": hooks})
+}
+
+// deleteWebhook handles DELETE /v1/webhooks/{id}.
+func (h *Handlers) deleteWebhook(w http.ResponseWriter, req *http.Request) {
+	defer req.Body.Close()
+
+	id := mux.Vars(req)["id"]
+	if err := h.webhooks.Remove(id); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		writeErrorJSON(w, status, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,385 @@
+package adapters
+
+import (
+	"context"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	wsWriteWait  = 10 * time.Second
+	wsPongWait   = 60 * time.Second
+	wsPingPeriod = wsPongWait * 9 / 10
+	wsOutBuffer  = 256
+	wsMaxFrame   = 1 << 20 // unless WithLimits sets a body limit
+	wsMaxSubs    = 64      // watch subscriptions per socket
+)
+
+var (
+	// errDraining stops a WebSocket read loop during shutdown.
+	errDraining = errors.New("server draining")
+
+	// errTooManySubs refuses a watch beyond wsMaxSubs on one socket.
+	errTooManySubs = fmt.Errorf("too many subscriptions on this socket (at most %d)", wsMaxSubs)
+)
+
+// upgrader returns the WebSocket upgrader. Browsers send credentials
+// such as client certificates with cross-site requests too, so only
+// pages from the API's own origin or an allowed one may connect.
+func (h *Handlers) upgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  4096,
+		WriteBufferSize: 4096,
+		CheckOrigin:     h.checkOrigin,
+	}
+}
+
+// checkOrigin accepts requests without an Origin header (non-browser
+// clients), same-origin ones and those from an allowed origin.
+func (h *Handlers) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, allowed := range h.wsOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// wsSession is the per-socket state: outbound frames and watch
+// subscriptions multiplexed onto the same connection.
+type wsSession struct {
+	ctx        context.Context
+	identity   *auth.Identity
+	remoteAddr string
+	out        chan interface{}
+	subs       map[string]context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// websocket handles GET /v1/ws. Clients send wsRequest frames and get a
+// wsResponse with the same ID; watch subscriptions push wsPush frames.
+func (h *Handlers) websocket(w http.ResponseWriter, req *http.Request) {
+	if h.sockets != nil {
+		h.sockets.Add(1)
+		defer h.sockets.Done()
+	}
+	conn, err := h.upgrader().Upgrade(w, req, nil)
+	if err != nil {
+		// the upgrader has already replied with an HTTP error
+		return
+	}
+	defer conn.Close()
+
+	identity, _ := auth.FromContext(req.Context())
+	// frames keep the upgrade request's namespace, identity, request ID
+	// and trace, but outlive the request itself
+	ctx, cancel := context.WithCancel(context.WithoutCancel(req.Context()))
+	sess := &wsSession{
+		ctx:        ctx,
+		identity:   identity,
+		remoteAddr: req.RemoteAddr,
+		out:        make(chan interface{}, wsOutBuffer),
+		subs:       make(map[string]context.CancelFunc),
+	}
+
+	writerDone := make(chan struct{})
+	go func() {
+		defer close(writerDone)
+		wsWritePump(conn, sess.out, cancel)
+	}()
+
+	maxFrame := int64(wsMaxFrame)
+	if h.maxBody > 0 {
+		maxFrame = h.maxBody
+	}
+	conn.SetReadLimit(maxFrame)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		select {
+		case <-h.draining:
+			return errDraining
+		default:
+		}
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// a drain interrupts the read below; the loop then exits as if the
+	// peer had gone and the write pump sends the close frame
+	go func() {
+		select {
+		case <-h.draining:
+			conn.SetReadDeadline(time.Now())
+		case <-ctx.Done():
+		}
+	}()
+
+	for {
+		var frame wsRequest
+		if err := conn.ReadJSON(&frame); err != nil {
+			var syntaxErr *json.SyntaxError
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+				sess.send(wsResponse{Error: &wsError{Code: http.StatusBadRequest, Message: "invalid JSON frame"}})
+				continue
+			}
+			// closed by the peer, timed out or failed to write
+			break
+		}
+		sess.send(h.wsExecute(sess, frame))
+	}
+
+	cancel()
+	sess.wg.Wait()
+	close(sess.out)
+	<-writerDone
+}
+
+// wsWritePump serialises all writes to conn and keeps it alive with pings.
+func wsWritePump(conn *websocket.Conn, out <-chan interface{}, cancel context.CancelFunc) {
+	ticker := time.NewTicker(wsPingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, ok := <-out:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if !ok {
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+			if err := conn.WriteJSON(msg); err != nil {
+				cancel()
+				conn.Close()
+				// drain so producers never block on a dead socket
+				for range out {
+				}
+				return
+			}
+		case <-ticker.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				cancel()
+				conn.Close()
+				for range out {
+				}
+				return
+			}
+		}
+	}
+}
+
+// send queues a frame unless the session is shutting down.
+func (s *wsSession) send(msg interface{}) {
+	select {
+	case s.out <- msg:
+	case <-s.ctx.Done():
+	}
+}
+
+// wsExecute runs one command frame against the StoreService.
+func (h *Handlers) wsExecute(sess *wsSession, f wsRequest) (resp wsResponse) {
+	if h.audit != nil && wsMutates(f.Op) {
+		start := time.Now()
+		defer func() { h.auditFrame(sess, f, resp, start) }()
+	}
+
+	if class, ok := wsOpClass(f.Op); ok {
+		key := f.Key
+		if f.Op == "watch" {
+			key = watchPrefix("", f.Pattern)
+		}
+		if !sess.identity.Allows(class, key) {
+			return wsResponse{ID: f.ID, Error: &wsError{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("forbidden: %s access to %q denied", class, key),
+			}}
+		}
+		// frames share the caller's HTTP buckets, which the upgrade
+		// request itself does not touch
+		if h.limiter != nil {
+			if ok, retryAfter := h.limiter.Allow(class, middleware.Caller(sess.identity, sess.remoteAddr)); !ok {
+				return wsResponse{ID: f.ID, Error: &wsError{
+					Code:    http.StatusTooManyRequests,
+					Message: fmt.Sprintf("rate limit exceeded, retry in %s", retryAfter.Round(time.Millisecond)),
+				}}
+			}
+		}
+	}
+
+	ctx := sess.ctx
+	ttl := time.Duration(f.TTLSeconds) * time.Second
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch f.Op {
+	case "ping":
+		result = "pong"
+	case "set":
+		err = h.storeService.SetString(ctx, f.Key, f.Value, ttl)
+	case "get":
+		result, err = h.storeService.GetString(ctx, f.Key)
+	case "del":
+		err = h.storeService.DeleteString(ctx, f.Key)
+	case "store":
+		result, err = h.wsStore(ctx, f)
+	case "get_entry":
+		var entry *domain.Entry
+		if entry, err = h.storeService.GetStringEntry(ctx, f.Key); err == nil {
+			result = map[string]interface{}{"value": entry.Str, "flags": entry.Flags, "cas": entry.CAS}
+		}
+	case "incr":
+		result, err = h.storeService.Incr(ctx, f.Key, f.Delta)
+	case "decr":
+		result, err = h.storeService.Decr(ctx, f.Key, f.Delta)
+	case "lpush":
+		err = h.storeService.LPush(ctx, f.Key, f.Items...)
+	case "rpop":
+		result, err = h.storeService.RPop(ctx, f.Key)
+	case "llen":
+		result, err = h.storeService.LLen(ctx, f.Key)
+	case "expire":
+		err = h.storeService.Expire(ctx, f.Key, ttl)
+	case "ttl":
+		var left time.Duration
+		if left, err = h.storeService.TTL(ctx, f.Key); err == nil {
+			result = int64(-1)
+			if left >= 0 {
+				result = int64(left / time.Second)
+			}
+		}
+	case "touch":
+		var expiry time.Time
+		if ttl > 0 {
+			expiry = time.Now().Add(ttl)
+		}
+		err = h.storeService.Touch(ctx, f.Key, expiry)
+	case "flush_all":
+		err = h.storeService.FlushAll(ctx)
+	case "watch":
+		result, err = h.wsWatch(sess, f)
+	case "unwatch":
+		err = sess.unwatch(f.Subscription)
+	default:
+		return wsResponse{ID: f.ID, Error: &wsError{Code: http.StatusBadRequest, Message: "unknown op " + strconv.Quote(f.Op)}}
+	}
+
+	if err != nil {
+		return wsResponse{ID: f.ID, Error: &wsError{Code: errorStatus(err), Message: err.Error()}}
+	}
+	return wsResponse{ID: f.ID, OK: true, Result: result}
+}
+
+// wsStore maps the "store" op onto StoreString; the mode selects set,
+// add, replace or cas semantics and the new CAS is returned.
+func (h *Handlers) wsStore(ctx context.Context, f wsRequest) (uint64, error) {
+	write := domain.StringWrite{Value: f.Value, Flags: f.Flags, CAS: f.CAS}
+	if f.TTLSeconds > 0 {
+		write.Expiry = time.Now().Add(time.Duration(f.TTLSeconds) * time.Second)
+	}
+	switch f.Mode {
+	case "", "set":
+		write.Mode = domain.SetAlways
+	case "add":
+		write.Mode = domain.SetIfAbsent
+	case "replace":
+		write.Mode = domain.SetIfPresent
+	case "cas":
+		write.Mode = domain.SetIfCAS
+	default:
+		return 0, domain.ErrInvalidMode
+	}
+	return h.storeService.StoreString(ctx, f.Key, write)
+}
+
+// wsWatch starts a subscription whose events are pushed on the socket.
+// The subscription ID is the request ID, or a generated one.
+func (h *Handlers) wsWatch(sess *wsSession, f wsRequest) (string, error) {
+	id := f.ID
+	if id == "" {
+		id = "sub-" + strconv.Itoa(len(sess.subs)+1)
+	}
+	if _, exists := sess.subs[id]; exists {
+		return "", domain.ErrKeyExists
+	}
+	if len(sess.subs) >= wsMaxSubs {
+		return "", errTooManySubs
+	}
+
+	ctx, cancel := context.WithCancel(sess.ctx)
+	stream, err := h.storeService.Watch(ctx, f.Pattern, f.AfterID)
+	if err != nil {
+		cancel()
+		return "", err
+	}
+	sess.subs[id] = cancel
+
+	sess.wg.Add(1)
+	go func() {
+		defer sess.wg.Done()
+		for ev := range stream {
+			sess.send(wsPush{Type: "event", Subscription: id, Event: ev})
+		}
+	}()
+	return id, nil
+}
+
+// unwatch cancels the subscription with the given ID.
+func (s *wsSession) unwatch(id string) error {
+	cancel, ok := s.subs[id]
+	if !ok {
+		return domain.ErrNotFound
+	}
+	cancel()
+	delete(s.subs, id)
+	return nil
+}
+
+// auditFrame records a mutating frame and its outcome.
+func (h *Handlers) auditFrame(sess *wsSession, f wsRequest, resp wsResponse, start time.Time) {
+	status := http.StatusOK
+	if resp.Error != nil {
+		status = resp.Error.Code
+	}
+	value := f.Value
+	if len(f.Items) > 0 {
+		items, _ := json.Marshal(f.Items)
+		value = string(items)
+	}
+	var identity string
+	if sess.identity != nil {
+		identity = sess.identity.Name
+	}
+	h.audit.Log(audit.Record{
+		Time:       start,
+		Identity:   identity,
+		RemoteAddr: sess.remoteAddr,
+		Transport:  "ws",
+		Operation:  f.Op,
+		Namespace:  domain.NamespaceFrom(sess.ctx),
+		Key:        f.Key,
+		Value:      value,
+		Status:     status,
+		Outcome:    audit.OutcomeFor(status),
+		LatencyMS:  float64(time.Since(start).Microseconds()) / 1000,
+	})
+}
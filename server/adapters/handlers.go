@@ -2,33 +2,308 @@ package adapters
 
 import (
 	"data_storage/server/adapters/middleware"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/health"
+	"data_storage/server/metrics"
+	"data_storage/server/monitor"
+	"data_storage/server/slowlog"
+	"data_storage/server/storage"
 	"data_storage/server/store_service"
+	"data_storage/server/webhooks"
 	"github.com/gorilla/mux"
 	"net/http"
+	"sync"
 )
 
 // Handlers holds references to the use-case service.
 type Handlers struct {
 	storeService store_service.StoreServiceRepo
+	webhooks     *webhooks.Dispatcher
+	tokens       *auth.Registry
+	authn        auth.Authenticator
+	signatures   *auth.RequestVerifier
+	signedOnly   bool
+	limiter      *middleware.RateLimiter
+	audit        *audit.Logger
+	metrics      *metrics.Registry
+	metricsRoute bool
+	readiness    *health.Readiness
+	started      <-chan struct{}
+	info         *ServerInfo
+	slowlog      *slowlog.Log
+	monitor      *monitor.Hub
+	namespaces   *storage.Data
+	maxBody      int64
+	limits       domain.Limits
+	draining     <-chan struct{}
+	sockets      *sync.WaitGroup
+	wsOrigins    []string
+}
+
+// HandlerOption enables optional endpoints on the router.
+type HandlerOption func(*Handlers)
+
+// WithWebhooks exposes webhook management under /v1/webhooks.
+func WithWebhooks(d *webhooks.Dispatcher) HandlerOption {
+	return func(h *Handlers) {
+		h.webhooks = d
+	}
+}
+
+// WithTokenRegistry authenticates requests against reg instead of the
+// single expected token, and exposes token management under
+// /v1/admin/tokens.
+func WithTokenRegistry(reg *auth.Registry) HandlerOption {
+	return func(h *Handlers) {
+		h.tokens = reg
+	}
+}
+
+// WithAuthenticator replaces token registry lookups for bearer
+// credentials, e.g. with an auth.JWTValidator or an auth.Chain of both.
+// Token management stays on the registry.
+func WithAuthenticator(a auth.Authenticator) HandlerOption {
+	return func(h *Handlers) {
+		h.authn = a
+	}
+}
+
+// WithRequestSigning accepts HMAC-signed requests verified by v. With
+// required set, bearer tokens are no longer accepted on their own.
+func WithRequestSigning(v *auth.RequestVerifier, required bool) HandlerOption {
+	return func(h *Handlers) {
+		h.signatures = v
+		h.signedOnly = required
+	}
+}
+
+// WithRateLimiter throttles requests per caller and operation class.
+func WithRateLimiter(rl *middleware.RateLimiter) HandlerOption {
+	return func(h *Handlers) {
+		h.limiter = rl
+	}
+}
+
+// WithAuditLog records every mutating request, over HTTP and WebSocket,
+// to l.
+func WithAuditLog(l *audit.Logger) HandlerOption {
+	return func(h *Handlers) {
+		h.audit = l
+	}
+}
+
+// WithMetrics records request counts, latencies and auth failures on
+// reg. With endpoint set, reg is also served at /metrics to admin
+// tokens; otherwise serve reg.Handler() on a private listener.
+func WithMetrics(reg *metrics.Registry, endpoint bool) HandlerOption {
+	return func(h *Handlers) {
+		h.metrics = reg
+		h.metricsRoute = endpoint
+	}
+}
+
+// WithReadiness reports r on /readyz; without it the server is always
+// ready.
+func WithReadiness(r *health.Readiness) HandlerOption {
+	return func(h *Handlers) {
+		h.readiness = r
+	}
+}
+
+// WithServerInfo exposes GET /v1/admin/info with live statistics of
+// info.Repo.
+func WithServerInfo(info ServerInfo) HandlerOption {
+	return func(h *Handlers) {
+		h.info = &info
+	}
+}
+
+// WithSlowLog exposes l under /v1/admin/slowlog; the service records
+// into it (see store_service.WithSlowLog).
+func WithSlowLog(l *slowlog.Log) HandlerOption {
+	return func(h *Handlers) {
+		h.slowlog = l
+	}
+}
+
+// WithMonitor streams m under /v1/admin/monitor; the service publishes
+// into it (see store_service.WithMonitor).
+func WithMonitor(m *monitor.Hub) HandlerOption {
+	return func(h *Handlers) {
+		h.monitor = m
+	}
+}
+
+// WithNamespaces exposes key counts, default TTL overrides and flushing
+// of the namespaces in d under /v1/admin/namespaces.
+func WithNamespaces(d *storage.Data) HandlerOption {
+	return func(h *Handlers) {
+		h.namespaces = d
+	}
+}
+
+// WithLimits rejects request bodies over maxBody bytes with 413 and
+// publishes them with the service's limits l (see
+// store_service.WithLimits) in /v1/admin/info. Zero is unlimited.
+func WithLimits(maxBody int64, l domain.Limits) HandlerOption {
+	return func(h *Handlers) {
+		h.maxBody = maxBody
+		h.limits = l
+	}
+}
+
+// WithAllowedOrigins lets browser pages from origins (e.g.
+// "https://app.example.com", or "*" for any) open /v1/ws. Pages from the
+// API's own origin, and clients that send no Origin, are always allowed.
+func WithAllowedOrigins(origins []string) HandlerOption {
+	return func(h *Handlers) {
+		h.wsOrigins = origins
+	}
+}
+
+// WithDrain ends watch, monitor and WebSocket streams once draining is
+// closed, so a graceful shutdown does not wait for clients that never
+// hang up. Watch clients reconnect with their last event ID. Each
+// WebSocket handler is added to sockets while it runs, as
+// http.Server.Shutdown does not wait for hijacked connections.
+func WithDrain(draining <-chan struct{}, sockets *sync.WaitGroup) HandlerOption {
+	return func(h *Handlers) {
+		h.draining = draining
+		h.sockets = sockets
+	}
+}
+
+// WithStartup answers every request but the probes with 503 until
+// started is closed, so nothing reads or writes the store while a
+// snapshot is still being loaded into it.
+func WithStartup(started <-chan struct{}) HandlerOption {
+	return func(h *Handlers) {
+		h.started = started
+	}
 }
 
 // NewHandler constructs HTTP handlers from the service.
-func NewHandler(s store_service.StoreServiceRepo, expectedToken string) http.Handler {
+func NewHandler(s store_service.StoreServiceRepo, expectedToken string, opts ...HandlerOption) http.Handler {
 	h := &Handlers{storeService: s}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.tokens == nil {
+		h.tokens = auth.NewStaticRegistry(expectedToken)
+	}
+	if h.authn == nil {
+		h.authn = h.tokens
+	}
 	router := mux.NewRouter()
 	h.RegisterHandlers(router)
-	router.Use(middleware.LoggingMiddleWare, middleware.RecoveryMiddleware, middleware.TokenAuth(expectedToken))
+	router.Use(middleware.LoggingMiddleWare, middleware.Tracing(routeTemplate))
+	if h.metrics != nil {
+		router.Use(middleware.Metrics(middleware.NewHTTPMetrics(h.metrics), routeTemplate))
+	}
+	router.Use(middleware.RecoveryMiddleware, middleware.MaxBodySize(h.maxBody))
+	if h.limiter != nil {
+		// ahead of the authenticators, so bad credentials are throttled too
+		router.Use(middleware.AuthFailureLimit(h.limiter))
+	}
+	router.Use(middleware.ClientCertAuth(h.tokens))
+	if h.signatures != nil {
+		router.Use(middleware.SignatureAuth(h.signatures, h.signedOnly))
+	}
+	router.Use(middleware.BearerAuth(h.authn), middleware.Namespace(routeNamespace))
+	if h.audit != nil {
+		router.Use(middleware.Audit(h.audit, describeRoute))
+	}
+	if h.limiter != nil {
+		router.Use(middleware.RateLimit(h.limiter, classifyRoute))
+	}
+	router.Use(middleware.Authorize(classifyRoute), middleware.TraceHandler(routeName))
+
+	// probes bypass the router's middleware: no auth, logging or metrics
+	if h.readiness == nil {
+		h.readiness = health.NewReadiness()
+	}
+	root := http.NewServeMux()
+	root.Handle("/healthz", health.LivenessHandler())
+	root.Handle("/readyz", health.ReadinessHandler(h.readiness))
+	root.Handle("/", h.untilStarted(router))
+	return root
+}
 
-	return router
+// untilStarted guards next with the WithStartup channel, if any.
+func (h *Handlers) untilStarted(next http.Handler) http.Handler {
+	if h.started == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-h.started:
+			next.ServeHTTP(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "server starting", http.StatusServiceUnavailable)
+		}
+	})
 }
 
-// RegisterHandlers wires handlers onto the mux.Router.
+// RegisterHandlers wires handlers onto the mux.Router. Route names are
+// looked up by classifyRoute, so every route must be named.
 func (h *Handlers) RegisterHandlers(router *mux.Router) {
-	router.HandleFunc("/v1/string/{key}", h.setString).Methods("POST")
-	router.HandleFunc("/v1/string/{key}", h.getString).Methods("GET")
-	router.HandleFunc("/v1/string/{key}", h.deleteString).Methods("DELETE")
+	// data routes act on the namespace in the path, e.g.
+	// /v1/ns/{ns}/string/{key}, or else the one middleware.Namespace picks
+	h.registerData(router, "/v1")
+	h.registerData(router, "/v1/ns/{ns}")
+
+	if h.webhooks != nil {
+		router.HandleFunc("/v1/webhooks", h.createWebhook).Methods("POST").Name(routeAdmin)
+		router.HandleFunc("/v1/webhooks", h.listWebhooks).Methods("GET").Name(routeAdmin)
+		router.HandleFunc("/v1/webhooks/{id}", h.deleteWebhook).Methods("DELETE").Name(routeAdmin)
+	}
+
+	router.HandleFunc("/v1/admin/tokens", h.putToken).Methods("POST").Name(routeAdmin)
+	router.HandleFunc("/v1/admin/tokens", h.listTokens).Methods("GET").Name(routeAdmin)
+	router.HandleFunc("/v1/admin/tokens/{name}", h.deleteToken).Methods("DELETE").Name(routeAdmin)
+
+	if h.info != nil {
+		router.HandleFunc("/v1/admin/info", h.getInfo).Methods("GET").Name(routeAdmin)
+	}
+
+	if h.slowlog != nil {
+		router.HandleFunc("/v1/admin/slowlog", h.getSlowLog).Methods("GET").Name(routeAdmin)
+		router.HandleFunc("/v1/admin/slowlog", h.resetSlowLog).Methods("DELETE").Name(routeAdmin)
+	}
+
+	if h.monitor != nil {
+		router.HandleFunc("/v1/admin/monitor", h.streamMonitor).Methods("GET").Name(routeAdmin)
+	}
+
+	if h.namespaces != nil {
+		router.HandleFunc("/v1/admin/namespaces", h.listNamespaces).Methods("GET").Name(routeAdmin)
+		router.HandleFunc("/v1/admin/namespaces/{ns}", h.getNamespace).Methods("GET").Name(routeAdmin)
+		router.HandleFunc("/v1/admin/namespaces/{ns}", h.putNamespace).Methods("PUT").Name(routeAdmin)
+		router.HandleFunc("/v1/admin/namespaces/{ns}/flush", h.flushNamespace).Methods("POST").Name(routeAdmin)
+	}
+
+	if h.metrics != nil && h.metricsRoute {
+		router.Handle("/metrics", h.metrics.Handler()).Methods("GET").Name(routeAdmin)
+	}
+}
+
+// registerData wires the key and stream routes under prefix.
+func (h *Handlers) registerData(router *mux.Router, prefix string) {
+	router.HandleFunc(prefix+"/string/{key}", h.setString).Methods("POST").Name(routeStringSet)
+	router.HandleFunc(prefix+"/string/{key}", h.getString).Methods("GET").Name(routeStringGet)
+	router.HandleFunc(prefix+"/string/{key}", h.deleteString).Methods("DELETE").Name(routeStringDelete)
+
+	router.HandleFunc(prefix+"/blob/{key}", h.putBlob).Methods("PUT").Name(routeBlobPut)
+	router.HandleFunc(prefix+"/blob/{key}", h.getBlob).Methods("GET", "HEAD").Name(routeBlobGet)
+	router.HandleFunc(prefix+"/blob/{key}", h.deleteBlob).Methods("DELETE").Name(routeBlobDelete)
+
+	list := router.PathPrefix(prefix + "/list/{key}").Subrouter()
+	list.HandleFunc("/push", h.pushList).Methods("POST").Name(routeListPush)
+	list.HandleFunc("/pop", h.popList).Methods("POST").Name(routeListPop)
 
-	list := router.PathPrefix("/v1/list/{key}").Subrouter()
-	list.HandleFunc("/push", h.pushList).Methods("POST")
-	list.HandleFunc("/pop", h.popList).Methods("POST")
+	router.HandleFunc(prefix+"/watch", h.watch).Methods("GET").Name(routeWatch)
+	router.HandleFunc(prefix+"/ws", h.websocket).Methods("GET").Name(routeWebSocket)
 }
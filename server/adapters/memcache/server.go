@@ -0,0 +1,605 @@
+// Package memcache exposes the store's string operations over the
+// memcached text protocol for legacy clients.
+package memcache
+
+import (
+	"bufio"
+	"context"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/store_service"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRelativeExptime is memcached's cut-over: larger exptimes are
+	// absolute Unix timestamps rather than seconds from now.
+	maxRelativeExptime = 60 * 60 * 24 * 30
+
+	// maxValueLen caps a single data block (memcached's default item size).
+	maxValueLen = 1 << 20
+
+	// maxKeyLen is the memcached protocol key length limit.
+	maxKeyLen = 250
+
+	// maxLineLen caps a command line, which leaves room for a multi-get
+	// of a few hundred full-length keys.
+	maxLineLen = 64 << 10
+
+	version = "1.6.0-data_storage"
+)
+
+// Server accepts memcached text-protocol connections. The protocol has
+// no authentication: unless WithIdentity binds it to a token every
+// client has full access to the default namespace, so bind it to a
+// trusted interface only.
+type Server struct {
+	storeService store_service.StoreServiceRepo
+
+	tokens    *auth.Registry // set by WithIdentity
+	identity  string
+	namespace string
+	limiter   *middleware.RateLimiter
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	timers   map[*time.Timer]struct{} // delayed flush_all
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithIdentity runs every command as the token named identity in reg,
+// checked against its ACL, in namespace ns (the token's home namespace
+// if empty). The token is looked up for each command, so ACL reloads
+// and revocation apply straight away.
+func WithIdentity(reg *auth.Registry, identity, ns string) Option {
+	return func(s *Server) {
+		s.tokens, s.identity, s.namespace = reg, identity, ns
+	}
+}
+
+// WithRateLimiter throttles commands with the HTTP API's buckets: per
+// bound identity, or per client IP. Throttled commands get a
+// SERVER_ERROR reply.
+func WithRateLimiter(rl *middleware.RateLimiter) Option {
+	return func(s *Server) {
+		s.limiter = rl
+	}
+}
+
+// NewServer creates a memcached protocol server over the StoreService.
+func NewServer(s store_service.StoreServiceRepo, opts ...Option) *Server {
+	srv := &Server{
+		storeService: s,
+		conns:        make(map[net.Conn]struct{}),
+		timers:       make(map[*time.Timer]struct{}),
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
+
+// ListenAndServe listens on addr and serves until Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close is called.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return net.ErrClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops accepting connections, closes open ones, cancels pending
+// delayed flushes and waits for the connection goroutines to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	for t := range s.timers {
+		t.Stop()
+		delete(s.timers, t)
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// errClientClosed signals that the client asked to quit.
+var errClientClosed = errors.New("client quit")
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	ctx := audit.WithRemoteAddr(context.Background(), conn.RemoteAddr().String())
+	for {
+		line, err := readLine(r)
+		if err != nil {
+			if errors.Is(err, errLineTooLong) {
+				w.WriteString("CLIENT_ERROR line too long\r\n")
+				w.Flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				slog.Warn("memcached read error", "remote_addr", conn.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// an error means quit or lost framing (e.g. a short data block)
+		if err := s.dispatch(ctx, r, w, fields); err != nil {
+			w.Flush()
+			return
+		}
+		if r.Buffered() == 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// errLineTooLong closes a connection whose command line exceeds maxLineLen.
+var errLineTooLong = errors.New("line too long")
+
+// readLine reads one command line of at most maxLineLen bytes.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLineLen {
+			return "", errLineTooLong
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(line), nil
+	}
+}
+
+// errNoIdentity signals that the bound token no longer exists.
+var errNoIdentity = errors.New("memcached identity unavailable")
+
+// bind returns ctx running as the configured identity and namespace,
+// or ctx itself when the server is not bound to one.
+func (s *Server) bind(ctx context.Context) (context.Context, error) {
+	if s.tokens == nil {
+		return ctx, nil
+	}
+	id, err := s.tokens.Lookup(s.identity)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q: %v", errNoIdentity, s.identity, err)
+	}
+	ns := s.namespace
+	if ns == "" {
+		ns = id.HomeNamespace()
+	}
+	if !id.CanUseNamespace(ns) {
+		return nil, fmt.Errorf("%w: %q may not use namespace %q", errNoIdentity, s.identity, ns)
+	}
+	return domain.WithNamespace(auth.WithIdentity(ctx, id), ns), nil
+}
+
+// commandClass maps commands onto ACL operation classes.
+var commandClass = map[string]auth.OpClass{
+	"get":       auth.OpRead,
+	"gets":      auth.OpRead,
+	"set":       auth.OpWrite,
+	"add":       auth.OpWrite,
+	"replace":   auth.OpWrite,
+	"cas":       auth.OpWrite,
+	"incr":      auth.OpWrite,
+	"decr":      auth.OpWrite,
+	"touch":     auth.OpWrite,
+	"delete":    auth.OpDelete,
+	"flush_all": auth.OpAdmin,
+}
+
+// allowed reports whether the identity in ctx, if any, may run cmd;
+// get and gets check every key, the other commands their first
+// argument.
+func allowed(ctx context.Context, cmd string, args []string) bool {
+	id, ok := auth.FromContext(ctx)
+	class, known := commandClass[cmd]
+	if !ok || !known {
+		return true
+	}
+	keys := args
+	if cmd != "get" && cmd != "gets" && len(args) > 0 {
+		keys = args[:1]
+	}
+	if class == auth.OpAdmin {
+		keys = []string{""}
+	}
+	for _, key := range keys {
+		if !id.Allows(class, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// throttle takes a rate limit token for cmd, if it touches data, and
+// otherwise returns the reply.
+func (s *Server) throttle(ctx context.Context, cmd string) (string, bool) {
+	class, ok := commandClass[cmd]
+	if !ok || s.limiter == nil {
+		return "", true
+	}
+	id, _ := auth.FromContext(ctx)
+	if ok, retryAfter := s.limiter.Allow(class, middleware.Caller(id, audit.RemoteAddr(ctx))); !ok {
+		return "SERVER_ERROR rate limit exceeded, retry in " + retryAfter.Round(time.Millisecond).String(), false
+	}
+	return "", true
+}
+
+// dispatch runs one command. A returned error means the connection
+// can no longer be used.
+func (s *Server) dispatch(ctx context.Context, r *bufio.Reader, w *bufio.Writer, f []string) error {
+	ctx, err := s.bind(ctx)
+	if err != nil {
+		slog.Warn("memcached command refused", "err", err)
+		w.WriteString("SERVER_ERROR " + err.Error() + "\r\n")
+		return err
+	}
+	switch cmd := f[0]; cmd {
+	case "set", "add", "replace", "cas":
+		// store checks the ACL once the data block has been read
+	default:
+		if !allowed(ctx, cmd, f[1:]) {
+			w.WriteString("CLIENT_ERROR permission denied\r\n")
+			return nil
+		}
+		if msg, ok := s.throttle(ctx, cmd); !ok {
+			w.WriteString(msg + "\r\n")
+			return nil
+		}
+	}
+
+	switch cmd := f[0]; cmd {
+	case "get", "gets":
+		s.get(ctx, w, f[1:], cmd == "gets")
+	case "set", "add", "replace", "cas":
+		return s.store(ctx, r, w, cmd, f[1:])
+	case "delete":
+		s.delete(ctx, w, f[1:])
+	case "incr", "decr":
+		s.incrDecr(ctx, w, cmd, f[1:])
+	case "touch":
+		s.touch(ctx, w, f[1:])
+	case "flush_all":
+		s.flushAll(ctx, w, f[1:])
+	case "version":
+		fmt.Fprintf(w, "VERSION %s\r\n", version)
+	case "quit":
+		return errClientClosed
+	default:
+		w.WriteString("ERROR\r\n")
+	}
+	return nil
+}
+
+func (s *Server) get(ctx context.Context, w *bufio.Writer, keys []string, withCAS bool) {
+	if len(keys) == 0 {
+		w.WriteString("ERROR\r\n")
+		return
+	}
+	for _, key := range keys {
+		entry, err := s.storeService.GetStringEntry(ctx, key)
+		if err != nil {
+			// misses, expired and non-string keys are all omitted
+			continue
+		}
+		if withCAS {
+			fmt.Fprintf(w, "VALUE %s %d %d %d\r\n", key, entry.Flags, len(entry.Str), entry.CAS)
+		} else {
+			fmt.Fprintf(w, "VALUE %s %d %d\r\n", key, entry.Flags, len(entry.Str))
+		}
+		w.WriteString(entry.Str)
+		w.WriteString("\r\n")
+	}
+	w.WriteString("END\r\n")
+}
+
+// store handles set|add|replace <key> <flags> <exptime> <bytes> [noreply]
+// and cas <key> <flags> <exptime> <bytes> <cas unique> [noreply].
+func (s *Server) store(ctx context.Context, r *bufio.Reader, w *bufio.Writer, cmd string, args []string) error {
+	want := 4
+	if cmd == "cas" {
+		want = 5
+	}
+	if len(args) < want || len(args) > want+1 {
+		w.WriteString("ERROR\r\n")
+		return nil
+	}
+	noreply := len(args) == want+1 && args[want] == "noreply"
+
+	key := args[0]
+	flags, errF := strconv.ParseUint(args[1], 10, 32)
+	exptime, errE := strconv.ParseInt(args[2], 10, 64)
+	size, errS := strconv.Atoi(args[3])
+	if errF != nil || errE != nil || errS != nil || size < 0 {
+		w.WriteString("CLIENT_ERROR bad command line format\r\n")
+		return nil
+	}
+	if size > maxValueLen {
+		w.WriteString("SERVER_ERROR object too large for cache\r\n")
+		// swallow the data block so the stream stays in sync
+		_, err := r.Discard(size + 2)
+		return err
+	}
+
+	data := make([]byte, size+2)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return err
+	}
+	if data[size] != '\r' || data[size+1] != '\n' {
+		w.WriteString("CLIENT_ERROR bad data chunk\r\n")
+		return errors.New("bad data chunk")
+	}
+	if len(key) > maxKeyLen {
+		reply(w, noreply, "CLIENT_ERROR key too long")
+		return nil
+	}
+	if !allowed(ctx, cmd, args) {
+		reply(w, noreply, "CLIENT_ERROR permission denied")
+		return nil
+	}
+	if msg, ok := s.throttle(ctx, cmd); !ok {
+		reply(w, noreply, msg)
+		return nil
+	}
+
+	write := domain.StringWrite{
+		Value:  string(data[:size]),
+		Flags:  uint32(flags),
+		Expiry: expiryFromExptime(exptime),
+	}
+	switch cmd {
+	case "add":
+		write.Mode = domain.SetIfAbsent
+	case "replace":
+		write.Mode = domain.SetIfPresent
+	case "cas":
+		cas, err := strconv.ParseUint(args[4], 10, 64)
+		if err != nil {
+			reply(w, noreply, "CLIENT_ERROR bad command line format")
+			return nil
+		}
+		write.Mode = domain.SetIfCAS
+		write.CAS = cas
+	}
+
+	_, err := s.storeService.StoreString(ctx, key, write)
+	switch {
+	case err == nil:
+		reply(w, noreply, "STORED")
+	case errors.Is(err, domain.ErrKeyExists):
+		reply(w, noreply, "NOT_STORED")
+	case errors.Is(err, domain.ErrNotFound) && cmd == "replace":
+		reply(w, noreply, "NOT_STORED")
+	case errors.Is(err, domain.ErrNotFound):
+		reply(w, noreply, "NOT_FOUND")
+	case errors.Is(err, domain.ErrCASMismatch):
+		reply(w, noreply, "EXISTS")
+	case errors.Is(err, domain.ErrEmptyValue):
+		reply(w, noreply, "CLIENT_ERROR empty values are not supported")
+	default:
+		reply(w, noreply, "SERVER_ERROR "+err.Error())
+	}
+	return nil
+}
+
+// delete handles delete <key> [noreply].
+func (s *Server) delete(ctx context.Context, w *bufio.Writer, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		w.WriteString("ERROR\r\n")
+		return
+	}
+	noreply := len(args) == 2 && args[1] == "noreply"
+
+	if _, err := s.storeService.TTL(ctx, args[0]); err != nil {
+		reply(w, noreply, "NOT_FOUND")
+		return
+	}
+	if err := s.storeService.DeleteString(ctx, args[0]); err != nil {
+		reply(w, noreply, "SERVER_ERROR "+err.Error())
+		return
+	}
+	reply(w, noreply, "DELETED")
+}
+
+// incrDecr handles incr|decr <key> <value> [noreply].
+func (s *Server) incrDecr(ctx context.Context, w *bufio.Writer, cmd string, args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		w.WriteString("ERROR\r\n")
+		return
+	}
+	noreply := len(args) == 3 && args[2] == "noreply"
+
+	delta, err := strconv.ParseUint(args[1], 10, 64)
+	if err != nil {
+		reply(w, noreply, "CLIENT_ERROR invalid numeric delta argument")
+		return
+	}
+
+	var n uint64
+	if cmd == "incr" {
+		n, err = s.storeService.Incr(ctx, args[0], delta)
+	} else {
+		n, err = s.storeService.Decr(ctx, args[0], delta)
+	}
+	switch {
+	case err == nil:
+		reply(w, noreply, strconv.FormatUint(n, 10))
+	case errors.Is(err, domain.ErrNotFound):
+		reply(w, noreply, "NOT_FOUND")
+	case errors.Is(err, domain.ErrNotInteger), errors.Is(err, domain.ErrWrongType):
+		reply(w, noreply, "CLIENT_ERROR cannot increment or decrement non-numeric value")
+	default:
+		reply(w, noreply, "SERVER_ERROR "+err.Error())
+	}
+}
+
+// touch handles touch <key> <exptime> [noreply].
+func (s *Server) touch(ctx context.Context, w *bufio.Writer, args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		w.WriteString("ERROR\r\n")
+		return
+	}
+	noreply := len(args) == 3 && args[2] == "noreply"
+
+	exptime, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		reply(w, noreply, "CLIENT_ERROR invalid exptime argument")
+		return
+	}
+	if err := s.storeService.Touch(ctx, args[0], expiryFromExptime(exptime)); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			reply(w, noreply, "NOT_FOUND")
+			return
+		}
+		reply(w, noreply, "SERVER_ERROR "+err.Error())
+		return
+	}
+	reply(w, noreply, "TOUCHED")
+}
+
+// flushAll handles flush_all [delay] [noreply]. Like exptimes, delays
+// over 30 days are absolute Unix timestamps; those in the past flush
+// now, and those more than 30 days ahead are refused.
+func (s *Server) flushAll(ctx context.Context, w *bufio.Writer, args []string) {
+	noreply := len(args) > 0 && args[len(args)-1] == "noreply"
+	if noreply {
+		args = args[:len(args)-1]
+	}
+
+	var delay time.Duration
+	if len(args) > 0 {
+		d, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || d < 0 {
+			reply(w, noreply, "CLIENT_ERROR bad command line format")
+			return
+		}
+		if d > maxRelativeExptime {
+			d -= time.Now().Unix()
+		}
+		if d > maxRelativeExptime {
+			reply(w, noreply, "CLIENT_ERROR flush delay too long")
+			return
+		}
+		delay = time.Duration(d) * time.Second
+	}
+
+	if delay > 0 {
+		s.mu.Lock()
+		var t *time.Timer
+		t = time.AfterFunc(delay, func() {
+			s.mu.Lock()
+			delete(s.timers, t)
+			s.mu.Unlock()
+			if err := s.storeService.FlushAll(ctx); err != nil {
+				slog.Error("memcached delayed flush_all failed", "err", err)
+			}
+		})
+		s.timers[t] = struct{}{}
+		s.mu.Unlock()
+		reply(w, noreply, "OK")
+		return
+	}
+	if err := s.storeService.FlushAll(ctx); err != nil {
+		reply(w, noreply, "SERVER_ERROR "+err.Error())
+		return
+	}
+	reply(w, noreply, "OK")
+}
+
+// expiryFromExptime maps a memcached exptime onto an absolute expiry:
+// 0 never expires, up to 30 days is relative, anything larger is a
+// Unix timestamp and negative values are already expired.
+func expiryFromExptime(exptime int64) time.Time {
+	switch {
+	case exptime == 0:
+		return time.Time{}
+	case exptime < 0:
+		return time.Now()
+	case exptime <= maxRelativeExptime:
+		return time.Now().Add(time.Duration(exptime) * time.Second)
+	default:
+		return time.Unix(exptime, 0)
+	}
+}
+
+func reply(w *bufio.Writer, noreply bool, msg string) {
+	if noreply {
+		return
+	}
+	w.WriteString(msg)
+	w.WriteString("\r\n")
+}
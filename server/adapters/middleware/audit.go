@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Describer names the audited operation and key of a routed request; ok
+// is false for requests that change nothing.
+type Describer func(r *http.Request) (op, key string, ok bool)
+
+// Audit returns a middleware that records every request describe marks
+// as a mutation, with the caller's identity, outcome and latency. It
+// must run after routing and authentication, and before authorization
+// and rate limiting so rejected attempts are recorded too.
+func Audit(l *audit.Logger, describe Describer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			op, key, ok := describe(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			var value string
+			if r.ContentLength != 0 {
+				if l.IncludesValues() {
+					body, _ := bufferBody(r)
+					value = strings.TrimSpace(string(body))
+					if !utf8.ValidString(value) {
+						// binary blobs would garble the log
+						value = audit.Redacted
+					}
+				} else {
+					value = audit.Redacted
+				}
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+
+			var identity string
+			if id, ok := auth.FromContext(r.Context()); ok {
+				identity = id.Name
+			}
+			l.Log(audit.Record{
+				Time:       start,
+				Identity:   identity,
+				RemoteAddr: r.RemoteAddr,
+				Transport:  "http",
+				Operation:  op,
+				Namespace:  domain.NamespaceFrom(r.Context()),
+				Key:        key,
+				Value:      value,
+				Status:     rec.status,
+				Outcome:    audit.OutcomeFor(rec.status),
+				LatencyMS:  float64(time.Since(start).Microseconds()) / 1000,
+			})
+		})
+	}
+}
@@ -1,43 +1,197 @@
 package middleware
 
 import (
-	"log"
+	"crypto/sha256"
+	"data_storage/server/auth"
+	"data_storage/server/logging"
+	"data_storage/server/tracing"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 )
 
-func LoggingMiddleWare(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
-		log.Printf("%s %s", req.Method, req.URL.Path)
-		next.ServeHTTP(w, req)
-	})
-}
-
-func RecoveryMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		defer func() {
-			if rec := recover(); rec != nil {
-				http.Error(w, "internal error", http.StatusInternalServerError)
-			}
-		}()
-		next.ServeHTTP(w, r)
-	})
-}
-
 // TokenAuth returns a middleware that enforces
 // Authorization: Bearer <expectedToken>.
 func TokenAuth(expectedToken string) func(http.Handler) http.Handler {
+	return BearerAuth(auth.NewStaticRegistry(expectedToken))
+}
+
+// BearerAuth returns a middleware that resolves the bearer credential
+// with a and stores the caller's auth.Identity in the request context.
+// Browsers cannot set headers on a WebSocket handshake, so upgrade
+// requests may pass the token as ?access_token=<token> instead.
+func BearerAuth(a auth.Authenticator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			auth := r.Header.Get("Authorization")
+			if _, ok := auth.FromContext(r.Context()); ok {
+				// already identified, e.g. by ClientCertAuth
+				next.ServeHTTP(w, r)
+				return
+			}
+			header := r.Header.Get("Authorization")
+			if header == "" && isWebSocketUpgrade(r) {
+				if token := r.URL.Query().Get("access_token"); token != "" {
+					header = "Bearer " + token
+				}
+			}
 			// must be "Bearer <token>"
-			parts := strings.SplitN(auth, " ", 2)
-			if len(parts) != 2 || parts[0] != "Bearer" || parts[1] != expectedToken {
-				w.Header().Set("WWW-Authenticate", `Bearer realm="restricted"`)
-				http.Error(w, "unauthorized", http.StatusUnauthorized)
+			parts := strings.SplitN(header, " ", 2)
+			if len(parts) != 2 || parts[0] != "Bearer" {
+				unauthorized(w)
+				return
+			}
+			_, span := tracing.Start(r.Context(), "auth.bearer")
+			id, err := a.Authenticate(parts[1])
+			span.RecordError(err)
+			span.End()
+			if err != nil {
+				unauthorized(w)
 				return
 			}
+			next.ServeHTTP(w, withIdentity(r, id))
+		})
+	}
+}
+
+// ClientCertAuth returns a middleware that identifies callers by a
+// verified TLS client certificate: the token whose cert_subjects binds
+// the subject CN, or else a DNS, URI or email SAN, becomes the request's
+// auth.Identity (see auth.Registry.LookupCert). Requests without a
+// verified certificate, or whose names are not bound, fall through to
+// the next authenticator.
+func ClientCertAuth(reg *auth.Registry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+				if id, err := reg.LookupCert(auth.CertNames(r.TLS.VerifiedChains[0][0])); err == nil {
+					r = withIdentity(r, id)
+				}
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// SignatureAuth returns a middleware that authenticates requests signed
+// with auth.Sign over method, path and query, X-Namespace, Content-Type,
+// Content-Encoding, timestamp, nonce and body hash (see
+// auth.SignedRequest). Unsigned requests fall through to the next
+// authenticator unless required is set, which allows migrating clients
+// from bearer tokens one at a time.
+func SignatureAuth(v *auth.RequestVerifier, required bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := auth.FromContext(r.Context()); ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			sig := r.Header.Get(auth.SignatureHeader)
+			if sig == "" {
+				if required {
+					unauthorized(w)
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body, err := bufferBody(r)
+			if msg, ok := TooLarge(err); ok {
+				writeError(w, r, http.StatusRequestEntityTooLarge, msg)
+				return
+			}
+			if err != nil {
+				http.Error(w, "read body", http.StatusBadRequest)
+				return
+			}
+			sum := sha256.Sum256(body)
+
+			_, span := tracing.Start(r.Context(), "auth.signature")
+			id, err := v.Verify(auth.SignedRequest{
+				KeyID:           r.Header.Get(auth.SignatureKeyIDHeader),
+				Method:          r.Method,
+				Path:            r.URL.RequestURI(),
+				Namespace:       r.Header.Get(NamespaceHeader),
+				ContentType:     r.Header.Get("Content-Type"),
+				ContentEncoding: r.Header.Get("Content-Encoding"),
+				Timestamp:       r.Header.Get(auth.SignatureTimestampHeader),
+				Nonce:           r.Header.Get(auth.SignatureNonceHeader),
+				BodyHash:        hex.EncodeToString(sum[:]),
+				Signature:       sig,
+			})
+			span.RecordError(err)
+			span.End()
+			if err != nil {
+				slog.WarnContext(r.Context(), "rejected signed request", "err", err)
+				unauthorized(w)
+				return
+			}
+			next.ServeHTTP(w, withIdentity(r, id))
+		})
+	}
+}
+
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="restricted"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+// Classifier reports the operation class and key a routed request
+// needs; ok is false for routes that need no further authorization.
+type Classifier func(r *http.Request) (class auth.OpClass, key string, ok bool)
+
+// forbiddenResponse is the structured 403 body.
+type forbiddenResponse struct {
+	Code      int          `json:"code"`
+	Message   string       `json:"message"`
+	Identity  string       `json:"identity"`
+	Operation auth.OpClass `json:"operation"`
+	Key       string       `json:"key,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// Authorize returns a middleware that checks the authenticated identity
+// against the class and key reported by classify. It must run after
+// routing (e.g. via mux.Router.Use) and after the authenticators.
+func Authorize(classify Classifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class, key, ok := classify(r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			id, _ := auth.FromContext(r.Context())
+			if id.Allows(class, key) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			resp := forbiddenResponse{
+				Code:      http.StatusForbidden,
+				Message:   fmt.Sprintf("forbidden: %s access denied", class),
+				Operation: class,
+				Key:       key,
+				RequestID: logging.RequestID(r.Context()),
+			}
+			if id != nil {
+				resp.Identity = id.Name
+				if key != "" {
+					resp.Message = fmt.Sprintf("forbidden: token %q may not %s key %q", id.Name, class, key)
+				} else {
+					resp.Message = fmt.Sprintf("forbidden: token %q lacks %s access", id.Name, class)
+				}
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(resp)
+		})
+	}
+}
+
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
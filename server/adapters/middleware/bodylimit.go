@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxBodySize returns a middleware that rejects request bodies over n
+// bytes with 413: at once when Content-Length says so, otherwise once a
+// handler reads past n (see http.MaxBytesReader and TooLarge). Zero
+// disables the limit. It must run before anything reads the body.
+func MaxBodySize(n int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > n {
+				writeError(w, r, http.StatusRequestEntityTooLarge,
+					fmt.Sprintf("request body of %d bytes exceeds the limit of %d", r.ContentLength, n))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, n)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// TooLarge reports whether err came from reading past MaxBodySize's
+// limit, and the 413 message to reply with.
+func TooLarge(err error) (string, bool) {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return "", false
+	}
+	return fmt.Sprintf("request body exceeds the limit of %d bytes", tooLarge.Limit), true
+}
+
+// bufferBody reads r's body for a middleware and puts back one that
+// replays it, followed by the read error if there was one, so handlers
+// still see why reading stopped.
+func bufferBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	replay := io.Reader(bytes.NewReader(body))
+	if err != nil {
+		replay = io.MultiReader(replay, errReader{err})
+	}
+	r.Body = io.NopCloser(replay)
+	return body, err
+}
+
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"context"
+	"data_storage/server/auth"
+	"data_storage/server/logging"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// requestLog collects what later middleware learn about a request for
+// the access log line.
+type requestLog struct {
+	identity string
+}
+
+type requestLogKey struct{}
+
+// LoggingMiddleWare assigns each request an ID, taken from a valid
+// X-Request-ID header or generated, which is echoed in the response and
+// stored in the context (see logging.RequestID). When the request
+// completes it logs method, path, status, latency, response bytes and
+// the caller's identity; 5xx responses are logged as errors.
+func LoggingMiddleWare(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		id := req.Header.Get(logging.RequestIDHeader)
+		if !logging.ValidRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		w.Header().Set(logging.RequestIDHeader, id)
+
+		info := &requestLog{}
+		ctx := context.WithValue(logging.WithRequestID(req.Context(), id), requestLogKey{}, info)
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(rec, req.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.LogAttrs(ctx, level, "request",
+			slog.String("request_id", id),
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Duration("latency", time.Since(start)),
+			slog.Int64("bytes", rec.bytes),
+			slog.String("identity", info.identity),
+			slog.String("remote_addr", req.RemoteAddr),
+		)
+	})
+}
+
+// RecoveryMiddleware turns a panicking handler into a 500 and logs the
+// panic.
+func RecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				slog.ErrorContext(r.Context(), "handler panic",
+					"request_id", logging.RequestID(r.Context()), "panic", rec)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withIdentity stores id in r's context and notes it for the access log.
+func withIdentity(r *http.Request, id *auth.Identity) *http.Request {
+	if info, ok := r.Context().Value(requestLogKey{}).(*requestLog); ok {
+		info.identity = id.Name
+	}
+	return r.WithContext(auth.WithIdentity(r.Context(), id))
+}
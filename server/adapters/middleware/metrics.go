@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"data_storage/server/metrics"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPMetrics are the request metrics recorded by Metrics.
+type HTTPMetrics struct {
+	requests     *metrics.CounterVec
+	latency      *metrics.HistogramVec
+	authFailures *metrics.CounterVec
+}
+
+// NewHTTPMetrics registers the HTTP request metrics on reg.
+func NewHTTPMetrics(reg *metrics.Registry) *HTTPMetrics {
+	return &HTTPMetrics{
+		requests: reg.CounterVec("store_http_requests_total",
+			"HTTP requests by route, method and status.", "route", "method", "status"),
+		latency: reg.HistogramVec("store_http_request_duration_seconds",
+			"HTTP request latency by route, method and status.", metrics.DefaultBuckets, "route", "method", "status"),
+		authFailures: reg.CounterVec("store_auth_failures_total",
+			"HTTP requests rejected as unauthenticated (401) or unauthorized (403).", "status"),
+	}
+}
+
+// AuthFailures returns the number of rejections with status, 401 or 403.
+func (m *HTTPMetrics) AuthFailures(status int) float64 {
+	return m.authFailures.Value(strconv.Itoa(status))
+}
+
+// Metrics returns a middleware that counts and times requests per route,
+// as named by route, and counts authentication and authorization
+// failures. It must run first after routing so every rejection is seen.
+// WebSocket and watch requests are timed until the stream ends.
+func Metrics(m *HTTPMetrics, route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			start := time.Now()
+			next.ServeHTTP(rec, r)
+
+			status := strconv.Itoa(rec.status)
+			name := route(r)
+			m.requests.Inc(name, r.Method, status)
+			m.latency.Observe(time.Since(start).Seconds(), name, r.Method, status)
+			if rec.status == http.StatusUnauthorized || rec.status == http.StatusForbidden {
+				m.authFailures.Inc(status)
+			}
+		})
+	}
+}
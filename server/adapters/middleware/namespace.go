@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/logging"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// NamespaceHeader selects the namespace of a request whose path names
+// none.
+const NamespaceHeader = "X-Namespace"
+
+// Namespace returns a middleware that puts the request's namespace in
+// its context (see domain.WithNamespace): the one routed reports from
+// the path, else the X-Namespace header, else the caller's home
+// namespace. Invalid names are rejected with 400 and namespaces the
+// caller is not bound to with 403. It must run after routing and
+// authentication.
+func Namespace(routed func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := auth.FromContext(r.Context())
+			ns := routed(r)
+			if ns == "" {
+				ns = r.Header.Get(NamespaceHeader)
+			}
+			if ns == "" {
+				ns = id.HomeNamespace()
+			}
+
+			if !domain.ValidNamespace(ns) {
+				writeError(w, r, http.StatusBadRequest, fmt.Sprintf("invalid namespace %q", ns))
+				return
+			}
+			if id != nil && !id.CanUseNamespace(ns) {
+				writeError(w, r, http.StatusForbidden, fmt.Sprintf("forbidden: token %q may not use namespace %q", id.Name, ns))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(domain.WithNamespace(r.Context(), ns)))
+		})
+	}
+}
+
+// writeError writes a JSON error body shaped like the handlers' ones.
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	body := map[string]interface{}{
+		"code":    status,
+		"message": msg,
+	}
+	if id := logging.RequestID(r.Context()); id != "" {
+		body["request_id"] = id
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
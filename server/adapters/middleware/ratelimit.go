@@ -0,0 +1,279 @@
+package middleware
+
+import (
+	"data_storage/server/auth"
+	"data_storage/server/logging"
+	"data_storage/server/tracing"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket: Rate requests per second on average, with
+// bursts of up to Burst requests. A zero Rate disables limiting.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// AuthFailures is the RateLimiter class of failed authentications,
+// which AuthFailureLimit counts per client IP.
+const AuthFailures auth.OpClass = "auth_failure"
+
+// RateLimiter keeps one token bucket per caller and route class: reads
+// (auth.OpRead), writes (every other class, deletes and admin calls
+// included) and AuthFailures. Callers are identified by their
+// authenticated identity, falling back to the client IP.
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    map[auth.OpClass]Limit
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// NewRateLimiter returns a limiter enforcing limits keyed by auth.OpRead,
+// auth.OpWrite and AuthFailures; a class without an entry is not
+// limited.
+func NewRateLimiter(limits map[auth.OpClass]Limit) *RateLimiter {
+	rl := &RateLimiter{buckets: make(map[string]*bucket), now: time.Now}
+	rl.SetLimits(limits)
+	return rl
+}
+
+// SetLimits replaces the limits; existing buckets adopt them on their
+// next request.
+func (rl *RateLimiter) SetLimits(limits map[auth.OpClass]Limit) {
+	copied := make(map[auth.OpClass]Limit, len(limits))
+	for class, l := range limits {
+		if l.Rate > 0 {
+			if l.Burst < 1 {
+				l.Burst = int(math.Max(1, math.Ceil(l.Rate)))
+			}
+			copied[class] = l
+		}
+	}
+	rl.mu.Lock()
+	rl.limits = copied
+	rl.mu.Unlock()
+}
+
+// enabled reports whether any class is limited.
+func (rl *RateLimiter) enabled() bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return len(rl.limits) > 0
+}
+
+// decision is the outcome of taking a token.
+type decision struct {
+	limited    bool
+	allowed    bool
+	limit      int
+	remaining  int
+	retryAfter time.Duration // until one token is available
+	reset      time.Duration // until the bucket is full again
+}
+
+// take consumes a token for caller in class.
+func (rl *RateLimiter) take(class auth.OpClass, caller string) decision {
+	return rl.check(class, caller, true)
+}
+
+// peek reports whether caller has a token left in class without
+// consuming it.
+func (rl *RateLimiter) peek(class auth.OpClass, caller string) decision {
+	return rl.check(class, caller, false)
+}
+
+func (rl *RateLimiter) check(class auth.OpClass, caller string, consume bool) decision {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	l, ok := rl.limits[class]
+	if !ok {
+		return decision{allowed: true}
+	}
+	now := rl.now()
+	rl.prune(now)
+
+	key := string(class) + "|" + caller
+	b, ok := rl.buckets[key]
+	if !ok || b.limit != l {
+		b = &bucket{tokens: float64(l.Burst), last: now, limit: l}
+		rl.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+
+	d := decision{limited: true, limit: l.Burst}
+	if b.tokens >= 1 {
+		if consume {
+			b.tokens--
+		}
+		d.allowed = true
+	} else {
+		d.retryAfter = time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+	}
+	d.remaining = int(b.tokens)
+	d.reset = time.Duration((float64(l.Burst) - b.tokens) / l.Rate * float64(time.Second))
+	return d
+}
+
+// prune drops buckets that have refilled completely, at most once a
+// minute; a fresh bucket behaves exactly like a full one.
+func (rl *RateLimiter) prune(now time.Time) {
+	if now.Sub(rl.lastPrune) < time.Minute {
+		return
+	}
+	rl.lastPrune = now
+	for key, b := range rl.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate >= float64(b.limit.Burst) {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// Allow takes a token for caller in class, counting every class but
+// auth.OpRead as a write as RateLimit does, and reports whether the
+// call may go ahead; if not, retryAfter is when it may. The RESP, gRPC,
+// memcached and WebSocket transports call it for each command; caller
+// comes from Caller.
+func (rl *RateLimiter) Allow(class auth.OpClass, caller string) (ok bool, retryAfter time.Duration) {
+	if class != auth.OpRead {
+		class = auth.OpWrite
+	}
+	d := rl.take(class, caller)
+	return d.allowed, d.retryAfter
+}
+
+// AuthBlocked reports whether the client at remoteAddr has used up its
+// AuthFailures bucket, and if so how long until it may try again.
+func (rl *RateLimiter) AuthBlocked(remoteAddr string) (blocked bool, retryAfter time.Duration) {
+	d := rl.peek(AuthFailures, "ip:"+hostOf(remoteAddr))
+	return d.limited && !d.allowed, d.retryAfter
+}
+
+// AuthFailed records a failed authentication by the client at remoteAddr.
+func (rl *RateLimiter) AuthFailed(remoteAddr string) {
+	rl.take(AuthFailures, "ip:"+hostOf(remoteAddr))
+}
+
+// Caller identifies a caller for Allow: the identity, or the client at
+// remoteAddr when there is none.
+func Caller(id *auth.Identity, remoteAddr string) string {
+	if id != nil {
+		return "id:" + id.Name
+	}
+	return "ip:" + hostOf(remoteAddr)
+}
+
+// RateLimit returns a middleware that throttles requests per caller and
+// operation class, as reported by classify. It must run after routing
+// and authentication. Limited routes always carry X-RateLimit-Limit,
+// X-RateLimit-Remaining and X-RateLimit-Reset (seconds until the bucket
+// is full); throttled requests get 429 with Retry-After.
+func RateLimit(rl *RateLimiter, classify Classifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			class, _, ok := classify(r)
+			if !ok || !rl.enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if class != auth.OpRead {
+				class = auth.OpWrite
+			}
+			_, span := tracing.Start(r.Context(), "ratelimit")
+			d := rl.take(class, callerKey(r))
+			span.SetAttr("allowed", d.allowed)
+			span.End()
+			if !d.limited {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("X-RateLimit-Limit", strconv.Itoa(d.limit))
+			h.Set("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+			h.Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.reset)))
+			if d.allowed {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tooManyRequests(w, r, d.retryAfter, "rate limit exceeded for "+string(class)+" requests")
+		})
+	}
+}
+
+// AuthFailureLimit returns a middleware that throttles clients that keep
+// failing authentication, per client IP: every 401 reply takes a token
+// from the client's AuthFailures bucket, and once it is empty requests
+// from that IP get 429 before any credential is checked. It must run
+// before the authenticators.
+func AuthFailureLimit(rl *RateLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !rl.enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if blocked, retryAfter := rl.AuthBlocked(r.RemoteAddr); blocked {
+				tooManyRequests(w, r, retryAfter, "too many failed authentication attempts")
+				return
+			}
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+			if rec.status == http.StatusUnauthorized {
+				rl.AuthFailed(r.RemoteAddr)
+			}
+		})
+	}
+}
+
+// tooManyRequests writes the 429 reply with Retry-After.
+func tooManyRequests(w http.ResponseWriter, r *http.Request, retryAfter time.Duration, msg string) {
+	h := w.Header()
+	h.Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	h.Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusTooManyRequests)
+	body := map[string]interface{}{
+		"code":    http.StatusTooManyRequests,
+		"message": msg,
+	}
+	if id := logging.RequestID(r.Context()); id != "" {
+		body["request_id"] = id
+	}
+	json.NewEncoder(w).Encode(body)
+}
+
+// callerKey identifies the caller for rate limiting: the authenticated
+// identity, or the client IP on routes that need none.
+func callerKey(r *http.Request) string {
+	id, _ := auth.FromContext(r.Context())
+	return Caller(id, r.RemoteAddr)
+}
+
+// hostOf is the host part of a remote address.
+func hostOf(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return remoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"data_storage/server/logging"
+	"data_storage/server/tracing"
+	"net/http"
+)
+
+// Tracing returns a middleware that starts the request's server span,
+// named "HTTP <method> <route>", continuing the caller's trace when the
+// request carries a valid traceparent header. It should run right after
+// LoggingMiddleWare so the middleware that follows is inside the span.
+func Tracing(route func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !tracing.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			if sc, err := tracing.ParseTraceparent(r.Header.Get(tracing.TraceparentHeader)); err == nil {
+				ctx = tracing.WithRemoteParent(ctx, sc)
+			}
+			name := route(r)
+			ctx, span := tracing.Start(ctx, "HTTP "+r.Method+" "+name)
+			defer span.End()
+			span.SetAttr("http.method", r.Method)
+			span.SetAttr("http.route", name)
+			if id := logging.RequestID(ctx); id != "" {
+				span.SetAttr("request_id", id)
+			}
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r.WithContext(ctx))
+			span.SetAttr("http.status_code", rec.status)
+			// the identity is set deeper in the chain; LoggingMiddleWare's
+			// request log has it
+			if info, ok := ctx.Value(requestLogKey{}).(*requestLog); ok && info.identity != "" {
+				span.SetAttr("identity", info.identity)
+			}
+		})
+	}
+}
+
+// TraceHandler returns a middleware, meant to run last, that wraps the
+// route's handler in a span named "handler <name>"; the gap between it
+// and the server span is time spent in middleware.
+func TraceHandler(name func(r *http.Request) string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx, span := tracing.Start(r.Context(), "handler "+name(r))
+			if span == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			defer span.End()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// limits bounds the commands readCommand accepts.
+type limits struct {
+	args    int   // arguments per command
+	bulk    int   // bytes per argument
+	request int64 // bytes of all arguments together; 0 is unbounded
+}
+
+// defaultLimits apply to authenticated sessions unless WithLimits
+// narrows them; bulk matches Redis' proto-max-bulk-len.
+var defaultLimits = limits{args: 1 << 20, bulk: 512 << 20}
+
+// unauthenticated is enough for AUTH, HELLO with AUTH and SETNAME, and
+// PING, so clients that have not authenticated cannot make the server
+// buffer large commands.
+var unauthenticated = limits{args: 8, bulk: 16 << 10}
+
+// maxInline caps an inline command or a protocol header line, like
+// Redis' 64KB limit on inline requests.
+const maxInline = 64 << 10
+
+var errProtocol = errors.New("protocol error")
+
+// readCommand reads one command, either a RESP array of bulk strings
+// or an inline command (space separated, as sent by telnet), within lim.
+func readCommand(r *bufio.Reader, lim limits) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, nil
+	}
+	if line[0] != '*' {
+		args := strings.Fields(line)
+		if len(args) > lim.args {
+			return nil, fmt.Errorf("%w: too many arguments", errProtocol)
+		}
+		for _, a := range args {
+			if len(a) > lim.bulk {
+				return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+			}
+		}
+		return args, nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n > lim.args {
+		return nil, fmt.Errorf("%w: invalid multibulk length", errProtocol)
+	}
+	args := make([]string, 0, max(n, 0))
+	var total int64
+	for i := 0; i < n; i++ {
+		hdr, err := readLine(r)
+		if err != nil {
+			return nil, err
+		}
+		if hdr == "" || hdr[0] != '$' {
+			return nil, fmt.Errorf("%w: expected '$', got %q", errProtocol, hdr)
+		}
+		size, err := strconv.Atoi(hdr[1:])
+		if err != nil || size < 0 || size > lim.bulk {
+			return nil, fmt.Errorf("%w: invalid bulk length", errProtocol)
+		}
+		if total += int64(size); lim.request > 0 && total > lim.request {
+			return nil, fmt.Errorf("%w: command too large", errProtocol)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		if buf[size] != '\r' || buf[size+1] != '\n' {
+			return nil, fmt.Errorf("%w: bulk string not terminated", errProtocol)
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+// readLine reads up to CRLF (or a bare LF) and strips the terminator.
+// Lines over maxInline are a protocol error.
+func readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > maxInline {
+			return "", fmt.Errorf("%w: too big inline request", errProtocol)
+		}
+		line = append(line, chunk...)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
+
+// writer encodes replies in RESP2 or RESP3 depending on the
+// protocol version negotiated with HELLO.
+type writer struct {
+	*bufio.Writer
+	proto int
+}
+
+func (w *writer) simple(s string) { fmt.Fprintf(w, "+%s\r\n", s) }
+
+func (w *writer) err(s string) { fmt.Fprintf(w, "-%s\r\n", s) }
+
+func (w *writer) integer(n int64) { fmt.Fprintf(w, ":%d\r\n", n) }
+
+func (w *writer) bulk(s string) { fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s) }
+
+func (w *writer) null() {
+	if w.proto >= 3 {
+		w.WriteString("_\r\n")
+		return
+	}
+	w.WriteString("$-1\r\n")
+}
+
+func (w *writer) arrayHeader(n int) { fmt.Fprintf(w, "*%d\r\n", n) }
+
+// mapHeader starts a map of n pairs; RESP2 clients see a flat array.
+func (w *writer) mapHeader(n int) {
+	if w.proto >= 3 {
+		fmt.Fprintf(w, "%%%d\r\n", n)
+		return
+	}
+	w.arrayHeader(n * 2)
+}
@@ -0,0 +1,618 @@
+// Package resp exposes the store over the Redis serialization protocol
+// (RESP2 and RESP3) so redis-cli and existing client libraries can
+// talk to it directly.
+package resp
+
+import (
+	"bufio"
+	"context"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/store_service"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server accepts RESP connections and maps commands onto the StoreService.
+type Server struct {
+	storeService store_service.StoreServiceRepo
+	authn        auth.Authenticator
+	limits       limits
+	limiter      *middleware.RateLimiter
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// Option configures a Server.
+type Option func(*Server)
+
+// WithLimits bounds the commands of authenticated clients by the
+// request and value limits the HTTP API applies: a command carries at
+// most maxRequest bytes of arguments and, as LPUSH key item..., at
+// most l.MaxPushItems items of l.MaxValueBytes each. Zero is
+// unlimited. Commands over the limits close the connection with a
+// protocol error, as Redis does.
+func WithLimits(maxRequest int64, l domain.Limits) Option {
+	return func(s *Server) {
+		s.limits.request = maxRequest
+		if l.MaxPushItems > 0 {
+			s.limits.args = max(l.MaxPushItems+2, unauthenticated.args)
+		}
+		if l.MaxValueBytes > 0 {
+			s.limits.bulk = max(l.MaxValueBytes, l.MaxKeyLength, unauthenticated.bulk)
+		}
+	}
+}
+
+// WithRateLimiter throttles commands per identity with the HTTP API's
+// buckets, and failed AUTH attempts per client IP. Throttled commands
+// get an error reply; the connection stays open.
+func WithRateLimiter(rl *middleware.RateLimiter) Option {
+	return func(s *Server) {
+		s.limiter = rl
+	}
+}
+
+// NewServer creates a RESP server. When authn is non-nil, clients must
+// AUTH with a token it accepts (or pass it via HELLO ... AUTH) before
+// other commands, and each command is checked against the token's ACL.
+// Until then commands are kept to a few small arguments.
+func NewServer(s store_service.StoreServiceRepo, authn auth.Authenticator, opts ...Option) *Server {
+	srv := &Server{
+		storeService: s,
+		authn:        authn,
+		limits:       defaultLimits,
+		conns:        make(map[net.Conn]struct{}),
+	}
+	for _, opt := range opts {
+		opt(srv)
+	}
+	return srv
+}
+
+// ListenAndServe listens on addr and serves until Close is called.
+func (s *Server) ListenAndServe(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on ln until Close is called.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return net.ErrClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serveConn(conn)
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Close stops accepting connections, closes open ones and waits for
+// their goroutines to finish.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+// session is the per-connection state.
+type session struct {
+	identity  *auth.Identity // nil until authenticated
+	namespace string         // chosen by SELECT; the identity's home namespace by default
+	addr      string         // the client's address
+	w         *writer
+}
+
+// setIdentity authenticates the session as id, in id's home namespace.
+func (sess *session) setIdentity(id *auth.Identity) {
+	sess.identity = id
+	sess.namespace = id.HomeNamespace()
+}
+
+// serveConn reads commands until the client disconnects. Replies are
+// buffered and flushed only once no further pipelined input is waiting.
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	sess := &session{addr: conn.RemoteAddr().String(), w: &writer{Writer: bufio.NewWriter(conn), proto: 2}}
+	if s.authn == nil {
+		sess.setIdentity(auth.FullAccess("anonymous"))
+	}
+
+	for {
+		lim := s.limits
+		if sess.identity == nil {
+			lim = unauthenticated
+		}
+		args, err := readCommand(r, lim)
+		if err != nil {
+			if errors.Is(err, errProtocol) {
+				sess.w.err("ERR " + err.Error())
+				sess.w.Flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				slog.Warn("RESP read error", "remote_addr", conn.RemoteAddr().String(), "err", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.dispatch(sess, args)
+
+		if r.Buffered() == 0 || quit {
+			if err := sess.w.Flush(); err != nil {
+				return
+			}
+		}
+		if quit {
+			return
+		}
+	}
+}
+
+// dispatch executes one command and reports whether the connection
+// should be closed afterwards.
+func (s *Server) dispatch(sess *session, args []string) bool {
+	w := sess.w
+	name := strings.ToUpper(args[0])
+
+	switch name {
+	case "QUIT":
+		w.simple("OK")
+		return true
+	case "AUTH":
+		s.auth(sess, args[1:])
+		return false
+	case "HELLO":
+		s.hello(sess, args[1:])
+		return false
+	}
+
+	if sess.identity == nil {
+		w.err("NOAUTH Authentication required.")
+		return false
+	}
+	if !allowed(sess.identity, name, args[1:]) {
+		w.err("NOPERM this user has no permissions to run the '" + strings.ToLower(name) + "' command or access one of its keys")
+		return false
+	}
+	if class, ok := commandClass[name]; ok && s.limiter != nil {
+		if ok, retryAfter := s.limiter.Allow(class, middleware.Caller(sess.identity, sess.addr)); !ok {
+			w.err("ERR rate limit exceeded, retry in " + retryAfter.Round(time.Millisecond).String())
+			return false
+		}
+	}
+
+	ctx := audit.WithRemoteAddr(auth.WithIdentity(context.Background(), sess.identity), sess.addr)
+	ctx = domain.WithNamespace(ctx, sess.namespace)
+	switch name {
+	case "PING":
+		s.ping(w, args[1:])
+	case "SELECT":
+		s.selectNamespace(sess, args[1:])
+	case "GET":
+		s.get(ctx, w, args[1:])
+	case "SET":
+		s.set(ctx, w, args[1:])
+	case "DEL":
+		s.del(ctx, w, args[1:])
+	case "LPUSH":
+		s.lpush(ctx, w, args[1:])
+	case "RPOP":
+		s.rpop(ctx, w, args[1:])
+	case "LLEN":
+		s.llen(ctx, w, args[1:])
+	case "EXPIRE":
+		s.expire(ctx, w, args[1:])
+	case "TTL":
+		s.ttl(ctx, w, args[1:])
+	default:
+		w.err("ERR unknown command '" + args[0] + "'")
+	}
+	return false
+}
+
+// commandClass maps commands onto ACL operation classes.
+var commandClass = map[string]auth.OpClass{
+	"GET":    auth.OpRead,
+	"LLEN":   auth.OpRead,
+	"TTL":    auth.OpRead,
+	"SET":    auth.OpWrite,
+	"LPUSH":  auth.OpWrite,
+	"RPOP":   auth.OpWrite,
+	"EXPIRE": auth.OpWrite,
+	"DEL":    auth.OpDelete,
+}
+
+// allowed reports whether id may run the command; DEL checks every key,
+// the other commands only their first argument.
+func allowed(id *auth.Identity, name string, args []string) bool {
+	class, ok := commandClass[name]
+	if !ok || len(args) == 0 {
+		// PING, unknown commands and arity errors are answered as usual
+		return true
+	}
+	keys := args[:1]
+	if name == "DEL" {
+		keys = args
+	}
+	for _, key := range keys {
+		if !id.Allows(class, key) {
+			return false
+		}
+	}
+	return true
+}
+
+// authenticate resolves candidate to an identity for the client of
+// sess, or returns the error to reply with. With a rate limiter,
+// failures count against the client's IP, and once it has failed too
+// often candidates are not even checked until its bucket refills.
+func (s *Server) authenticate(sess *session, candidate string) (*auth.Identity, string) {
+	if s.limiter != nil {
+		if blocked, retryAfter := s.limiter.AuthBlocked(sess.addr); blocked {
+			return nil, "ERR too many failed authentication attempts, retry in " + retryAfter.Round(time.Millisecond).String()
+		}
+	}
+	id, err := s.authn.Authenticate(candidate)
+	if err != nil {
+		if s.limiter != nil {
+			s.limiter.AuthFailed(sess.addr)
+		}
+		return nil, "WRONGPASS invalid username-password pair or user is disabled."
+	}
+	return id, ""
+}
+
+// auth handles AUTH <token> and AUTH <user> <token>; the user is ignored.
+func (s *Server) auth(sess *session, args []string) {
+	if len(args) < 1 || len(args) > 2 {
+		sess.w.err("ERR wrong number of arguments for 'auth' command")
+		return
+	}
+	if s.authn == nil {
+		sess.w.err("ERR AUTH called without any password configured")
+		return
+	}
+	id, msg := s.authenticate(sess, args[len(args)-1])
+	if id == nil {
+		sess.w.err(msg)
+		return
+	}
+	sess.setIdentity(id)
+	sess.w.simple("OK")
+}
+
+// hello handles HELLO [protover [AUTH user token] [SETNAME name]].
+func (s *Server) hello(sess *session, args []string) {
+	proto := sess.w.proto
+	if len(args) > 0 {
+		v, err := strconv.Atoi(args[0])
+		if err != nil || v < 2 || v > 3 {
+			sess.w.err("NOPROTO unsupported protocol version")
+			return
+		}
+		proto = v
+		args = args[1:]
+	}
+	for len(args) > 0 {
+		switch strings.ToUpper(args[0]) {
+		case "AUTH":
+			if len(args) < 3 {
+				sess.w.err("ERR syntax error in HELLO option 'auth'")
+				return
+			}
+			if s.authn != nil {
+				id, msg := s.authenticate(sess, args[2])
+				if id == nil {
+					sess.w.err(msg)
+					return
+				}
+				sess.setIdentity(id)
+			}
+			args = args[3:]
+		case "SETNAME":
+			if len(args) < 2 {
+				sess.w.err("ERR syntax error in HELLO option 'setname'")
+				return
+			}
+			args = args[2:]
+		default:
+			sess.w.err("ERR syntax error in HELLO option '" + args[0] + "'")
+			return
+		}
+	}
+	if sess.identity == nil {
+		sess.w.err("NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+		return
+	}
+
+	sess.w.proto = proto
+	w := sess.w
+	w.mapHeader(3)
+	w.bulk("server")
+	w.bulk("data_storage")
+	w.bulk("proto")
+	w.integer(int64(proto))
+	w.bulk("mode")
+	w.bulk("standalone")
+}
+
+// selectNamespace handles SELECT <namespace>. Unlike Redis, databases
+// are named: numeric names are namespaces like any other.
+func (s *Server) selectNamespace(sess *session, args []string) {
+	if len(args) != 1 {
+		sess.w.err("ERR wrong number of arguments for 'select' command")
+		return
+	}
+	ns := args[0]
+	if !domain.ValidNamespace(ns) {
+		sess.w.err("ERR invalid namespace '" + ns + "'")
+		return
+	}
+	if !sess.identity.CanUseNamespace(ns) {
+		sess.w.err("NOPERM this user has no permissions to access the '" + ns + "' namespace")
+		return
+	}
+	sess.namespace = ns
+	sess.w.simple("OK")
+}
+
+func (s *Server) ping(w *writer, args []string) {
+	switch len(args) {
+	case 0:
+		w.simple("PONG")
+	case 1:
+		w.bulk(args[0])
+	default:
+		w.err("ERR wrong number of arguments for 'ping' command")
+	}
+}
+
+func (s *Server) get(ctx context.Context, w *writer, args []string) {
+	if len(args) != 1 {
+		w.err("ERR wrong number of arguments for 'get' command")
+		return
+	}
+	value, err := s.storeService.GetString(ctx, args[0])
+	if err != nil {
+		if isMissing(err) {
+			w.null()
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	w.bulk(value)
+}
+
+// set handles SET key value [EX seconds | PX milliseconds]. Without an
+// expiry option the service default TTL applies.
+func (s *Server) set(ctx context.Context, w *writer, args []string) {
+	if len(args) < 2 {
+		w.err("ERR wrong number of arguments for 'set' command")
+		return
+	}
+	key, value := args[0], args[1]
+
+	var ttl time.Duration
+	for opts := args[2:]; len(opts) > 0; opts = opts[2:] {
+		if len(opts) < 2 {
+			w.err("ERR syntax error")
+			return
+		}
+		n, err := strconv.ParseInt(opts[1], 10, 64)
+		if err != nil || n <= 0 {
+			w.err("ERR invalid expire time in 'set' command")
+			return
+		}
+		switch strings.ToUpper(opts[0]) {
+		case "EX":
+			ttl = time.Duration(n) * time.Second
+		case "PX":
+			ttl = time.Duration(n) * time.Millisecond
+		default:
+			w.err("ERR syntax error")
+			return
+		}
+	}
+
+	if err := s.storeService.SetString(ctx, key, value, ttl); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.simple("OK")
+}
+
+// del handles DEL key [key ...] and replies with the number of keys removed.
+func (s *Server) del(ctx context.Context, w *writer, args []string) {
+	if len(args) == 0 {
+		w.err("ERR wrong number of arguments for 'del' command")
+		return
+	}
+	var removed int64
+	for _, key := range args {
+		if _, err := s.storeService.TTL(ctx, key); err != nil {
+			if isMissing(err) {
+				continue
+			}
+			writeError(w, err)
+			return
+		}
+		if err := s.storeService.DeleteString(ctx, key); err != nil {
+			writeError(w, err)
+			return
+		}
+		removed++
+	}
+	w.integer(removed)
+}
+
+// lpush handles LPUSH key item [item ...] and replies with the new length.
+func (s *Server) lpush(ctx context.Context, w *writer, args []string) {
+	if len(args) < 2 {
+		w.err("ERR wrong number of arguments for 'lpush' command")
+		return
+	}
+	// Redis pushes items one by one, so the last argument ends up first.
+	items := make([]string, 0, len(args)-1)
+	for i := len(args) - 1; i >= 1; i-- {
+		items = append(items, args[i])
+	}
+	if err := s.storeService.LPush(ctx, args[0], items...); err != nil {
+		writeError(w, err)
+		return
+	}
+	n, err := s.storeService.LLen(ctx, args[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.integer(int64(n))
+}
+
+func (s *Server) rpop(ctx context.Context, w *writer, args []string) {
+	if len(args) != 1 {
+		w.err("ERR wrong number of arguments for 'rpop' command")
+		return
+	}
+	value, err := s.storeService.RPop(ctx, args[0])
+	if err != nil {
+		if isMissing(err) || errors.Is(err, domain.ErrEmptyEntry) {
+			w.null()
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	w.bulk(value)
+}
+
+func (s *Server) llen(ctx context.Context, w *writer, args []string) {
+	if len(args) != 1 {
+		w.err("ERR wrong number of arguments for 'llen' command")
+		return
+	}
+	n, err := s.storeService.LLen(ctx, args[0])
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.integer(int64(n))
+}
+
+// expire handles EXPIRE key seconds; replies 1 if the timeout was set.
+func (s *Server) expire(ctx context.Context, w *writer, args []string) {
+	if len(args) != 2 {
+		w.err("ERR wrong number of arguments for 'expire' command")
+		return
+	}
+	secs, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		w.err("ERR value is not an integer or out of range")
+		return
+	}
+	if err := s.storeService.Expire(ctx, args[0], time.Duration(secs)*time.Second); err != nil {
+		if isMissing(err) {
+			w.integer(0)
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	w.integer(1)
+}
+
+// ttl handles TTL key: seconds left, -1 without expiry, -2 if missing.
+func (s *Server) ttl(ctx context.Context, w *writer, args []string) {
+	if len(args) != 1 {
+		w.err("ERR wrong number of arguments for 'ttl' command")
+		return
+	}
+	left, err := s.storeService.TTL(ctx, args[0])
+	if err != nil {
+		if isMissing(err) {
+			w.integer(-2)
+			return
+		}
+		writeError(w, err)
+		return
+	}
+	if left < 0 {
+		w.integer(-1)
+		return
+	}
+	// round up like Redis so a fresh "EX 10" reports 10, not 9
+	w.integer(int64((left + time.Second - 1) / time.Second))
+}
+
+func isMissing(err error) bool {
+	return errors.Is(err, domain.ErrNotFound) || errors.Is(err, domain.ErrExpiredEntry)
+}
+
+// writeError maps service errors onto Redis error replies.
+func writeError(w *writer, err error) {
+	if errors.Is(err, domain.ErrWrongType) {
+		w.err("WRONGTYPE Operation against a key holding the wrong kind of value")
+		return
+	}
+	if errors.Is(err, domain.ErrQuotaExceeded) {
+		w.err("OOM " + err.Error())
+		return
+	}
+	w.err("ERR " + err.Error())
+}
@@ -0,0 +1,100 @@
+// Package audit records who changed which key, and when, as JSON lines.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Redacted replaces values in records unless values are logged.
+const Redacted = "[REDACTED]"
+
+// Outcomes of an audited request.
+const (
+	OutcomeOK     = "ok"
+	OutcomeDenied = "denied" // rejected by authorization or rate limits
+	OutcomeFailed = "failed"
+)
+
+// Record is one audited mutation.
+type Record struct {
+	Time       time.Time `json:"time"`
+	Identity   string    `json:"identity"`
+	RemoteAddr string    `json:"remote_addr"`
+	Transport  string    `json:"transport"` // "http", "ws", "resp", "grpc" or "memcache"
+	Operation  string    `json:"operation"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Key        string    `json:"key,omitempty"`
+	Value      string    `json:"value,omitempty"`
+	Status     int       `json:"status,omitempty"` // HTTP and WebSocket only
+	Outcome    string    `json:"outcome"`
+	LatencyMS  float64   `json:"latency_ms"`
+}
+
+// Logger writes records as JSON lines.
+type Logger struct {
+	mu            sync.Mutex
+	w             io.Writer
+	enc           *json.Encoder
+	includeValues bool
+}
+
+// NewLogger writes to w, typically a *RotatingFile. Values are replaced
+// with Redacted unless includeValues is set.
+func NewLogger(w io.Writer, includeValues bool) *Logger {
+	return &Logger{w: w, enc: json.NewEncoder(w), includeValues: includeValues}
+}
+
+// Log appends rec. A record with a Value has it redacted unless the
+// logger includes values. Write failures are logged, never returned, so
+// auditing cannot fail a request that already happened.
+func (l *Logger) Log(rec Record) {
+	if rec.Value != "" && !l.includeValues {
+		rec.Value = Redacted
+	}
+	if rec.Time.IsZero() {
+		rec.Time = time.Now()
+	}
+	rec.Time = rec.Time.UTC()
+
+	l.mu.Lock()
+	err := l.enc.Encode(rec)
+	l.mu.Unlock()
+	if err != nil {
+		slog.Error("audit write failed", "err", err)
+	}
+}
+
+type remoteAddrKey struct{}
+
+// WithRemoteAddr records the caller's address in ctx for records made
+// away from the HTTP middleware.
+func WithRemoteAddr(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, remoteAddrKey{}, addr)
+}
+
+// RemoteAddr returns the address stored by WithRemoteAddr, if any.
+func RemoteAddr(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
+
+// OutcomeFor maps an HTTP status onto an outcome.
+func OutcomeFor(status int) string {
+	switch {
+	case status < 400:
+		return OutcomeOK
+	case status == 401, status == 403, status == 429:
+		return OutcomeDenied
+	}
+	return OutcomeFailed
+}
+
+// IncludesValues reports whether values are written unredacted.
+func (l *Logger) IncludesValues() bool {
+	return l.includeValues
+}
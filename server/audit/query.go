@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Filter selects records; zero fields match everything.
+type Filter struct {
+	Key   string
+	Since time.Time // inclusive
+	Until time.Time // exclusive
+}
+
+// Match reports whether rec passes the filter.
+func (f Filter) Match(rec Record) bool {
+	if f.Key != "" && rec.Key != f.Key {
+		return false
+	}
+	if !f.Since.IsZero() && rec.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !rec.Time.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// Query reads the audit file at path and its rotated siblings, oldest
+// first, and returns the matching records. Lines that are not valid
+// records, e.g. a torn final line, are skipped.
+func Query(path string, f Filter) ([]Record, error) {
+	files := append(RotatedFiles(path), path)
+
+	var out []Record
+	found := false
+	for _, name := range files {
+		file, err := os.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open audit file: %w", err)
+		}
+		found = true
+
+		sc := bufio.NewScanner(file)
+		sc.Buffer(make([]byte, 64*1024), 16<<20)
+		for sc.Scan() {
+			var rec Record
+			if json.Unmarshal(sc.Bytes(), &rec) != nil {
+				continue
+			}
+			if f.Match(rec) {
+				out = append(out, rec)
+			}
+		}
+		err = sc.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", name, err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("no audit files at %s", path)
+	}
+	return out, nil
+}
@@ -0,0 +1,141 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedLayout is appended to the file name of rotated files; it sorts
+// chronologically.
+const rotatedLayout = "20060102T150405.000000000"
+
+// RotatingFile is an append-only file that is renamed aside and
+// replaced once it exceeds MaxSize bytes or grows older than MaxAge.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu     sync.Mutex
+	f      *os.File
+	size   int64
+	opened time.Time
+}
+
+// OpenRotatingFile opens (or creates) path for appending. Zero maxSize or
+// maxAge disables that trigger; maxBackups > 0 deletes the oldest
+// rotated files beyond that count.
+func OpenRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("open audit file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("stat audit file: %w", err)
+	}
+	r.f = f
+	r.size = info.Size()
+	r.opened = time.Now()
+	if r.size > 0 {
+		// an existing file is as old as its last modification at best
+		r.opened = info.ModTime()
+	}
+	return nil
+}
+
+// Write appends p, rotating first if p would overflow the file or the
+// file is too old. A single write is never split across files.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.f == nil {
+		return 0, os.ErrClosed
+	}
+	if r.size > 0 && ((r.maxSize > 0 && r.size+int64(len(p)) > r.maxSize) ||
+		(r.maxAge > 0 && time.Since(r.opened) > r.maxAge)) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate renames the current file aside and opens a fresh one. If that
+// fails the file at path is reopened, or else the current one kept, so
+// that later writes still land somewhere and retry the rotation.
+func (r *RotatingFile) rotate() error {
+	old := r.f
+	rotated := r.path + "." + time.Now().UTC().Format(rotatedLayout)
+	if err := os.Rename(r.path, rotated); err != nil {
+		// e.g. the file was removed under us: start a new one at path
+		if r.open() == nil {
+			old.Close()
+		}
+		return fmt.Errorf("rotate audit file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		// keep appending to the renamed file under its old name
+		os.Rename(rotated, r.path)
+		return err
+	}
+	r.opened = time.Now()
+	r.prune()
+	return old.Close()
+}
+
+// prune removes the oldest rotated files beyond maxBackups.
+func (r *RotatingFile) prune() {
+	if r.maxBackups <= 0 {
+		return
+	}
+	rotated := RotatedFiles(r.path)
+	for len(rotated) > r.maxBackups {
+		os.Remove(rotated[0])
+		rotated = rotated[1:]
+	}
+}
+
+// Close closes the current file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+// RotatedFiles returns the rotated siblings of path, oldest first.
+func RotatedFiles(path string) []string {
+	matches, _ := filepath.Glob(path + ".*")
+	var out []string
+	for _, m := range matches {
+		suffix := strings.TrimPrefix(m, path+".")
+		if _, err := time.Parse(rotatedLayout, suffix); err == nil {
+			out = append(out, m)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
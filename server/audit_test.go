@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/adapters/grpcapi"
+	"data_storage/server/adapters/memcache"
+	"data_storage/server/adapters/resp"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_AuditLog(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "reader", Token: "reader-token", Prefixes: []string{"*"}, Classes: []auth.OpClass{auth.OpRead}})
+
+	// small enough that every record or two forces a rotation
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	file, err := audit.OpenRotatingFile(path, 300, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	defer file.Close()
+
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithAuditLog(audit.NewLogger(file, false)),
+	))
+	defer ts.Close()
+
+	do := func(token, method, path, body string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		resp.Body.Close()
+	}
+
+	do("my-secret-token", http.MethodPost, "/v1/string/invoice:1", `{"value":"card 4111"}`)
+	do("my-secret-token", http.MethodGet, "/v1/string/invoice:1", "")
+	do("reader-token", http.MethodDelete, "/v1/string/invoice:1", "")
+	do("my-secret-token", http.MethodPost, "/v1/list/jobs/push", `{"items":["a"]}`)
+	do("my-secret-token", http.MethodDelete, "/v1/string/invoice:1", "")
+
+	if len(audit.RotatedFiles(path)) == 0 {
+		t.Fatal("expected the audit file to rotate")
+	}
+
+	records, err := audit.Query(path, audit.Filter{})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(records) != 4 {
+		t.Fatalf("expected 4 mutation records (reads are not audited), got %+v", records)
+	}
+
+	set := records[0]
+	if set.Identity != "default" || set.Operation != "set" || set.Key != "invoice:1" || set.Outcome != audit.OutcomeOK {
+		t.Fatalf("unexpected set record %+v", set)
+	}
+	if set.Value != audit.Redacted || set.RemoteAddr == "" || set.LatencyMS < 0 {
+		t.Fatalf("expected a redacted value and request metadata, got %+v", set)
+	}
+	denied := records[1]
+	if denied.Identity != "reader" || denied.Operation != "del" || denied.Status != http.StatusForbidden || denied.Outcome != audit.OutcomeDenied {
+		t.Fatalf("unexpected denied record %+v", denied)
+	}
+
+	byKey, _ := audit.Query(path, audit.Filter{Key: "invoice:1", Since: set.Time.Add(time.Nanosecond)})
+	if len(byKey) != 2 {
+		t.Fatalf("expected 2 later invoice:1 records, got %+v", byKey)
+	}
+}
+
+// auditedService returns a service whose mutations over transport are
+// recorded in a fresh audit file, and a func reading the records back.
+func auditedService(t *testing.T, transport string) (store_service.StoreServiceRepo, func() []audit.Record) {
+	t.Helper()
+	repo := storage.NewDataRepo(time.Minute)
+	t.Cleanup(repo.ShutDownInvalidation)
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	file, err := audit.OpenRotatingFile(path, 1<<20, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	t.Cleanup(func() { file.Close() })
+	svc := store_service.Audited(store_service.NewStoreService(repo, time.Minute), audit.NewLogger(file, true), transport)
+	return svc, func() []audit.Record {
+		t.Helper()
+		recs, err := audit.Query(path, audit.Filter{})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		return recs
+	}
+}
+
+// checkRecord fails unless rec describes op on key by identity over
+// transport, with the given outcome.
+func checkRecord(t *testing.T, rec audit.Record, transport, identity, op, key, outcome string) {
+	t.Helper()
+	if rec.Transport != transport || rec.Identity != identity || rec.Operation != op || rec.Key != key || rec.Outcome != outcome || rec.RemoteAddr == "" {
+		t.Errorf("expected %s %s %s %s by %q with a remote address, got %+v", transport, op, key, outcome, identity, rec)
+	}
+}
+
+func TestAudit_RESP(t *testing.T) {
+	svc, records := auditedService(t, "resp")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := resp.NewServer(svc, auth.NewStaticRegistry("my-secret-token"))
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "AUTH my-secret-token\r\nSET k v\r\nGET k\r\nRPOP k\r\n")
+	r := bufio.NewReader(conn)
+	for i := 0; i < 4; i++ {
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+	}
+
+	recs := records()
+	if len(recs) != 2 {
+		t.Fatalf("expected the two mutations to be audited, got %+v", recs)
+	}
+	checkRecord(t, recs[0], "resp", "default", "set", "k", audit.OutcomeOK)
+	checkRecord(t, recs[1], "resp", "default", "rpop", "k", audit.OutcomeFailed)
+	if recs[0].Value != "v" {
+		t.Errorf("expected the value to be logged, got %q", recs[0].Value)
+	}
+}
+
+func TestAudit_GRPC(t *testing.T) {
+	svc, records := auditedService(t, "grpc")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpcapi.NewServer(svc, auth.NewStaticRegistry("my-secret-token"))
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	cli, err := client.NewGRPCClient(ln.Addr().String(), "my-secret-token")
+	if err != nil {
+		t.Fatalf("client setup: %v", err)
+	}
+	defer cli.Close()
+	ctx := context.Background()
+	cli.LPush(ctx, "jobs", "a", "b")
+	cli.GetString(ctx, "jobs")
+	cli.DeleteString(ctx, "jobs")
+
+	recs := records()
+	if len(recs) != 2 {
+		t.Fatalf("expected the two mutations to be audited, got %+v", recs)
+	}
+	checkRecord(t, recs[0], "grpc", "default", "lpush", "jobs", audit.OutcomeOK)
+	checkRecord(t, recs[1], "grpc", "default", "del", "jobs", audit.OutcomeOK)
+	if recs[0].Value != `["a","b"]` {
+		t.Errorf("expected the pushed items to be logged, got %q", recs[0].Value)
+	}
+}
+
+func TestAudit_Memcache(t *testing.T) {
+	svc, records := auditedService(t, "memcache")
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := memcache.NewServer(svc)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	io.WriteString(conn, "set n 0 0 1\r\n5\r\nincr n 2\r\nget n\r\n")
+	r := bufio.NewReader(conn)
+	for i := 0; i < 5; i++ { // STORED, 7, VALUE, 7, END
+		if _, err := r.ReadString('\n'); err != nil {
+			t.Fatalf("reply %d: %v", i, err)
+		}
+	}
+
+	recs := records()
+	if len(recs) != 2 {
+		t.Fatalf("expected the two mutations to be audited, got %+v", recs)
+	}
+	checkRecord(t, recs[0], "memcache", "", "store", "n", audit.OutcomeOK)
+	checkRecord(t, recs[1], "memcache", "", "incr", "n", audit.OutcomeOK)
+}
+
+func TestAudit_RotationFailureKeepsLogging(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	file, err := audit.OpenRotatingFile(path, 16, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("OpenRotatingFile: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write([]byte("first record\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// with the file gone the rename for the next rotation fails
+	os.Remove(path)
+	if _, err := file.Write([]byte("second record\n")); err == nil {
+		t.Fatal("expected the failed rotation to be reported")
+	}
+	if _, err := file.Write([]byte("third record\n")); err != nil {
+		t.Fatalf("expected writes to go on after a failed rotation, got %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "third record\n" {
+		t.Errorf("expected a fresh file at the original path, got %q", data)
+	}
+}
@@ -0,0 +1,133 @@
+// Package auth holds caller identities and the access rules attached
+// to them, independent of the transport that authenticated the caller.
+package auth
+
+import (
+	"context"
+	"data_storage/server/domain"
+	"strings"
+)
+
+// OpClass groups operations for authorization.
+type OpClass string
+
+const (
+	OpRead   OpClass = "read"
+	OpWrite  OpClass = "write"
+	OpDelete OpClass = "delete"
+	OpAdmin  OpClass = "admin"
+)
+
+// AllClasses lists every operation class.
+var AllClasses = []OpClass{OpRead, OpWrite, OpDelete, OpAdmin}
+
+// Valid reports whether c is a known operation class.
+func (c OpClass) Valid() bool {
+	for _, known := range AllClasses {
+		if c == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity is an authenticated caller and what it may do.
+type Identity struct {
+	Name string
+	// Prefixes restricts keys to those starting with one of the prefixes.
+	// A trailing '*' is ignored, and "*" or "" allows every key.
+	Prefixes []string
+	Classes  []OpClass
+	// Namespaces lists the namespaces the identity may use; "*" allows
+	// every namespace and none allows only domain.DefaultNamespace.
+	Namespaces []string
+}
+
+// FullAccess returns an identity allowed every class on every key of
+// every namespace.
+func FullAccess(name string) *Identity {
+	return &Identity{Name: name, Prefixes: []string{"*"}, Classes: AllClasses, Namespaces: []string{"*"}}
+}
+
+// Can reports whether the identity holds class.
+func (id *Identity) Can(class OpClass) bool {
+	for _, c := range id.Classes {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccess reports whether key (or key prefix) lies inside one of the
+// identity's allowed prefixes.
+func (id *Identity) CanAccess(key string) bool {
+	for _, p := range id.Prefixes {
+		p = strings.TrimSuffix(p, "*")
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the identity may perform class on key. Admin
+// operations only check the class; keyless data operations (key == "",
+// e.g. watching every key) require unrestricted key access.
+func (id *Identity) Allows(class OpClass, key string) bool {
+	if id == nil || !id.Can(class) {
+		return false
+	}
+	if class == OpAdmin {
+		return true
+	}
+	return id.CanAccess(key)
+}
+
+// CanUseNamespace reports whether the identity may use namespace ns.
+func (id *Identity) CanUseNamespace(ns string) bool {
+	if id == nil {
+		return false
+	}
+	if len(id.Namespaces) == 0 {
+		return ns == domain.DefaultNamespace
+	}
+	for _, n := range id.Namespaces {
+		if n == "*" || n == ns {
+			return true
+		}
+	}
+	return false
+}
+
+// HomeNamespace is the namespace of requests that select none:
+// domain.DefaultNamespace if the identity may use it, otherwise the
+// first namespace it is bound to.
+func (id *Identity) HomeNamespace() string {
+	if id == nil || id.CanUseNamespace(domain.DefaultNamespace) {
+		return domain.DefaultNamespace
+	}
+	return id.Namespaces[0]
+}
+
+// PatternPrefix returns the literal prefix of a glob pattern, i.e. every
+// key the pattern can match starts with it.
+func PatternPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx carrying id.
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// FromContext returns the identity stored by WithIdentity, if any.
+func FromContext(ctx context.Context) (*Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(*Identity)
+	return id, ok && id != nil
+}
@@ -0,0 +1,185 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// jwk is one entry of a JSON Web Key Set (RFC 7517). Only the members
+// needed for HS256, RS256 and ES256 are decoded.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	K   string `json:"k"`   // oct
+	N   string `json:"n"`   // RSA
+	E   string `json:"e"`   // RSA
+	Crv string `json:"crv"` // EC
+	X   string `json:"x"`   // EC
+	Y   string `json:"y"`   // EC
+}
+
+// verificationKey is a parsed JWK: exactly one of the key fields is set.
+type verificationKey struct {
+	alg    string // "HS256", "RS256" or "ES256"
+	secret []byte
+	rsa    *rsa.PublicKey
+	ecdsa  *ecdsa.PublicKey
+}
+
+// JWKSFile is a key set read from a local JWKS file. The file is
+// re-read whenever its size or modification time changes, so keys can be
+// rotated without a restart.
+type JWKSFile struct {
+	path string
+
+	mu      sync.RWMutex
+	modTime time.Time
+	size    int64
+	keys    map[string]verificationKey
+}
+
+// LoadJWKSFile reads the JWKS file at path.
+func LoadJWKSFile(path string) (*JWKSFile, error) {
+	ks := &JWKSFile{path: path}
+	if err := ks.refresh(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// key returns the key with the given ID, re-reading the file first if it
+// changed. An empty kid matches the only key of a single-key set.
+func (ks *JWKSFile) key(kid string) (verificationKey, error) {
+	if err := ks.refresh(); err != nil {
+		// keep serving the last good key set while the file is rewritten
+		ks.mu.RLock()
+		empty := ks.keys == nil
+		ks.mu.RUnlock()
+		if empty {
+			return verificationKey{}, err
+		}
+	}
+
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if kid == "" && len(ks.keys) == 1 {
+		for _, k := range ks.keys {
+			return k, nil
+		}
+	}
+	k, ok := ks.keys[kid]
+	if !ok {
+		return verificationKey{}, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+	}
+	return k, nil
+}
+
+// refresh re-reads the file when it changed since the last read.
+func (ks *JWKSFile) refresh() error {
+	info, err := os.Stat(ks.path)
+	if err != nil {
+		return fmt.Errorf("stat JWKS file: %w", err)
+	}
+	ks.mu.RLock()
+	unchanged := ks.keys != nil && info.ModTime().Equal(ks.modTime) && info.Size() == ks.size
+	ks.mu.RUnlock()
+	if unchanged {
+		return nil
+	}
+
+	data, err := os.ReadFile(ks.path)
+	if err != nil {
+		return fmt.Errorf("read JWKS file: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("JWKS file %s: %w", ks.path, err)
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.modTime = info.ModTime()
+	ks.size = info.Size()
+	ks.mu.Unlock()
+	return nil
+}
+
+// parseJWKS decodes {"keys":[...]}, skipping keys not meant for
+// signatures and rejecting unsupported ones.
+func parseJWKS(data []byte) (map[string]verificationKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]verificationKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		vk, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if k.Alg != "" && k.Alg != vk.alg {
+			return nil, fmt.Errorf("key %q: unsupported alg %q", k.Kid, k.Alg)
+		}
+		keys[k.Kid] = vk
+	}
+	return keys, nil
+}
+
+func (k jwk) parse() (verificationKey, error) {
+	switch k.Kty {
+	case "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return verificationKey{}, errors.New("invalid oct key")
+		}
+		return verificationKey{alg: "HS256", secret: secret}, nil
+	case "RSA":
+		n, errN := decodeBigInt(k.N)
+		e, errE := decodeBigInt(k.E)
+		if errN != nil || errE != nil || !e.IsInt64() {
+			return verificationKey{}, errors.New("invalid RSA key")
+		}
+		return verificationKey{alg: "RS256", rsa: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return verificationKey{}, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, errX := decodeBigInt(k.X)
+		y, errY := decodeBigInt(k.Y)
+		if errX != nil || errY != nil || !elliptic.P256().IsOnCurve(x, y) {
+			return verificationKey{}, errors.New("invalid EC key")
+		}
+		return verificationKey{alg: "ES256", ecdsa: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	}
+	return verificationKey{}, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+)
+
+var ErrInvalidJWT = errors.New("invalid JWT")
+
+// Claims that map a JWT onto an Identity.
+const (
+	// PrefixesClaim lists the key prefixes the bearer may access; without
+	// it the token grants no key access at all.
+	PrefixesClaim = "store_prefixes"
+	// ClassesClaim lists operation classes; without it the bearer gets
+	// read, write and delete but never admin.
+	ClassesClaim = "store_classes"
+	// NamespacesClaim lists the namespaces the bearer may use; without it
+	// the bearer only uses the default namespace.
+	NamespacesClaim = "store_namespaces"
+)
+
+// Authenticator resolves a bearer credential to an identity. Registry
+// and JWTValidator both implement it.
+type Authenticator interface {
+	Authenticate(token string) (*Identity, error)
+}
+
+// Chain tries each authenticator in order and returns the first
+// identity; the error of the last one is returned if none accepts.
+func Chain(auths ...Authenticator) Authenticator {
+	return chain(auths)
+}
+
+type chain []Authenticator
+
+func (c chain) Authenticate(token string) (*Identity, error) {
+	err := ErrUnknownToken
+	for _, a := range c {
+		var id *Identity
+		if id, err = a.Authenticate(token); err == nil {
+			return id, nil
+		}
+	}
+	return nil, err
+}
+
+// JWTValidator authenticates HS256, RS256 and ES256 signed JWTs against
+// a JWKS file and checks their registered claims.
+type JWTValidator struct {
+	Keys     *JWKSFile
+	Issuer   string        // required "iss" value; empty accepts any
+	Audience string        // value that must appear in "aud"; empty accepts any
+	Leeway   time.Duration // clock skew tolerated for exp and nbf
+	Now      func() time.Time
+}
+
+// NewJWTValidator returns a validator for tokens signed by keys.
+func NewJWTValidator(keys *JWKSFile, issuer, audience string, leeway time.Duration) *JWTValidator {
+	return &JWTValidator{Keys: keys, Issuer: issuer, Audience: audience, Leeway: leeway, Now: time.Now}
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	Subject    string          `json:"sub"`
+	Issuer     string          `json:"iss"`
+	Audience   json.RawMessage `json:"aud"`
+	ExpiresAt  *float64        `json:"exp"`
+	NotBefore  *float64        `json:"nbf"`
+	Prefixes   []string        `json:"store_prefixes"`
+	Classes    []OpClass       `json:"store_classes"`
+	Namespaces []string        `json:"store_namespaces"`
+}
+
+// Authenticate verifies token and maps its claims onto an Identity
+// named after the "sub" claim.
+func (v *JWTValidator) Authenticate(token string) (*Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidJWT)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidJWT, err)
+	}
+	key, err := v.Keys.key(header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+	// the key decides the algorithm, so an RSA public key can never be
+	// abused as an HMAC secret
+	if header.Alg != key.alg {
+		return nil, fmt.Errorf("%w: alg %q does not match key", ErrInvalidJWT, header.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature encoding", ErrInvalidJWT)
+	}
+	if !key.verify(parts[0]+"."+parts[1], sig) {
+		return nil, fmt.Errorf("%w: bad signature", ErrInvalidJWT)
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidJWT, err)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return nil, err
+	}
+
+	classes := claims.Classes
+	if classes == nil {
+		classes = []OpClass{OpRead, OpWrite, OpDelete}
+	}
+	return &Identity{Name: claims.Subject, Prefixes: claims.Prefixes, Classes: classes, Namespaces: claims.Namespaces}, nil
+}
+
+func (v *JWTValidator) checkClaims(c jwtClaims) error {
+	now := v.Now()
+	if c.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing exp", ErrInvalidJWT)
+	}
+	if now.After(unixTime(*c.ExpiresAt).Add(v.Leeway)) {
+		return fmt.Errorf("%w: expired", ErrInvalidJWT)
+	}
+	if c.NotBefore != nil && now.Add(v.Leeway).Before(unixTime(*c.NotBefore)) {
+		return fmt.Errorf("%w: not valid yet", ErrInvalidJWT)
+	}
+	if v.Issuer != "" && c.Issuer != v.Issuer {
+		return fmt.Errorf("%w: issuer %q", ErrInvalidJWT, c.Issuer)
+	}
+	if v.Audience != "" && !hasAudience(c.Audience, v.Audience) {
+		return fmt.Errorf("%w: audience", ErrInvalidJWT)
+	}
+	return nil
+}
+
+// hasAudience reports whether aud, a string or array of strings,
+// contains want.
+func hasAudience(aud json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(aud, &many) == nil {
+		for _, a := range many {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (k verificationKey) verify(signingInput string, sig []byte) bool {
+	digest := sha256.Sum256([]byte(signingInput))
+	switch k.alg {
+	case "HS256":
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write([]byte(signingInput))
+		return hmac.Equal(sig, mac.Sum(nil))
+	case "RS256":
+		return rsa.VerifyPKCS1v15(k.rsa, crypto.SHA256, digest[:], sig) == nil
+	case "ES256":
+		// JWS uses the fixed-size r||s encoding, not ASN.1
+		if len(sig) != 64 {
+			return false
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(k.ecdsa, digest[:], r, s)
+	}
+	return false
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// unixTime converts a NumericDate, which may carry a fraction.
+func unixTime(secs float64) time.Time {
+	whole := math.Floor(secs)
+	return time.Unix(int64(whole), int64((secs-whole)*float64(time.Second)))
+}
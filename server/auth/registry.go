@@ -0,0 +1,347 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"data_storage/server/domain"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownToken  = errors.New("unknown token")
+	ErrInvalidToken  = errors.New("invalid token definition")
+	ErrTokenNotFound = errors.New("token not found")
+	ErrBuiltinToken  = errors.New("the built-in token cannot be changed")
+)
+
+// Token is the persisted definition of an API token.
+type Token struct {
+	Name     string    `json:"name"`
+	Token    string    `json:"token,omitempty"`
+	Prefixes []string  `json:"prefixes"`
+	Classes  []OpClass `json:"classes"`
+	// Namespaces binds the token to namespaces (see Identity.Namespaces).
+	Namespaces []string `json:"namespaces,omitempty"`
+	// CertSubjects are the verified client certificate names (subject CN,
+	// or a DNS, URI or email SAN) that identify callers as this token.
+	CertSubjects []string `json:"cert_subjects,omitempty"`
+
+	static bool // bootstrap token from the environment; never written to the ACL file
+}
+
+// tokenFile is the on-disk layout of an ACL file.
+type tokenFile struct {
+	Tokens []Token `json:"tokens"`
+}
+
+// Registry maps bearer tokens to identities. Tokens are indexed by their
+// SHA-256 digest so lookups do not compare secrets byte by byte.
+type Registry struct {
+	mu        sync.RWMutex
+	byHash    map[[sha256.Size]byte]*Token
+	byName    map[string]*Token
+	bySubject map[string]*Token // from Token.CertSubjects
+	path      string            // ACL file rewritten after admin changes; may be empty
+
+	saveMu sync.Mutex // orders ACL file rewrites, so the last change wins
+}
+
+// NewRegistry creates an empty in-memory registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		byHash:    make(map[[sha256.Size]byte]*Token),
+		byName:    make(map[string]*Token),
+		bySubject: make(map[string]*Token),
+	}
+}
+
+// NewStaticRegistry returns a registry holding a single full-access token
+// named "default", matching the original single shared token behaviour.
+// It may use every namespace.
+// The default token is never written to an ACL file, cannot be replaced
+// or removed through Put and Remove, and no certificate maps onto it.
+func NewStaticRegistry(token string) *Registry {
+	r := NewRegistry()
+	if token != "" {
+		_ = r.put(Token{Name: "default", Token: token, Prefixes: []string{"*"}, Classes: AllClasses, Namespaces: []string{"*"}, static: true})
+	}
+	return r
+}
+
+// LoadFile reads a JSON ACL file of the form {"tokens":[...]} into the
+// registry. Later admin changes are written back to the same file.
+func (r *Registry) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read ACL file: %w", err)
+	}
+	var f tokenFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("parse ACL file %s: %w", path, err)
+	}
+	for _, t := range f.Tokens {
+		if err := r.put(t); err != nil {
+			return fmt.Errorf("ACL file %s: %w", path, err)
+		}
+	}
+
+	r.mu.Lock()
+	r.path = path
+	r.mu.Unlock()
+	return nil
+}
+
+// Reload replaces every token with the static token (see
+// NewStaticRegistry) and those in the ACL file at path, if not empty.
+// If the file cannot be loaded the current tokens stay in place.
+func (r *Registry) Reload(static, path string) error {
+	fresh := NewStaticRegistry(static)
+	if path != "" {
+		if err := fresh.LoadFile(path); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.byHash, r.byName, r.bySubject, r.path = fresh.byHash, fresh.byName, fresh.bySubject, fresh.path
+	r.mu.Unlock()
+	return nil
+}
+
+// Authenticate resolves a bearer token to its identity.
+func (r *Registry) Authenticate(token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrUnknownToken
+	}
+	r.mu.RLock()
+	t, ok := r.byHash[sha256.Sum256([]byte(token))]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownToken
+	}
+	return t.identity(), nil
+}
+
+// Lookup returns the identity of the token named name. No secret is
+// compared, so callers must have proven the name some other way.
+func (r *Registry) Lookup(name string) (*Identity, error) {
+	r.mu.RLock()
+	t, ok := r.byName[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return t.identity(), nil
+}
+
+// LookupCert returns the identity of the first token that lists one of
+// names, as vouched for by a verified client certificate, in its
+// CertSubjects. Certificates are never matched by token name.
+func (r *Registry) LookupCert(names []string) (*Identity, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, name := range names {
+		if t, ok := r.bySubject[name]; ok {
+			return t.identity(), nil
+		}
+	}
+	return nil, ErrTokenNotFound
+}
+
+// CertNames lists the names a certificate vouches for, CN first, for
+// LookupCert.
+func CertNames(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	for _, u := range cert.URIs {
+		names = append(names, u.String())
+	}
+	return append(names, cert.EmailAddresses...)
+}
+
+// identity copies the access rules of t.
+func (t *Token) identity() *Identity {
+	return &Identity{
+		Name:       t.Name,
+		Prefixes:   append([]string(nil), t.Prefixes...),
+		Classes:    append([]OpClass(nil), t.Classes...),
+		Namespaces: append([]string(nil), t.Namespaces...),
+	}
+}
+
+// secret returns the token secret and identity of the named token, for
+// schemes that prove possession of the secret without sending it.
+func (r *Registry) secret(name string) (string, *Identity, error) {
+	r.mu.RLock()
+	t, ok := r.byName[name]
+	r.mu.RUnlock()
+	if !ok {
+		return "", nil, ErrTokenNotFound
+	}
+	id, err := r.Lookup(name)
+	return t.Token, id, err
+}
+
+// Put adds or replaces the token named t.Name. An empty t.Token is
+// replaced by a random secret; the stored definition is returned.
+func (r *Registry) Put(t Token) (Token, error) {
+	if t.Token == "" {
+		t.Token = randomToken()
+	}
+	if err := r.put(t); err != nil {
+		return Token{}, err
+	}
+	return t, r.save()
+}
+
+func (r *Registry) put(t Token) error {
+	if t.Name == "" || t.Token == "" {
+		return fmt.Errorf("%w: name and token are required", ErrInvalidToken)
+	}
+	if len(t.Classes) == 0 {
+		return fmt.Errorf("%w: %q has no operation classes", ErrInvalidToken, t.Name)
+	}
+	for _, c := range t.Classes {
+		if !c.Valid() {
+			return fmt.Errorf("%w: unknown class %q", ErrInvalidToken, c)
+		}
+	}
+	if len(t.Prefixes) == 0 {
+		return fmt.Errorf("%w: %q has no key prefixes", ErrInvalidToken, t.Name)
+	}
+	for _, ns := range t.Namespaces {
+		if ns != "*" && !domain.ValidNamespace(ns) {
+			return fmt.Errorf("%w: %q has invalid namespace %q", ErrInvalidToken, t.Name, ns)
+		}
+	}
+	for _, subject := range t.CertSubjects {
+		if subject == "" {
+			return fmt.Errorf("%w: %q has an empty certificate subject", ErrInvalidToken, t.Name)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, replacing := r.byName[t.Name]
+	if replacing && old.static {
+		return fmt.Errorf("%w: %q", ErrBuiltinToken, t.Name)
+	}
+	if other, ok := r.byHash[sha256.Sum256([]byte(t.Token))]; ok && other.Name != t.Name {
+		return fmt.Errorf("%w: token already assigned to %q", ErrInvalidToken, other.Name)
+	}
+	for _, subject := range t.CertSubjects {
+		if other, ok := r.bySubject[subject]; ok && other.Name != t.Name {
+			return fmt.Errorf("%w: certificate subject %q already bound to %q", ErrInvalidToken, subject, other.Name)
+		}
+	}
+	if replacing {
+		r.unindex(old)
+	}
+	stored := t
+	r.byName[t.Name] = &stored
+	r.byHash[sha256.Sum256([]byte(t.Token))] = &stored
+	for _, subject := range t.CertSubjects {
+		r.bySubject[subject] = &stored
+	}
+	return nil
+}
+
+// unindex drops t from the secret and certificate indexes; r.mu must be
+// held.
+func (r *Registry) unindex(t *Token) {
+	delete(r.byHash, sha256.Sum256([]byte(t.Token)))
+	for _, subject := range t.CertSubjects {
+		delete(r.bySubject, subject)
+	}
+}
+
+// Remove deletes the token with the given name. The built-in token
+// cannot be removed.
+func (r *Registry) Remove(name string) error {
+	r.mu.Lock()
+	t, ok := r.byName[name]
+	if ok && t.static {
+		r.mu.Unlock()
+		return fmt.Errorf("%w: %q", ErrBuiltinToken, name)
+	}
+	if ok {
+		delete(r.byName, name)
+		r.unindex(t)
+	}
+	r.mu.Unlock()
+
+	if !ok {
+		return ErrTokenNotFound
+	}
+	return r.save()
+}
+
+// List returns all token definitions ordered by name, without secrets.
+func (r *Registry) List() []Token {
+	r.mu.RLock()
+	out := make([]Token, 0, len(r.byName))
+	for _, t := range r.byName {
+		c := *t
+		c.Token = ""
+		out = append(out, c)
+	}
+	r.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// save rewrites the ACL file, if one was loaded, through a temporary
+// file in the same directory.
+func (r *Registry) save() error {
+	r.saveMu.Lock()
+	defer r.saveMu.Unlock()
+
+	r.mu.RLock()
+	path := r.path
+	f := tokenFile{Tokens: make([]Token, 0, len(r.byName))}
+	for _, t := range r.byName {
+		if !t.static {
+			f.Tokens = append(f.Tokens, *t)
+		}
+	}
+	r.mu.RUnlock()
+	if path == "" {
+		return nil
+	}
+
+	sort.Slice(f.Tokens, func(i, j int) bool { return f.Tokens[i].Name < f.Tokens[j].Name })
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("write ACL file: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write ACL file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write ACL file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func randomToken() string {
+	var b [24]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
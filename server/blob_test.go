@@ -0,0 +1,144 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+)
+
+func TestBlobs_RoundTripAndRanges(t *testing.T) {
+	srv, err := New(testConfig(""), "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	base := "http://" + srv.Addr()
+	ctx := context.Background()
+
+	c, _ := client.NewClient(base, "my-secret-token")
+	blobs := c.(client.BlobClient)
+
+	data := []byte{0x89, 'P', 'N', 'G', 0x00, 0xff, 0xfe, '\r', '\n', 0x1a}
+	if err := blobs.SetBlob(ctx, "logo", data, "image/png", time.Hour); err != nil {
+		t.Fatalf("SetBlob: %v", err)
+	}
+	got, err := blobs.GetBlob(ctx, "logo")
+	if err != nil {
+		t.Fatalf("GetBlob: %v", err)
+	}
+	if !bytes.Equal(got.Data, data) || got.ContentType != "image/png" || got.ETag == "" {
+		t.Errorf("unexpected blob %q %q %q", got.Data, got.ContentType, got.ETag)
+	}
+
+	part, err := blobs.GetBlobRange(ctx, "logo", 4, 3)
+	if err != nil {
+		t.Fatalf("GetBlobRange: %v", err)
+	}
+	if !bytes.Equal(part.Data, data[4:7]) {
+		t.Errorf("expected bytes 4-6, got %q", part.Data)
+	}
+	tail, _ := blobs.GetBlobRange(ctx, "logo", 8, -1)
+	if !bytes.Equal(tail.Data, data[8:]) {
+		t.Errorf("expected the tail, got %q", tail.Data)
+	}
+
+	// the ETag makes conditional requests work
+	req, _ := http.NewRequest(http.MethodGet, base+"/v1/blob/logo", nil)
+	req.Header.Set("Authorization", "Bearer my-secret-token")
+	req.Header.Set("If-None-Match", got.ETag)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotModified {
+		t.Errorf("expected 304 for a matching ETag, got %d", resp.StatusCode)
+	}
+
+	// untyped uploads default to octet-stream, bad types are refused
+	blobs.SetBlob(ctx, "raw", data, "", time.Hour)
+	if raw, _ := blobs.GetBlob(ctx, "raw"); raw == nil || raw.ContentType != "application/octet-stream" {
+		t.Errorf("expected application/octet-stream, got %+v", raw)
+	}
+	var he *client.HTTPError
+	if err := blobs.SetBlob(ctx, "bad", data, "not a type", time.Hour); !errors.As(err, &he) || he.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid content type to be refused, got %v", err)
+	}
+
+	if err := blobs.DeleteBlob(ctx, "logo"); err != nil {
+		t.Fatalf("DeleteBlob: %v", err)
+	}
+	if _, err := blobs.GetBlob(ctx, "logo"); err == nil {
+		t.Error("expected the blob to be gone")
+	}
+}
+
+func TestBlobs_SnapshotKeepsBinaryValues(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	ctx := context.Background()
+	data := string([]byte{0x00, 0xff, 0xc3, 0x28, 'o', 'k'})
+
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	entry := domain.NewStringEntry(data, time.Hour)
+	entry.ContentType = "application/x-test"
+	repo.Set(ctx, "bin", entry)
+	if _, err := repo.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	loaded := storage.NewDataRepo(time.Minute)
+	defer loaded.ShutDownInvalidation()
+	if _, err := loaded.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	got, err := loaded.Get(ctx, "bin")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.Str != data || got.ContentType != "application/x-test" {
+		t.Errorf("expected the binary value back, got %q as %q", got.Str, got.ContentType)
+	}
+}
+
+func TestSnapshot_BinaryListItemsRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	ctx := context.Background()
+	items := []string{"plain", string([]byte{0x00, 0xff, 0xfe}), "", string([]byte{0xc3, 0x28})}
+
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	repo.Set(ctx, "list", domain.NewListEntry(items, time.Hour))
+	if _, err := repo.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+
+	loaded := storage.NewDataRepo(time.Minute)
+	defer loaded.ShutDownInvalidation()
+	if _, err := loaded.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	got, err := loaded.Get(ctx, "list")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(got.Items) != len(items) {
+		t.Fatalf("expected %d items, got %q", len(items), got.Items)
+	}
+	for i := range items {
+		if got.Items[i] != items[i] {
+			t.Errorf("item %d: got %q, want %q", i, got.Items[i], items[i])
+		}
+	}
+}
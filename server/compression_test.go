@@ -0,0 +1,186 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+)
+
+func TestCompression_StorageRoundTrip(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	repo.SetCompression(storage.Compression{MinSize: 64})
+	ctx := context.Background()
+
+	doc := strings.Repeat(`{"id":1,"name":"widget","tags":["a","b"]},`, 100)
+	lookalike := "\x1f\x8bnot really gzip" // short, but must not be mistaken for gzip
+	repo.Set(ctx, "doc", domain.NewStringEntry(doc, time.Hour))
+	repo.Set(ctx, "list", domain.NewListEntry([]string{doc, "small", lookalike}, time.Hour))
+
+	got, err := repo.Get(ctx, "doc")
+	if err != nil || got.Str != doc || got.Encoding != "" {
+		t.Fatalf("expected the decoded string back, got %v %q", err, got.Encoding)
+	}
+	list, _ := repo.Get(ctx, "list")
+	if len(list.Items) != 3 || list.Items[0] != doc || list.Items[1] != "small" || list.Items[2] != lookalike {
+		t.Errorf("unexpected list items %q", list.Items)
+	}
+	stored, _ := repo.GetEncoded(ctx, "doc")
+	if !stored.Gzipped() || len(stored.Str) >= len(doc) {
+		t.Errorf("expected the string to be held compressed, %d of %d bytes", len(stored.Str), len(doc))
+	}
+
+	// accounting sees the compressed sizes
+	stats := repo.Stats()
+	if stats.Bytes >= int64(2*len(doc)) || stats.Saved <= 0 {
+		t.Errorf("expected compressed accounting, got %d bytes and %d saved", stats.Bytes, stats.Saved)
+	}
+
+	// updates see decoded values, and snapshots hold them
+	err = repo.Update(ctx, "doc", func(current *domain.Entry) (*domain.Entry, error) {
+		if current.Str != doc {
+			t.Errorf("expected Update to see the decoded value")
+		}
+		return current, nil
+	})
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "store.json")
+	if _, err := repo.SaveSnapshot(path); err != nil {
+		t.Fatalf("SaveSnapshot: %v", err)
+	}
+	loaded := storage.NewDataRepo(time.Minute)
+	defer loaded.ShutDownInvalidation()
+	if _, err := loaded.LoadSnapshot(path); err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if list, _ := loaded.Get(ctx, "list"); list == nil || list.Items[0] != doc || list.Items[1] != "small" {
+		t.Errorf("expected the snapshot to keep the list, got %+v", list)
+	}
+
+	// turning compression off leaves stored values readable
+	repo.SetCompression(storage.Compression{})
+	repo.Set(ctx, "plain", domain.NewStringEntry(doc, time.Hour))
+	if plain, _ := repo.GetEncoded(ctx, "plain"); plain.Encoding != "" {
+		t.Errorf("expected new values to be stored as is")
+	}
+	if got, _ := repo.Get(ctx, "doc"); got == nil || got.Str != doc {
+		t.Errorf("expected the compressed value to stay readable")
+	}
+}
+
+func TestCompression_BlobPassThrough(t *testing.T) {
+	cfg := testConfig("")
+	cfg.CompressionMinBytes = 64
+	cfg.MaxValueBytes = 1 << 16
+	srv, err := New(cfg, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	base := "http://" + srv.Addr()
+
+	// a client that leaves Accept-Encoding and decoding to the test
+	raw := &http.Client{Transport: &http.Transport{DisableCompression: true}}
+	do := func(method, path string, body []byte, header ...string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, base+path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer my-secret-token")
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		resp, err := raw.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+
+	doc := strings.Repeat(`{"event":"click","x":10,"y":20}`, 200)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	io.WriteString(zw, doc)
+	zw.Close()
+
+	resp := do(http.MethodPut, "/v1/blob/events", gz.Bytes(), "Content-Type", "application/json", "Content-Encoding", "gzip")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("PUT gzip: %d", resp.StatusCode)
+	}
+
+	// accepted gzip comes back exactly as uploaded
+	resp = do(http.MethodGet, "/v1/blob/events", nil, "Accept-Encoding", "gzip")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "gzip" || !bytes.Equal(body, gz.Bytes()) {
+		t.Errorf("expected the uploaded gzip stream, got %q and %d bytes", resp.Header.Get("Content-Encoding"), len(body))
+	}
+	if !strings.HasSuffix(resp.Header.Get("ETag"), `-gzip"`) || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected headers %v", resp.Header)
+	}
+
+	// other callers get it decoded, over every API
+	resp = do(http.MethodGet, "/v1/blob/events", nil)
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" || string(body) != doc {
+		t.Errorf("expected the decoded value, got %d bytes", len(body))
+	}
+	c, _ := client.NewClient(base, "my-secret-token")
+	if s, err := c.GetString(context.Background(), "events"); err != nil || s != doc {
+		t.Errorf("expected GetString to decode, got %v", err)
+	}
+
+	// plain writes are compressed by the store and can be fetched compressed
+	c.SetString(context.Background(), "plain", doc, time.Hour)
+	resp = do(http.MethodGet, "/v1/blob/plain", nil, "Accept-Encoding", "br, gzip;q=0.5")
+	zr, err := gzip.NewReader(resp.Body)
+	if err != nil || resp.Header.Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected a gzip body, got %v", err)
+	}
+	body, _ = io.ReadAll(zr)
+	resp.Body.Close()
+	if string(body) != doc {
+		t.Errorf("expected the value after decoding, got %d bytes", len(body))
+	}
+
+	// bad encodings are refused
+	for _, tc := range []struct {
+		body     []byte
+		encoding string
+		want     int
+	}{
+		{[]byte("data"), "br", http.StatusUnsupportedMediaType},
+		{[]byte("not gzip"), "gzip", http.StatusBadRequest},
+		{gzipOf(t, strings.Repeat("x", 1<<17)), "gzip", http.StatusRequestEntityTooLarge},
+	} {
+		resp := do(http.MethodPut, "/v1/blob/bad", tc.body, "Content-Encoding", tc.encoding)
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Errorf("Content-Encoding %s: expected %d, got %d", tc.encoding, tc.want, resp.StatusCode)
+		}
+	}
+}
+
+func gzipOf(t *testing.T, s string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	io.WriteString(zw, s)
+	zw.Close()
+	return buf.Bytes()
+}
@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/config"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestConfig_FileWithEnvOverrides(t *testing.T) {
+	path := writeFile(t, "server.yaml", `
+listen:
+  http: 127.0.0.1:9000
+  resp: 127.0.0.1:6380
+auth:
+  token: file-token
+limits:
+  reads: "10:20"
+eviction:
+  default_ttl: 2m
+  cleanup_interval: 1s
+logging:
+  level: debug
+`)
+	t.Setenv("HTTP_ADDR", "127.0.0.1:0")
+	t.Setenv("LOG_LEVEL", "warn")
+
+	cfg, err := config.LoadServer(path)
+	if err != nil {
+		t.Fatalf("LoadServer: %v", err)
+	}
+	if cfg.HTTPAddr != "127.0.0.1:0" || cfg.LogLevel != "warn" {
+		t.Errorf("expected the environment to win, got addr %q level %q", cfg.HTTPAddr, cfg.LogLevel)
+	}
+	if cfg.RESPAddr != "127.0.0.1:6380" || cfg.APIToken != "file-token" || cfg.DefaultTTL != 2*time.Minute || cfg.CleanUpInterval != time.Second {
+		t.Errorf("expected file values, got %+v", cfg)
+	}
+	if cfg.ReadRateLimit != (config.RateLimit{PerSecond: 10, Burst: 20}) {
+		t.Errorf("unexpected read limit %+v", cfg.ReadRateLimit)
+	}
+	if cfg.ShutdownTimeout != 30*time.Second || cfg.AuthMode != "token" {
+		t.Errorf("expected defaults for unset settings, got %+v", cfg)
+	}
+}
+
+func TestConfig_ErrorsAreAggregated(t *testing.T) {
+	path := writeFile(t, "server.yaml", `
+auth:
+  mode: ldap
+logging:
+  level: loud
+  colour: true
+eviction:
+  cleanup_interval: soon
+`)
+	_, err := config.LoadServer(path)
+	if err == nil || !strings.Contains(err.Error(), `unknown setting "logging.colour"`) {
+		t.Fatalf("expected the unknown key to be rejected, got %v", err)
+	}
+
+	path = writeFile(t, "server.yaml", `
+auth:
+  mode: ldap
+logging:
+  level: loud
+eviction:
+  cleanup_interval: soon
+tls:
+  cert_file: cert.pem
+compression:
+  level: 12
+`)
+	_, err = config.LoadServer(path)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, want := range []string{"auth.mode (AUTH_MODE)", "logging.level (LOG_LEVEL)", "eviction.cleanup_interval (CLEANUP_INTERVAL)", "tls.key_file (TLS_KEY_FILE) must be set together", "compression.level (COMPRESSION_LEVEL) must be between 1 and 9"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestConfig_TokenOnlyRequiredWithoutOtherCallers(t *testing.T) {
+	if _, err := config.LoadServer(""); err == nil {
+		t.Fatal("expected an error without any credentials")
+	}
+	t.Setenv("ACL_FILE", "acl.json")
+	if _, err := config.LoadServer(""); err != nil {
+		t.Fatalf("expected an ACL file to be enough, got %v", err)
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	acl := writeFile(t, "acl.json", `{"tokens":[{"name":"reader","token":"reader-token","prefixes":["*"],"classes":["read"]}]}`)
+	t.Setenv("STORE_API_TOKEN", "old-token")
+	t.Setenv("ACL_FILE", acl)
+	t.Setenv("HTTP_ADDR", "127.0.0.1:0")
+	cfg, err := config.LoadServer("")
+	if err != nil {
+		t.Fatalf("LoadServer: %v", err)
+	}
+	srv, err := New(cfg, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	base := "http://" + srv.Addr()
+
+	// rotate the default token, drop the reader and limit writes
+	os.WriteFile(acl, []byte(`{"tokens":[{"name":"ops","token":"ops-token","prefixes":["*"],"classes":["admin"]}]}`), 0o600)
+	t.Setenv("STORE_API_TOKEN", "new-token")
+	t.Setenv("RATE_LIMIT_WRITES", "1:1")
+	t.Setenv("HTTP_ADDR", "127.0.0.1:1")
+	next, err := config.LoadServer("")
+	if err != nil {
+		t.Fatalf("LoadServer: %v", err)
+	}
+	if got := cfg.RestartRequired(next); len(got) != 1 || got[0] != "HTTP_ADDR" {
+		t.Errorf("expected only HTTP_ADDR to need a restart, got %v", got)
+	}
+	if err := srv.Reload(next); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	status := func(token, method, path string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, base+path, strings.NewReader(`{"value":"v"}`))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if got := status("old-token", http.MethodPost, "/v1/string/k"); got != http.StatusUnauthorized {
+		t.Fatalf("expected the old token to be rejected, got %d", got)
+	}
+	if got := status("reader-token", http.MethodGet, "/v1/string/k"); got != http.StatusUnauthorized {
+		t.Fatalf("expected the removed reader to be rejected, got %d", got)
+	}
+	if got := status("new-token", http.MethodPost, "/v1/string/k"); got != http.StatusOK {
+		t.Fatalf("expected the new token to work, got %d", got)
+	}
+	if got := status("new-token", http.MethodPost, "/v1/string/k"); got != http.StatusTooManyRequests {
+		t.Fatalf("expected the reloaded write limit, got %d", got)
+	}
+
+	// /v1/admin/info shows the reloaded settings, but the restart-only
+	// ones still as started
+	req, _ := http.NewRequest(http.MethodGet, base+"/v1/admin/info", nil)
+	req.Header.Set("Authorization", "Bearer ops-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /v1/admin/info: %v", err)
+	}
+	var info struct {
+		Config map[string]string `json:"config"`
+	}
+	json.NewDecoder(resp.Body).Decode(&info)
+	resp.Body.Close()
+	if info.Config["RATE_LIMIT_WRITES"] != "1:1" || info.Config["HTTP_ADDR"] != "127.0.0.1:0" {
+		t.Errorf("info config after reload = %v", info.Config)
+	}
+	// reloading the same settings again warns about nothing new
+	if got := srv.requested.RestartRequired(next); len(got) != 0 {
+		t.Errorf("expected no new restart-only changes, got %v", got)
+	}
+
+	// a broken ACL file leaves the tokens alone
+	os.WriteFile(acl, []byte(`{`), 0o600)
+	if err := srv.Reload(next); err == nil {
+		t.Fatal("expected the broken ACL file to be rejected")
+	}
+	if got := status("new-token", http.MethodGet, "/v1/string/k"); got != http.StatusOK {
+		t.Fatalf("expected the new token to keep working, got %d", got)
+	}
+}
+
+func TestConfig_WatchFiles(t *testing.T) {
+	path := writeFile(t, "server.yaml", "logging:\n  level: info\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changed := config.WatchFiles(ctx, 10*time.Millisecond, path, "")
+
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(path, []byte("logging:\n  level: debug\n"), 0o600)
+	select {
+	case <-changed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected a change notification")
+	}
+}
+
+func TestConfig_MemcacheStaysLocalWithoutIdentity(t *testing.T) {
+	t.Setenv("STORE_API_TOKEN", "token")
+	t.Setenv("MEMCACHE_ADDR", ":11211")
+	cfg, err := config.LoadServer("")
+	if err != nil {
+		t.Fatalf("LoadServer: %v", err)
+	}
+	if cfg.MemcacheAddr != "127.0.0.1:11211" {
+		t.Errorf("expected a bare port to bind loopback, got %q", cfg.MemcacheAddr)
+	}
+
+	t.Setenv("MEMCACHE_ADDR", "0.0.0.0:11211")
+	if _, err := config.LoadServer(""); err == nil || !strings.Contains(err.Error(), "(MEMCACHE_ADDR) must be a loopback address") {
+		t.Fatalf("expected a public address to be rejected, got %v", err)
+	}
+
+	t.Setenv("MEMCACHE_IDENTITY", "cache")
+	if cfg, err := config.LoadServer(""); err != nil || cfg.MemcacheAddr != "0.0.0.0:11211" {
+		t.Fatalf("expected a bound identity to allow any address, got %v", err)
+	}
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "strings"
+
+// EncodingGzip marks entries whose values may be gzip-compressed, see
+// Entry.Encoding.
+const EncodingGzip = "gzip"
+
+// gzipMagic starts every gzip member.
+const gzipMagic = "\x1f\x8b"
+
+// IsGzip reports whether s starts with the gzip header.
+func IsGzip(s string) bool {
+	return strings.HasPrefix(s, gzipMagic)
+}
+
+// Gzipped reports whether e holds Str gzip-compressed, so it can be
+// handed out with Content-Encoding: gzip as is.
+func (e *Entry) Gzipped() bool {
+	return e.Encoding == EncodingGzip && IsGzip(e.Str)
+}
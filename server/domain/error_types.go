@@ -9,4 +9,22 @@ var (
 	ErrEmptyKey     = errors.New("key or id is empty")
 	ErrEmptyValue   = errors.New("value is empty")
 	ErrExpiredEntry = errors.New("entry has expired")
+
+	ErrInvalidPattern = errors.New("invalid key pattern")
+	ErrInvalidWebhook = errors.New("invalid webhook")
+	ErrKeyExists      = errors.New("key already exists")
+	ErrCASMismatch    = errors.New("entry was modified since it was read")
+	ErrNotInteger     = errors.New("value is not an integer")
+	ErrInvalidMode    = errors.New("invalid write mode")
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+
+	ErrInvalidContentType  = errors.New("invalid content type")
+	ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+	ErrInvalidEncoding     = errors.New("value does not match its content encoding")
+
+	// oversize input, see Limits
+	ErrKeyTooLong    = errors.New("key is too long")
+	ErrValueTooLarge = errors.New("value is too large")
+	ErrTooManyItems  = errors.New("too many items in one push")
+	ErrListTooLong   = errors.New("list is too long")
 )
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// EventType names a keyspace change.
+type EventType string
+
+const (
+	EventSet     EventType = "set"
+	EventDel     EventType = "del"
+	EventExpired EventType = "expired"
+)
+
+// Event describes a single keyspace change. IDs increase monotonically
+// so watchers can resume after the last event they saw.
+type Event struct {
+	ID        uint64    `json:"id"`
+	Type      EventType `json:"type"`
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Time      time.Time `json:"time"`
+}
@@ -2,8 +2,22 @@ package domain
 
 import "context"
 
+// EntryRepository stores entries. Keys live in the namespace of the
+// context passed to each call (see WithNamespace).
 type EntryRepository interface {
 	Get(ctx context.Context, key string) (*Entry, error)
 	Set(ctx context.Context, key string, entry *Entry) error
 	Remove(ctx context.Context, key string) error
+
+	// Update atomically replaces the entry at key with the result of fn.
+	// fn receives nil if the key is missing or expired; returning a nil
+	// entry deletes the key, returning an error aborts without changes.
+	Update(ctx context.Context, key string, fn func(current *Entry) (*Entry, error)) error
+	// Flush removes every entry of the namespace.
+	Flush(ctx context.Context) error
+
+	// Watch streams keyspace events of the namespace for keys matching
+	// pattern, replaying buffered events newer than afterID. The channel
+	// closes when ctx ends or the watcher falls too far behind.
+	Watch(ctx context.Context, pattern string, afterID uint64) (<-chan Event, error)
 }
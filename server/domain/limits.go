@@ -0,0 +1,51 @@
+package domain
+
+import "fmt"
+
+// Limits caps the size of what callers may store. A zero limit is
+// unlimited.
+type Limits struct {
+	MaxKeyLength  int // bytes per key
+	MaxValueBytes int // bytes per string value or list item
+	MaxPushItems  int // items per push
+	MaxListLength int // items per list
+}
+
+// CheckKey returns ErrKeyTooLong if key is over the limit.
+func (l Limits) CheckKey(key string) error {
+	if l.MaxKeyLength > 0 && len(key) > l.MaxKeyLength {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrKeyTooLong, len(key), l.MaxKeyLength)
+	}
+	return nil
+}
+
+// CheckValue returns ErrValueTooLarge if value is over the limit.
+func (l Limits) CheckValue(value string) error {
+	if l.MaxValueBytes > 0 && len(value) > l.MaxValueBytes {
+		return fmt.Errorf("%w: %d bytes, the limit is %d", ErrValueTooLarge, len(value), l.MaxValueBytes)
+	}
+	return nil
+}
+
+// CheckPush returns ErrTooManyItems if a single push carries too many
+// items, or ErrValueTooLarge for the first item over the value limit.
+func (l Limits) CheckPush(items []string) error {
+	if l.MaxPushItems > 0 && len(items) > l.MaxPushItems {
+		return fmt.Errorf("%w: %d items, the limit is %d", ErrTooManyItems, len(items), l.MaxPushItems)
+	}
+	for _, item := range items {
+		if err := l.CheckValue(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckListLength returns ErrListTooLong if a list of n items is over
+// the limit.
+func (l Limits) CheckListLength(n int) error {
+	if l.MaxListLength > 0 && n > l.MaxListLength {
+		return fmt.Errorf("%w: %d items, the limit is %d", ErrListTooLong, n, l.MaxListLength)
+	}
+	return nil
+}
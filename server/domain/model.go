@@ -11,14 +11,40 @@ const (
 	TypeList
 )
 
+// String returns the lowercase type name, e.g. "string".
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	}
+	return "unknown"
+}
+
 // Entry holds data and an expiry timestamp.
 type Entry struct {
 	Type   ValueType
 	Str    string
 	Items  []string
 	Expiry time.Time
+
+	Flags uint32 // opaque client flags (memcached)
+	CAS   uint64 // assigned by the repository on every write
+
+	// ContentType is the media type of a string written as a blob;
+	// empty for plain strings.
+	ContentType string
+
+	// Encoding is EncodingGzip when Str, and every item, that starts with
+	// the gzip header is compressed. Only the repository and readers of
+	// encoded entries see it set; Get returns decoded entries.
+	Encoding string
 }
 
+// DefaultContentType is assumed for blobs written without a media type.
+const DefaultContentType = "application/octet-stream"
+
 // NewStringEntry creates a string entry that expires after ttl.
 func NewStringEntry(str string, expiry time.Duration) *Entry {
 
@@ -39,3 +65,22 @@ func NewListEntry(items []string, expiry time.Duration) *Entry {
 		Expiry: time.Now().Add(expiry),
 	}
 }
+
+// SetMode selects the precondition of a conditional string write.
+type SetMode int
+
+const (
+	SetAlways    SetMode = iota // set: unconditional
+	SetIfAbsent                 // add: only if the key does not exist
+	SetIfPresent                // replace: only if the key exists
+	SetIfCAS                    // cas: only if the entry's CAS matches
+)
+
+// StringWrite describes a conditional string write with metadata.
+type StringWrite struct {
+	Value  string
+	Flags  uint32
+	Expiry time.Time // absolute; zero never expires
+	Mode   SetMode
+	CAS    uint64 // expected CAS when Mode is SetIfCAS
+}
@@ -0,0 +1,23 @@
+package domain
+
+import "time"
+
+// Operation names a mutating service call.
+type Operation string
+
+const (
+	OpSet    Operation = "set"
+	OpDel    Operation = "del"
+	OpLPush  Operation = "lpush"
+	OpRPop   Operation = "rpop"
+	OpExpire Operation = "expire"
+	OpFlush  Operation = "flush" // Key is empty; only Namespace was flushed
+)
+
+// Mutation describes a successful write performed by the service layer.
+type Mutation struct {
+	Op        Operation `json:"event"`
+	Namespace string    `json:"namespace"`
+	Key       string    `json:"key"`
+	Time      time.Time `json:"time"`
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"context"
+	"errors"
+)
+
+// DefaultNamespace holds the keys of requests that select no namespace.
+const DefaultNamespace = "default"
+
+// MaxNamespaceLen bounds namespace names.
+const MaxNamespaceLen = 64
+
+var ErrInvalidNamespace = errors.New("invalid namespace")
+
+// ValidNamespace reports whether name is a usable namespace name: 1 to
+// MaxNamespaceLen letters, digits, '-', '_' or '.', starting with a
+// letter or digit.
+func ValidNamespace(name string) bool {
+	if name == "" || len(name) > MaxNamespaceLen {
+		return false
+	}
+	for i, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case i > 0 && (c == '-' || c == '_' || c == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type namespaceKey struct{}
+
+// WithNamespace returns a copy of ctx whose keys live in namespace ns.
+func WithNamespace(ctx context.Context, ns string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, ns)
+}
+
+// NamespaceFrom returns the namespace stored by WithNamespace, or
+// DefaultNamespace.
+func NamespaceFrom(ctx context.Context) string {
+	if ns, ok := ctx.Value(namespaceKey{}).(string); ok && ns != "" {
+		return ns
+	}
+	return DefaultNamespace
+}
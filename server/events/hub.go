@@ -0,0 +1,169 @@
+package events
+
+import (
+	"context"
+	"data_storage/server/domain"
+	"sync"
+	"time"
+)
+
+// DefaultHistory is how many recent events a Hub keeps for resuming watchers.
+const DefaultHistory = 1024
+
+// subscriberBuffer is the per-watcher channel capacity on top of any replay.
+const subscriberBuffer = 256
+
+type subscriber struct {
+	namespace string
+	pattern   glob
+	ch        chan domain.Event
+	closed    bool // guarded by Hub.mu
+}
+
+// Hub fans keyspace events out to watchers and keeps a bounded
+// history so that reconnecting watchers can resume by event ID.
+//
+// Patterns are matched outside mu, which only guards the bookkeeping;
+// order serialises Publish and Subscribe so that every watcher still
+// sees events in ID order, with no gap between replay and live events.
+type Hub struct {
+	order   sync.Mutex
+	mu      sync.Mutex
+	nextID  uint64
+	history []domain.Event
+	head    int // index of the oldest event once history is full
+	size    int
+	subs    map[*subscriber]struct{}
+}
+
+// NewHub creates a Hub that remembers the last history events.
+func NewHub(history int) *Hub {
+	if history <= 0 {
+		history = DefaultHistory
+	}
+	return &Hub{
+		history: make([]domain.Event, history),
+		subs:    make(map[*subscriber]struct{}),
+	}
+}
+
+// Publish records an event for key in namespace ns and delivers it to
+// every matching watcher of ns. It never blocks: a watcher whose buffer
+// is full is disconnected and is expected to reconnect with its last
+// seen event ID.
+func (h *Hub) Publish(typ domain.EventType, ns, key string) domain.Event {
+	h.order.Lock()
+	defer h.order.Unlock()
+
+	h.mu.Lock()
+	h.nextID++
+	ev := domain.Event{ID: h.nextID, Type: typ, Namespace: ns, Key: key, Time: time.Now()}
+
+	idx := (h.head + h.size) % len(h.history)
+	h.history[idx] = ev
+	if h.size < len(h.history) {
+		h.size++
+	} else {
+		h.head = (h.head + 1) % len(h.history)
+	}
+	subs := make([]*subscriber, 0, len(h.subs))
+	for sub := range h.subs {
+		if sub.namespace == ns {
+			subs = append(subs, sub)
+		}
+	}
+	h.mu.Unlock()
+
+	matched := subs[:0]
+	for _, sub := range subs {
+		if sub.pattern.match(key) {
+			matched = append(matched, sub)
+		}
+	}
+	if len(matched) == 0 {
+		return ev
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, sub := range matched {
+		if sub.closed {
+			continue
+		}
+		select {
+		case sub.ch <- ev:
+		default:
+			h.drop(sub)
+		}
+	}
+	return ev
+}
+
+// Subscribe returns a channel of events in namespace ns whose key
+// matches pattern.
+// Buffered events with an ID greater than afterID are replayed first;
+// pass 0 to receive only new events. The channel is closed when ctx
+// is done or the watcher is dropped for falling behind.
+func (h *Hub) Subscribe(ctx context.Context, ns, pattern string, afterID uint64) (<-chan domain.Event, error) {
+	g, ok := compile(pattern)
+	if !ok {
+		return nil, domain.ErrInvalidPattern
+	}
+
+	h.order.Lock()
+	var replay []domain.Event
+	if afterID > 0 {
+		h.mu.Lock()
+		for i := 0; i < h.size; i++ {
+			ev := h.history[(h.head+i)%len(h.history)]
+			if ev.ID > afterID && ev.Namespace == ns {
+				replay = append(replay, ev)
+			}
+		}
+		h.mu.Unlock()
+	}
+	matched := replay[:0]
+	for _, ev := range replay {
+		if g.match(ev.Key) {
+			matched = append(matched, ev)
+		}
+	}
+	sub := &subscriber{
+		namespace: ns,
+		pattern:   g,
+		ch:        make(chan domain.Event, len(matched)+subscriberBuffer),
+	}
+	for _, ev := range matched {
+		sub.ch <- ev
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	h.order.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		h.drop(sub)
+		h.mu.Unlock()
+	}()
+
+	return sub.ch, nil
+}
+
+// Subscribers reports the number of connected watchers.
+func (h *Hub) Subscribers() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subs)
+}
+
+// drop unregisters and closes a subscriber. Callers must hold h.mu.
+func (h *Hub) drop(sub *subscriber) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(h.subs, sub)
+	close(sub.ch)
+}
@@ -0,0 +1,174 @@
+package events
+
+// Limits on watch and webhook patterns, which are matched against
+// every written key. The length leaves room for a default-length key
+// with every byte escaped.
+const (
+	MaxPatternLength = 2048
+	MaxPatternStars  = 16
+)
+
+// Match reports whether key matches the Redis-style glob pattern.
+// Supported syntax: '*' (any run of characters, including none),
+// '?' (any single character), '[abc]' / '[a-z]' / '[^a]' classes and
+// '\' to escape the next character. An empty pattern matches every key.
+// Malformed patterns match nothing.
+func Match(pattern, key string) bool {
+	g, ok := compile(pattern)
+	return ok && g.match(key)
+}
+
+// ValidPattern reports whether pattern is well formed and within
+// MaxPatternLength and MaxPatternStars.
+func ValidPattern(pattern string) bool {
+	_, ok := compile(pattern)
+	return ok
+}
+
+// Escape quotes every glob metacharacter so that key matches only itself.
+func Escape(key string) string {
+	out := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		switch key[i] {
+		case '*', '?', '[', ']', '\\':
+			out = append(out, '\\')
+		}
+		out = append(out, key[i])
+	}
+	return string(out)
+}
+
+// token is one element of a compiled pattern: '*', '?', '[' for a
+// class, or 0 for the literal lit.
+type token struct {
+	kind   byte
+	lit    byte
+	class  string // class body, without the brackets
+	negate bool
+}
+
+// matches reports whether the single-character token t matches c.
+func (t token) matches(c byte) bool {
+	switch t.kind {
+	case '?':
+		return true
+	case '[':
+		return inClass(t.class, c) != t.negate
+	}
+	return t.lit == c
+}
+
+// glob is a compiled pattern.
+type glob []token
+
+// compile parses pattern, folding runs of '*'; ok is false for
+// malformed patterns and those over the limits. The empty pattern
+// compiles to a lone '*'.
+func compile(pattern string) (g glob, ok bool) {
+	if len(pattern) > MaxPatternLength {
+		return nil, false
+	}
+	if pattern == "" {
+		return glob{{kind: '*'}}, true
+	}
+	stars := 0
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '*':
+			if len(g) == 0 || g[len(g)-1].kind != '*' {
+				if stars++; stars > MaxPatternStars {
+					return nil, false
+				}
+				g = append(g, token{kind: '*'})
+			}
+		case '?':
+			g = append(g, token{kind: '?'})
+		case '[':
+			end, negate, ok := classEnd(pattern[i:])
+			if !ok {
+				return nil, false
+			}
+			g = append(g, token{kind: '[', class: pattern[i+1 : i+end], negate: negate})
+			i += end
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, false
+			}
+			i++
+			g = append(g, token{lit: pattern[i]})
+		default:
+			g = append(g, token{lit: pattern[i]})
+		}
+	}
+	return g, true
+}
+
+// match reports whether g matches all of key. Every token but '*'
+// consumes one byte, so on a mismatch it is enough to retry from the
+// last '*' with one more byte swallowed by it. The work is bounded by
+// len(g)*len(key) however many stars there are, rather than growing
+// exponentially with them.
+func (g glob) match(key string) bool {
+	p, k := 0, 0
+	star, starK := -1, 0 // last '*' seen and where its run ends in key
+	for k < len(key) {
+		switch {
+		case p < len(g) && g[p].kind == '*':
+			star, starK = p, k
+			p++
+		case p < len(g) && g[p].matches(key[k]):
+			p++
+			k++
+		case star >= 0:
+			starK++
+			p, k = star+1, starK
+		default:
+			return false
+		}
+	}
+	for p < len(g) && g[p].kind == '*' {
+		p++
+	}
+	return p == len(g)
+}
+
+// classEnd finds the closing ']' of a character class starting at pattern[0].
+func classEnd(pattern string) (end int, negate bool, ok bool) {
+	i := 1
+	if i < len(pattern) && pattern[i] == '^' {
+		negate = true
+		i++
+	}
+	for ; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case ']':
+			return i, negate, true
+		}
+	}
+	return 0, false, false
+}
+
+// inClass reports whether c is in the class body (without brackets).
+func inClass(class string, c byte) bool {
+	if len(class) > 0 && class[0] == '^' {
+		class = class[1:]
+	}
+	for i := 0; i < len(class); i++ {
+		lo := class[i]
+		if lo == '\\' && i+1 < len(class) {
+			i++
+			lo = class[i]
+		}
+		hi := lo
+		if i+2 < len(class) && class[i+1] == '-' {
+			hi = class[i+2]
+			i += 2
+		}
+		if lo <= c && c <= hi {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters/grpcapi"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_GRPC(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpcapi.NewServer(svc, auth.NewStaticRegistry("my-secret-token"))
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Wrong token is rejected with the same status as over HTTP
+	bad, _ := client.NewGRPCClient(ln.Addr().String(), "wrong-token")
+	defer bad.Close()
+	var he *client.HTTPError
+	if err := bad.SetString(ctx, "foo", "bar", 0); !errors.As(err, &he) || he.Code != 401 {
+		t.Fatalf("expected 401 HTTPError, got %v", err)
+	}
+	if _, err := bad.Watch(ctx, ""); !errors.As(err, &he) || he.Code != 401 {
+		t.Fatalf("expected 401 HTTPError from Watch, got %v", err)
+	}
+
+	var cli client.StoreClient
+	grpcCli, err := client.NewGRPCClient(ln.Addr().String(), "my-secret-token")
+	if err != nil {
+		t.Fatalf("client setup: %v", err)
+	}
+	defer grpcCli.Close()
+	cli = grpcCli
+
+	events, err := cli.Watch(ctx, "foo")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	if err := cli.SetString(ctx, "foo", "bar", time.Second); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	got, err := cli.GetString(ctx, "foo")
+	if err != nil || got != "bar" {
+		t.Fatalf("GetString = %q, %v", got, err)
+	}
+	if err := cli.DeleteString(ctx, "foo"); err != nil {
+		t.Fatalf("DeleteString failed: %v", err)
+	}
+	if _, err := cli.GetString(ctx, "foo"); !errors.As(err, &he) || he.Code != 400 {
+		t.Errorf("expected 400 HTTPError for missing key, got %v", err)
+	}
+
+	if err := cli.LPush(ctx, "mylist", "a", "b"); err != nil {
+		t.Fatalf("LPush failed: %v", err)
+	}
+	if val, err := cli.RPop(ctx, "mylist"); err != nil || val != "b" {
+		t.Errorf("RPop = %q, %v; want b", val, err)
+	}
+
+	for _, want := range []string{"set", "del"} {
+		select {
+		case ev := <-events:
+			if ev.Type != want || ev.Key != "foo" {
+				t.Errorf("got event %s %s, want %s foo", ev.Type, ev.Key, want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for %s event", want)
+		}
+	}
+
+	// x-namespace metadata selects a namespace, which events carry
+	tenant, err := client.NewGRPCClient(ln.Addr().String(), "my-secret-token", client.GRPCNamespace("tenant"))
+	if err != nil {
+		t.Fatalf("client setup: %v", err)
+	}
+	defer tenant.Close()
+	tenantEvents, err := tenant.Watch(ctx, "*")
+	if err != nil {
+		t.Fatalf("Watch in a namespace: %v", err)
+	}
+	if err := tenant.SetString(ctx, "foo", "scoped", time.Minute); err != nil {
+		t.Fatalf("SetString in a namespace: %v", err)
+	}
+	if _, err := cli.GetString(ctx, "foo"); !errors.As(err, &he) || he.Code != 400 {
+		t.Errorf("expected the namespaced key to stay out of the default namespace, got %v", err)
+	}
+	select {
+	case ev := <-tenantEvents:
+		if ev.Type != "set" || ev.Namespace != "tenant" || ev.Key != "foo" {
+			t.Errorf("got event %+v, want set tenant/foo", ev)
+		}
+	case <-ctx.Done():
+		t.Fatal("timed out waiting for the namespaced event")
+	}
+}
+
+func TestIntegration_GRPCCountersAndKeys(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "writer", Token: "writer-token", Prefixes: []string{"*"}, Classes: []auth.OpClass{auth.OpRead, auth.OpWrite}})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpcapi.NewServer(svc, tokens)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	admin, _ := client.NewGRPCClient(ln.Addr().String(), "my-secret-token")
+	defer admin.Close()
+	writer, _ := client.NewGRPCClient(ln.Addr().String(), "writer-token")
+	defer writer.Close()
+	var he *client.HTTPError
+
+	// 1) Counters
+	if _, err := writer.Incr(ctx, "hits", 1); !errors.As(err, &he) || he.Code != 400 {
+		t.Fatalf("expected 400 for a missing counter, got %v", err)
+	}
+	writer.SetString(ctx, "hits", "10", time.Minute)
+	if n, err := writer.Incr(ctx, "hits", 5); err != nil || n != 15 {
+		t.Fatalf("Incr = %d, %v; want 15", n, err)
+	}
+	if n, err := writer.Decr(ctx, "hits", 20); err != nil || n != 0 {
+		t.Fatalf("Decr = %d, %v; want 0", n, err)
+	}
+	writer.SetString(ctx, "name", "alice", time.Minute)
+	if _, err := writer.Incr(ctx, "name", 1); !errors.As(err, &he) || he.Code != 400 {
+		t.Fatalf("expected 400 for a non-numeric value, got %v", err)
+	}
+
+	// 2) Touch replaces the TTL, or drops it
+	if err := writer.Touch(ctx, "hits", time.Hour); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if left, _ := svc.TTL(ctx, "hits"); left < 59*time.Minute {
+		t.Fatalf("expected about an hour left, got %v", left)
+	}
+	if err := writer.Touch(ctx, "hits", 0); err != nil {
+		t.Fatalf("Touch: %v", err)
+	}
+	if left, _ := svc.TTL(ctx, "hits"); left != -1 {
+		t.Fatalf("expected no expiry, got %v", left)
+	}
+
+	// 3) FlushAll needs the admin class
+	if err := writer.FlushAll(ctx); !errors.As(err, &he) || he.Code != 403 {
+		t.Fatalf("expected 403 for a writer, got %v", err)
+	}
+	if err := admin.FlushAll(ctx); err != nil {
+		t.Fatalf("FlushAll: %v", err)
+	}
+	if _, err := admin.GetString(ctx, "name"); !errors.As(err, &he) || he.Code != 400 {
+		t.Fatalf("expected the flushed key to be gone, got %v", err)
+	}
+}
+
+func TestIntegration_GRPCBlobs(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := grpcapi.NewServer(svc, auth.NewStaticRegistry("my-secret-token"))
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	grpcCli, _ := client.NewGRPCClient(ln.Addr().String(), "my-secret-token")
+	defer grpcCli.Close()
+	var blobs client.BlobClient = grpcCli
+	var he *client.HTTPError
+
+	// bytes that are not valid UTF-8 survive the round trip
+	data := []byte{0x89, 'P', 'N', 'G', 0xff, 0x00, 0xfe, 0x10}
+	if err := blobs.SetBlob(ctx, "logo", data, "image/png", time.Minute); err != nil {
+		t.Fatalf("SetBlob: %v", err)
+	}
+	blob, err := blobs.GetBlob(ctx, "logo")
+	if err != nil || !bytes.Equal(blob.Data, data) || blob.ContentType != "image/png" || blob.ETag == "" {
+		t.Fatalf("GetBlob = %+v, %v", blob, err)
+	}
+	if part, err := blobs.GetBlobRange(ctx, "logo", 4, 2); err != nil || !bytes.Equal(part.Data, data[4:6]) {
+		t.Fatalf("GetBlobRange = %+v, %v", part, err)
+	}
+	if tail, err := blobs.GetBlobRange(ctx, "logo", 6, -1); err != nil || !bytes.Equal(tail.Data, data[6:]) {
+		t.Fatalf("GetBlobRange to the end = %+v, %v", tail, err)
+	}
+	if _, err := blobs.GetBlobRange(ctx, "logo", 100, 1); !errors.As(err, &he) || he.Code != 416 {
+		t.Fatalf("expected 416 past the end, got %v", err)
+	}
+	if err := blobs.SetBlob(ctx, "bad", data, "not a type", time.Minute); !errors.As(err, &he) || he.Code != 400 {
+		t.Fatalf("expected 400 for a bad media type, got %v", err)
+	}
+	if err := blobs.DeleteBlob(ctx, "logo"); err != nil {
+		t.Fatalf("DeleteBlob: %v", err)
+	}
+	if _, err := blobs.GetBlob(ctx, "logo"); !errors.As(err, &he) || he.Code != 400 {
+		t.Fatalf("expected the deleted blob to be gone, got %v", err)
+	}
+}
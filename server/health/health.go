@@ -0,0 +1,77 @@
+// Package health tracks whether the server is ready to take traffic.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// Readiness collects reasons the server is not ready, keyed by the
+// component that reported them, e.g. a snapshot being loaded or a
+// replica catching up. The zero value is not usable; use NewReadiness.
+type Readiness struct {
+	mu      sync.Mutex
+	pending map[string]string
+}
+
+// NewReadiness returns a Readiness with nothing pending, i.e. ready.
+func NewReadiness() *Readiness {
+	return &Readiness{pending: make(map[string]string)}
+}
+
+// NotReady marks component as not ready for reason until Ready is called.
+func (r *Readiness) NotReady(component, reason string) {
+	r.mu.Lock()
+	r.pending[component] = reason
+	r.mu.Unlock()
+}
+
+// Ready clears component's reason.
+func (r *Readiness) Ready(component string) {
+	r.mu.Lock()
+	delete(r.pending, component)
+	r.mu.Unlock()
+}
+
+// Check reports whether every component is ready, and the reasons of
+// those that are not.
+func (r *Readiness) Check() (bool, map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reasons := make(map[string]string, len(r.pending))
+	for c, reason := range r.pending {
+		reasons[c] = reason
+	}
+	return len(reasons) == 0, reasons
+}
+
+// LivenessHandler serves /healthz: 200 while the process can serve HTTP.
+func LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		writeStatus(w, http.StatusOK, map[string]interface{}{"status": "ok"})
+	})
+}
+
+// ReadinessHandler serves /readyz: 200 when r is ready, otherwise 503
+// with the pending reasons.
+func ReadinessHandler(r *Readiness) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		ok, reasons := r.Check()
+		if ok {
+			writeStatus(w, http.StatusOK, map[string]interface{}{"status": "ready"})
+			return
+		}
+		writeStatus(w, http.StatusServiceUnavailable, map[string]interface{}{
+			"status":  "not ready",
+			"reasons": reasons,
+		})
+	})
+}
+
+func writeStatus(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
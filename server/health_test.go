@@ -0,0 +1,206 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/auth"
+	"data_storage/server/health"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_HealthAndInfo(t *testing.T) {
+	repo := storage.NewDataRepo(10 * time.Millisecond)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "reader", Token: "reader-token", Prefixes: []string{"*"}, Classes: []auth.OpClass{auth.OpRead}})
+	ready := health.NewReadiness()
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithReadiness(ready),
+		adapters.WithServerInfo(adapters.ServerInfo{
+			Version: "v1.2.3",
+			Started: time.Now().Add(-time.Minute),
+			Repo:    repo,
+			Config:  func() map[string]string { return map[string]string{"STORE_DEFAULT_TTL": "1m0s"} },
+		}),
+	))
+	defer ts.Close()
+
+	probe := func(path string) (int, map[string]interface{}) {
+		t.Helper()
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		var body map[string]interface{}
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body
+	}
+
+	// 1) Probes need no token
+	if status, _ := probe("/healthz"); status != http.StatusOK {
+		t.Fatalf("healthz: status %d", status)
+	}
+	if status, _ := probe("/readyz"); status != http.StatusOK {
+		t.Fatalf("readyz: status %d", status)
+	}
+
+	// 2) Readiness fails while a component is catching up
+	ready.NotReady("snapshot", "loading")
+	status, body := probe("/readyz")
+	if status != http.StatusServiceUnavailable || body["reasons"].(map[string]interface{})["snapshot"] != "loading" {
+		t.Fatalf("readyz while loading: %d %v", status, body)
+	}
+	ready.Ready("snapshot")
+	if status, _ := probe("/readyz"); status != http.StatusOK {
+		t.Fatalf("readyz after loading: status %d", status)
+	}
+
+	// 3) Info reports the keyspace, sweeps and subscribers to admins only
+	ctx := context.Background()
+	svc.SetString(ctx, "a", "1", time.Minute)
+	svc.LPush(ctx, "l", "x")
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if _, err := svc.Watch(watchCtx, "*", 0); err != nil {
+		t.Fatalf("Watch: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond) // a few sweeps
+
+	admin, _ := client.NewClient(ts.URL, "my-secret-token")
+	info, err := admin.(client.AdminClient).Info(ctx)
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.Server.Version != "v1.2.3" || info.Server.UptimeSeconds < 60 {
+		t.Fatalf("unexpected server section %+v", info.Server)
+	}
+	if info.Keyspace.Keys != 2 || info.Keyspace.ByType["string"] != 1 || info.Keyspace.ByType["list"] != 1 {
+		t.Fatalf("unexpected keyspace %+v", info.Keyspace)
+	}
+	if info.Memory.StoreBytes <= 0 || info.Expiry.Sweeps == 0 || info.Expiry.LastSweepAt == nil {
+		t.Fatalf("unexpected memory/expiry %+v %+v", info.Memory, info.Expiry)
+	}
+	if info.Subscribers.Watchers != 1 || info.Config["STORE_DEFAULT_TTL"] != "1m0s" {
+		t.Fatalf("unexpected subscribers/config %+v %v", info.Subscribers, info.Config)
+	}
+
+	reader, _ := client.NewClient(ts.URL, "reader-token")
+	var he *client.HTTPError
+	if _, err := reader.(client.AdminClient).Info(ctx); !errors.As(err, &he) || he.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a reader, got %v", err)
+	}
+}
+
+func TestIntegration_StartupGate(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ready := health.NewReadiness()
+	ready.NotReady("snapshot", "loading")
+	started := make(chan struct{})
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithReadiness(ready),
+		adapters.WithStartup(started),
+	))
+	defer ts.Close()
+	cli, _ := client.NewClient(ts.URL, "my-secret-token")
+	ctx := context.Background()
+
+	// 1) While loading, the probes answer and everything else gets 503
+	status, body := probeStatus(t, ts.URL+"/readyz")
+	if status != http.StatusServiceUnavailable || body["reasons"].(map[string]interface{})["snapshot"] != "loading" {
+		t.Fatalf("readyz while loading: %d %v", status, body)
+	}
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/string/a", nil)
+	req.Header.Set("Authorization", "Bearer my-secret-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET string: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After while starting, got %d %v", resp.StatusCode, resp.Header)
+	}
+	if status, _ := probeStatus(t, ts.URL+"/healthz"); status != http.StatusOK {
+		t.Fatalf("healthz while loading: status %d", status)
+	}
+
+	// 2) Once started the API serves and readiness follows its components
+	close(started)
+	ready.Ready("snapshot")
+	if err := cli.SetString(ctx, "a", "1", time.Minute); err != nil {
+		t.Fatalf("SetString after start: %v", err)
+	}
+	if status, _ := probeStatus(t, ts.URL+"/readyz"); status != http.StatusOK {
+		t.Fatalf("readyz after start: status %d", status)
+	}
+}
+
+func TestServer_ReadinessDuringStartup(t *testing.T) {
+	// an unreadable snapshot keeps the server not ready, and is not
+	// overwritten by the empty store on shutdown
+	snapshot := filepath.Join(t.TempDir(), "store.json")
+	os.WriteFile(snapshot, []byte("not a snapshot"), 0o600)
+	srv, err := New(testConfig(snapshot), "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if ok, reasons := srv.readiness.Check(); ok || reasons["startup"] != "starting" {
+		t.Fatalf("expected not ready before Start, got %v %v", ok, reasons)
+	}
+	if err := srv.Start(); err == nil {
+		t.Fatal("expected Start to fail on a bad snapshot")
+	}
+	if ok, reasons := srv.readiness.Check(); ok || !strings.Contains(reasons["snapshot"], "store.json") {
+		t.Fatalf("expected the snapshot error as the reason, got %v %v", ok, reasons)
+	}
+	srv.Shutdown(context.Background())
+	if data, _ := os.ReadFile(snapshot); string(data) != "not a snapshot" {
+		t.Fatalf("expected the snapshot to be left alone, got %q", data)
+	}
+
+	// a good one is loaded by Start, which then reports ready
+	os.Remove(snapshot)
+	srv, err = New(testConfig(snapshot), "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if ok, reasons := srv.readiness.Check(); !ok {
+		t.Fatalf("expected ready after Start, got %v", reasons)
+	}
+}
+
+// probeStatus GETs url without credentials and returns the status and
+// decoded JSON body.
+func probeStatus(t *testing.T, url string) (int, map[string]interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	var body map[string]interface{}
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp.StatusCode, body
+}
@@ -5,6 +5,8 @@ import (
 	"context"
 	"data_storage/server/store_service"
 	"net/http/httptest"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
@@ -71,3 +73,50 @@ func TestIntegration_StringAndList(t *testing.T) {
 		t.Error("expected error on empty list, got none")
 	}
 }
+
+func TestIntegration_ConcurrentListOps(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	svc := store_service.NewStoreService(repo, time.Minute)
+	ctx := context.Background()
+
+	const n = 50
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := svc.LPush(ctx, "l", strconv.Itoa(i)); err != nil {
+				t.Errorf("LPush: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got, _ := svc.LLen(ctx, "l"); got != n {
+		t.Fatalf("expected %d items after concurrent pushes, got %d", n, got)
+	}
+
+	popped := make(chan string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := svc.RPop(ctx, "l")
+			if err != nil {
+				t.Errorf("RPop: %v", err)
+			}
+			popped <- v
+		}()
+	}
+	wg.Wait()
+	close(popped)
+	seen := map[string]bool{}
+	for v := range popped {
+		if seen[v] {
+			t.Errorf("item %q popped twice", v)
+		}
+		seen[v] = true
+	}
+	if len(seen) != n {
+		t.Errorf("expected %d distinct items popped, got %d", n, len(seen))
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/server/adapters"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+var b64 = base64.RawURLEncoding
+
+// signJWT builds a compact JWS for claims with the given key.
+func signJWT(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	input := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		sig, _ = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+	case *ecdsa.PrivateKey:
+		r, s, _ := ecdsa.Sign(rand.Reader, k, digest[:])
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return input + "." + b64.EncodeToString(sig)
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+	data, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIntegration_JWTAuth(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	jwksPath := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, jwksPath,
+		map[string]string{"kty": "oct", "kid": "hs", "k": b64.EncodeToString(secret)},
+		map[string]string{"kty": "RSA", "kid": "rs", "n": b64.EncodeToString(rsaKey.N.Bytes()),
+			"e": b64.EncodeToString(big.NewInt(int64(rsaKey.E)).Bytes())},
+		map[string]string{"kty": "EC", "kid": "es", "crv": "P-256",
+			"x": b64.EncodeToString(ecKey.X.FillBytes(make([]byte, 32))),
+			"y": b64.EncodeToString(ecKey.Y.FillBytes(make([]byte, 32)))},
+	)
+	keys, err := auth.LoadJWKSFile(jwksPath)
+	if err != nil {
+		t.Fatalf("LoadJWKSFile: %v", err)
+	}
+	jwts := auth.NewJWTValidator(keys, "https://idp.example", "data-store", 0)
+
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithAuthenticator(auth.Chain(tokens, jwts)),
+	))
+	defer ts.Close()
+
+	status := func(token, method, path, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"sub":            "orders-service",
+			"iss":            "https://idp.example",
+			"aud":            []string{"other", "data-store"},
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"store_prefixes": []string{"orders:"},
+		}
+		for k, v := range overrides {
+			c[k] = v
+		}
+		return c
+	}
+
+	// 1) Static tokens still work alongside JWTs
+	if got := status("my-secret-token", http.MethodPost, "/v1/string/users:1", `{"value":"alice"}`); got != http.StatusOK {
+		t.Fatalf("static token: status %d", got)
+	}
+
+	// 2) Each algorithm is accepted and mapped onto the prefix claim
+	for alg, token := range map[string]string{
+		"HS256": signJWT(t, "HS256", "hs", secret, claims(nil)),
+		"RS256": signJWT(t, "RS256", "rs", rsaKey, claims(nil)),
+		"ES256": signJWT(t, "ES256", "es", ecKey, claims(nil)),
+	} {
+		if got := status(token, http.MethodPost, "/v1/string/orders:1", `{"value":"x"}`); got != http.StatusOK {
+			t.Fatalf("%s: status %d, want 200", alg, got)
+		}
+		if got := status(token, http.MethodGet, "/v1/string/users:1", ""); got != http.StatusForbidden {
+			t.Fatalf("%s outside prefix: status %d, want 403", alg, got)
+		}
+	}
+
+	// 3) Registered claims are enforced
+	rejected := map[string]string{
+		"expired":      signJWT(t, "HS256", "hs", secret, claims(map[string]interface{}{"exp": time.Now().Add(-time.Minute).Unix()})),
+		"not before":   signJWT(t, "HS256", "hs", secret, claims(map[string]interface{}{"nbf": time.Now().Add(time.Minute).Unix()})),
+		"issuer":       signJWT(t, "HS256", "hs", secret, claims(map[string]interface{}{"iss": "https://evil.example"})),
+		"audience":     signJWT(t, "HS256", "hs", secret, claims(map[string]interface{}{"aud": "other"})),
+		"wrong alg":    signJWT(t, "HS256", "rs", secret, claims(nil)),
+		"unknown kid":  signJWT(t, "HS256", "nope", secret, claims(nil)),
+		"tampered sig": signJWT(t, "HS256", "hs", []byte("not-the-secret"), claims(nil)),
+	}
+	for name, token := range rejected {
+		if got := status(token, http.MethodGet, "/v1/string/orders:1", ""); got != http.StatusUnauthorized {
+			t.Fatalf("%s: status %d, want 401", name, got)
+		}
+	}
+
+	// 4) Classes come from the store_classes claim
+	readOnly := signJWT(t, "HS256", "hs", secret, claims(map[string]interface{}{"store_classes": []string{"read"}}))
+	if got := status(readOnly, http.MethodDelete, "/v1/string/orders:1", ""); got != http.StatusForbidden {
+		t.Fatalf("read-only delete: status %d, want 403", got)
+	}
+
+	// 5) Rotating the JWKS file takes effect without a restart
+	rotated := []byte("fedcba9876543210fedcba9876543210")
+	writeJWKS(t, jwksPath, map[string]string{"kty": "oct", "kid": "hs2", "k": b64.EncodeToString(rotated)})
+	if got := status(signJWT(t, "HS256", "hs2", rotated, claims(nil)), http.MethodGet, "/v1/string/orders:1", ""); got != http.StatusOK {
+		t.Fatalf("rotated key: status %d, want 200", got)
+	}
+	if got := status(signJWT(t, "HS256", "hs", secret, claims(nil)), http.MethodGet, "/v1/string/orders:1", ""); got != http.StatusUnauthorized {
+		t.Fatalf("retired key: status %d, want 401", got)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+)
+
+func TestLimits_OversizeRequestsAreRejected(t *testing.T) {
+	cfg := testConfig("")
+	cfg.MaxBodyBytes = 256
+	cfg.MaxKeyLength = 8
+	cfg.MaxValueBytes = 16
+	cfg.MaxPushItems = 2
+	cfg.MaxListLength = 3
+	srv, err := New(cfg, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	base := "http://" + srv.Addr()
+	ctx := context.Background()
+
+	c, _ := client.NewClient(base, "my-secret-token")
+	tooLarge := func(err error, want string) bool {
+		var he *client.HTTPError
+		return errors.As(err, &he) && he.Code == http.StatusRequestEntityTooLarge && strings.Contains(he.Message, want)
+	}
+
+	if err := c.SetString(ctx, "much-too-long", "v", time.Hour); !tooLarge(err, "key is too long") {
+		t.Errorf("expected a long key to be refused, got %v", err)
+	}
+	if err := c.SetString(ctx, "k", strings.Repeat("x", 17), time.Hour); !tooLarge(err, "value is too large") {
+		t.Errorf("expected a large value to be refused, got %v", err)
+	}
+	if err := c.LPush(ctx, "l", "a", "b", "c"); !tooLarge(err, "too many items") {
+		t.Errorf("expected a large push to be refused, got %v", err)
+	}
+	if err := c.LPush(ctx, "l", "a", "b"); err != nil {
+		t.Fatalf("LPush: %v", err)
+	}
+	c.LPush(ctx, "l", "c")
+	if err := c.LPush(ctx, "l", "d"); !tooLarge(err, "list is too long") {
+		t.Errorf("expected the list to stop growing, got %v", err)
+	}
+
+	// bodies over the limit, with and without Content-Length
+	post := func(body io.Reader) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, base+"/v1/string/k", body)
+		req.Header.Set("Authorization", "Bearer my-secret-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+	big := `{"value":"` + strings.Repeat("x", 512) + `"}`
+	if code, body := post(strings.NewReader(big)); code != http.StatusRequestEntityTooLarge || !strings.Contains(body, "limit of 256") {
+		t.Errorf("expected 413 for a large body, got %d %s", code, body)
+	}
+	if code, body := post(io.MultiReader(strings.NewReader(big))); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413 for a large chunked body, got %d %s", code, body)
+	}
+
+	// the limits are published for clients to check against
+	info, err := c.(client.AdminClient).Info(ctx)
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if info.Limits.MaxBodyBytes != 256 || info.Limits.MaxKeyLength != 8 || info.Limits.MaxListLength != 3 {
+		t.Errorf("unexpected published limits %+v", info.Limits)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"data_storage/server/adapters"
+	"data_storage/server/logging"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+// syncBuffer is a bytes.Buffer safe for the server's goroutines.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestIntegration_StructuredLogging(t *testing.T) {
+	var out syncBuffer
+	logger, err := logging.New(&out, "json", slog.LevelInfo)
+	if err != nil {
+		t.Fatalf("logging.New: %v", err)
+	}
+	prev := slog.Default()
+	slog.SetDefault(logger)
+	defer slog.SetDefault(prev)
+
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token"))
+	defer ts.Close()
+
+	do := func(method, path, body, requestID string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer my-secret-token")
+		if requestID != "" {
+			req.Header.Set(logging.RequestIDHeader, requestID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		return resp
+	}
+
+	// 1) A caller's request ID is propagated and echoed
+	resp := do(http.MethodPost, "/v1/string/greeting", `{"value":"hello"}`, "trace-123")
+	resp.Body.Close()
+	if got := resp.Header.Get(logging.RequestIDHeader); got != "trace-123" {
+		t.Fatalf("echoed request ID = %q, want trace-123", got)
+	}
+
+	// 2) Otherwise one is generated and quoted in JSON errors
+	resp = do(http.MethodGet, "/v1/string/missing", "", "bad id with spaces")
+	var errBody struct {
+		Code      int    `json:"code"`
+		RequestID string `json:"request_id"`
+	}
+	json.NewDecoder(resp.Body).Decode(&errBody)
+	resp.Body.Close()
+	generated := resp.Header.Get(logging.RequestIDHeader)
+	if len(generated) != 32 || errBody.RequestID != generated {
+		t.Fatalf("expected a generated ID in header and body, got %q and %+v", generated, errBody)
+	}
+
+	// 3) Each request is logged once, with status, latency, bytes and identity
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var rec map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("log line is not JSON: %q", line)
+		}
+		if rec["msg"] == "request" {
+			records = append(records, rec)
+		}
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 request log lines, got %v", records)
+	}
+	set, get := records[0], records[1]
+	if set["request_id"] != "trace-123" || set["status"] != float64(200) || set["identity"] != "default" || set["method"] != "POST" {
+		t.Fatalf("unexpected set log line %v", set)
+	}
+	if _, ok := set["latency"]; !ok {
+		t.Fatalf("log line lacks latency: %v", set)
+	}
+	if get["request_id"] != generated || get["status"] != float64(errBody.Code) || get["bytes"].(float64) == 0 {
+		t.Fatalf("unexpected get log line %v", get)
+	}
+}
@@ -0,0 +1,226 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/server/adapters/memcache"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestMemcache_TextProtocol(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := memcache.NewServer(svc)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	send := func(cmd string, want ...string) []string {
+		t.Helper()
+		if _, err := io.WriteString(conn, cmd); err != nil {
+			t.Fatalf("write %q: %v", cmd, err)
+		}
+		var got []string
+		for i := range want {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("%q: reading reply: %v", cmd, err)
+			}
+			line = strings.TrimRight(line, "\r\n")
+			got = append(got, line)
+			if want[i] != "*" && line != want[i] {
+				t.Errorf("%q: reply line %d = %q, want %q", cmd, i, line, want[i])
+			}
+		}
+		return got
+	}
+
+	send("set foo 42 0 3\r\nbar\r\n", "STORED")
+	send("add foo 0 0 1\r\nx\r\n", "NOT_STORED")
+	send("replace missing 0 0 1\r\nx\r\n", "NOT_STORED")
+	send("get foo missing\r\n", "VALUE foo 42 3", "bar", "END")
+
+	gets := send("gets foo\r\n", "*", "bar", "END")
+	casID := strings.Fields(gets[0])[4]
+	send("cas foo 7 0 3 "+casID+"\r\nbaz\r\n", "STORED")
+	send("cas foo 7 0 3 "+casID+"\r\nqux\r\n", "EXISTS")
+	send("cas missing 0 0 1 1\r\nx\r\n", "NOT_FOUND")
+	send("get foo\r\n", "VALUE foo 7 3", "baz", "END")
+
+	send("set n 0 100 2\r\n10\r\n", "STORED")
+	send("incr n 5\r\n", "15")
+	send("decr n 100\r\n", "0")
+	send("incr foo 1\r\n", "CLIENT_ERROR cannot increment or decrement non-numeric value")
+	send("incr missing 1\r\n", "NOT_FOUND")
+
+	send("touch n 0\r\n", "TOUCHED")
+	send("touch missing 10\r\n", "NOT_FOUND")
+	send("delete foo\r\n", "DELETED")
+	send("delete foo\r\n", "NOT_FOUND")
+
+	// noreply commands are followed by one that does reply
+	send("set a 0 0 1 noreply\r\n1\r\nset b 0 -1 1\r\n2\r\n", "STORED")
+	send("get a b\r\n", "VALUE a 0 1", "1", "END")
+
+	send("flush_all\r\n", "OK")
+	send("get a n\r\n", "END")
+	send("bogus\r\n", "ERROR")
+}
+
+// memcacheConn dials addr and returns a function sending cmd and
+// checking the reply lines against want.
+func memcacheConn(t *testing.T, addr string) func(cmd string, want ...string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	return func(cmd string, want ...string) {
+		t.Helper()
+		if _, err := io.WriteString(conn, cmd); err != nil {
+			t.Fatalf("write %q: %v", cmd, err)
+		}
+		for i, w := range want {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("%q: reading reply: %v", cmd, err)
+			}
+			if got := strings.TrimRight(line, "\r\n"); got != w {
+				t.Errorf("%q: reply line %d = %q, want %q", cmd, i, got, w)
+			}
+		}
+	}
+}
+
+func TestMemcache_BoundIdentity(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	if _, err := tokens.Put(auth.Token{Name: "cache", Prefixes: []string{"cache:"},
+		Classes: []auth.OpClass{auth.OpRead, auth.OpWrite}, Namespaces: []string{"team"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := memcache.NewServer(svc, memcache.WithIdentity(tokens, "cache", ""))
+	go srv.Serve(ln)
+	defer srv.Close()
+	send := memcacheConn(t, ln.Addr().String())
+
+	// 1) Commands run in the token's namespace
+	send("set cache:1 0 0 3\r\nbar\r\n", "STORED")
+	ctx := domain.WithNamespace(context.Background(), "team")
+	if v, err := svc.GetString(ctx, "cache:1"); err != nil || v != "bar" {
+		t.Fatalf("expected the value in namespace team, got %q, %v", v, err)
+	}
+	if _, err := svc.GetString(context.Background(), "cache:1"); err == nil {
+		t.Fatal("expected nothing in the default namespace")
+	}
+
+	// 2) ... and under its ACL, leaving the connection usable
+	send("set other 0 0 1\r\nx\r\n", "CLIENT_ERROR permission denied")
+	send("get cache:1 other\r\n", "CLIENT_ERROR permission denied")
+	send("delete cache:1\r\n", "CLIENT_ERROR permission denied")
+	send("flush_all\r\n", "CLIENT_ERROR permission denied")
+	send("get cache:1\r\n", "VALUE cache:1 0 3", "bar", "END")
+
+	// 3) Revoking the token stops the listener at once
+	if err := tokens.Remove("cache"); err != nil {
+		t.Fatal(err)
+	}
+	send("get cache:1\r\n", `SERVER_ERROR memcached identity unavailable: "cache": token not found`)
+}
+
+func TestMemcache_CloseStopsDelayedFlush(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := memcache.NewServer(svc)
+	go srv.Serve(ln)
+	send := memcacheConn(t, ln.Addr().String())
+
+	send("set foo 0 0 3\r\nbar\r\n", "STORED")
+	send("flush_all 1\r\n", "OK")
+	srv.Close()
+
+	time.Sleep(1200 * time.Millisecond)
+	if v, err := svc.GetString(context.Background(), "foo"); err != nil || v != "bar" {
+		t.Fatalf("expected the flush to be cancelled by Close, got %q, %v", v, err)
+	}
+}
+
+func TestMemcache_InputBounds(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := memcache.NewServer(svc)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	// flush_all delays: huge ones are refused rather than overflowing,
+	// and past absolute times flush straight away
+	send := memcacheConn(t, ln.Addr().String())
+	send("set foo 0 0 3\r\nbar\r\n", "STORED")
+	send("flush_all 9223372036854775807\r\n", "CLIENT_ERROR flush delay too long")
+	send("flush_all "+strconv.FormatInt(time.Now().Add(60*24*time.Hour).Unix(), 10)+"\r\n", "CLIENT_ERROR flush delay too long")
+	send("get foo\r\n", "VALUE foo 0 3", "bar", "END")
+	send("flush_all 2592001\r\n", "OK")
+	send("get foo\r\n", "END")
+
+	// a line without a newline is cut off and the connection closed
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	go io.WriteString(conn, "get "+strings.Repeat("k", 128<<10))
+	r := bufio.NewReader(conn)
+	if line, err := r.ReadString('\n'); err != nil || line != "CLIENT_ERROR line too long\r\n" {
+		t.Fatalf("reply to a long line = %q, %v", line, err)
+	}
+	// EOF, or a reset as the rest of the line was never read
+	if _, err := r.ReadByte(); err == nil {
+		t.Fatalf("expected the connection to be closed, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"data_storage/server/adapters"
+	"data_storage/server/auth"
+	"data_storage/server/metrics"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_Metrics(t *testing.T) {
+	repo := storage.NewDataRepo(20 * time.Millisecond)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	reg := metrics.NewRegistry()
+	repo.RegisterMetrics(reg)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "reader", Token: "reader-token", Prefixes: []string{"*"}, Classes: []auth.OpClass{auth.OpRead}})
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithMetrics(reg, true),
+	))
+	defer ts.Close()
+
+	do := func(token, method, path, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	do("my-secret-token", http.MethodPost, "/v1/string/a", `{"value":"1"}`)
+	do("my-secret-token", http.MethodPost, "/v1/string/b", `{"value":"2"}`)
+	do("my-secret-token", http.MethodPost, "/v1/list/jobs/push", `{"items":["x","y"]}`)
+	do("my-secret-token", http.MethodGet, "/v1/string/a", "")
+	do("", http.MethodGet, "/v1/string/a", "")
+	do("reader-token", http.MethodDelete, "/v1/string/a", "")
+
+	// the streaming routes still work through the metrics recorder
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/ws?access_token=my-secret-token", nil)
+	if err != nil {
+		t.Fatalf("dial through metrics middleware: %v", err)
+	}
+	conn.Close()
+
+	// the invalidator removes "short" within a couple of ticks
+	if err := svc.SetString(context.Background(), "short", "x", time.Millisecond); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	// only admin tokens may scrape
+	if status, _ := do("reader-token", http.MethodGet, "/metrics", ""); status != http.StatusForbidden {
+		t.Fatalf("reader scrape: status %d, want 403", status)
+	}
+	status, body := do("my-secret-token", http.MethodGet, "/metrics", "")
+	if status != http.StatusOK {
+		t.Fatalf("scrape: status %d", status)
+	}
+
+	for _, want := range []string{
+		"# TYPE store_http_requests_total counter",
+		`store_http_requests_total{route="/v1/string/{key}",method="POST",status="200"} 2`,
+		`store_http_requests_total{route="/v1/list/{key}/push",method="POST",status="200"} 1`,
+		`store_http_requests_total{route="/v1/string/{key}",method="GET",status="401"} 1`,
+		"# TYPE store_http_request_duration_seconds histogram",
+		`store_http_request_duration_seconds_bucket{route="/v1/string/{key}",method="GET",status="200",le="+Inf"} 1`,
+		`store_http_request_duration_seconds_count{route="/v1/string/{key}",method="POST",status="200"} 2`,
+		`store_auth_failures_total{status="401"} 1`,
+		`store_auth_failures_total{status="403"} 2`, // the delete and the reader's scrape
+		`store_keys{type="string"} 2`,
+		`store_keys{type="list"} 1`,
+		"store_expired_keys_total 1",
+	} {
+		if !strings.Contains(body, want+"\n") {
+			t.Errorf("metrics output lacks %q:\n%s", want, body)
+		}
+	}
+	if !strings.Contains(body, "store_memory_bytes ") || strings.Contains(body, "store_memory_bytes 0\n") {
+		t.Errorf("expected a non-zero memory estimate:\n%s", body)
+	}
+}
@@ -0,0 +1,127 @@
+// Package monitor streams every executed store command to connected
+// admins, like Redis MONITOR. Publishing is a single atomic load while
+// nobody is watching, and never blocks on a slow watcher.
+package monitor
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// MaxArgLen is how much of each value is shown.
+	MaxArgLen = 64
+	// MaxArgs is how many values of one command are shown.
+	MaxArgs = 8
+	// subscriberBuffer is how many events a watcher may fall behind
+	// before events are dropped for it.
+	subscriberBuffer = 1024
+)
+
+// Event is one executed command.
+type Event struct {
+	Time       time.Time `json:"time"`
+	Command    string    `json:"command"`
+	Namespace  string    `json:"namespace"`
+	Key        string    `json:"key,omitempty"`
+	Args       []string  `json:"args,omitempty"` // truncated values
+	ArgCount   int       `json:"arg_count"`
+	Identity   string    `json:"identity,omitempty"`
+	DurationMS float64   `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// TruncateArgs returns at most MaxArgs values of at most MaxArgLen bytes
+// each, marking what was cut.
+func TruncateArgs(args []string) []string {
+	n := len(args)
+	if n > MaxArgs {
+		n = MaxArgs
+	}
+	out := make([]string, n)
+	for i := 0; i < n; i++ {
+		out[i] = truncate(args[i])
+	}
+	return out
+}
+
+func truncate(s string) string {
+	if len(s) <= MaxArgLen {
+		return s
+	}
+	return s[:MaxArgLen] + "...(" + strconv.Itoa(len(s)) + " bytes)"
+}
+
+// Hub fans events out to subscribers.
+type Hub struct {
+	active atomic.Int32
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+}
+
+// NewHub returns a Hub with no subscribers.
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Active reports whether anybody is subscribed; callers skip building
+// events otherwise.
+func (h *Hub) Active() bool {
+	return h.active.Load() > 0
+}
+
+// Subscribers returns the number of connected monitors.
+func (h *Hub) Subscribers() int {
+	return int(h.active.Load())
+}
+
+// Publish delivers ev to every subscriber that has room for it and
+// counts a drop for those that do not.
+func (h *Hub) Publish(ev Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for sub := range h.subs {
+		select {
+		case sub.ch <- ev:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Subscription receives events on C until its context ends, which
+// closes C.
+type Subscription struct {
+	C       <-chan Event
+	ch      chan Event
+	dropped atomic.Uint64
+}
+
+// Dropped returns how many events were dropped because C was full.
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Subscribe streams events until ctx ends.
+func (h *Hub) Subscribe(ctx context.Context) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch}
+
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.active.Add(1)
+	h.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		h.mu.Lock()
+		delete(h.subs, sub)
+		h.active.Add(-1)
+		close(sub.ch)
+		h.mu.Unlock()
+	}()
+	return sub
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/auth"
+	"data_storage/server/monitor"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_Monitor(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+
+	hub := monitor.NewHub()
+	svc := store_service.NewStoreService(repo, time.Minute, store_service.WithMonitor(hub))
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "writer", Token: "writer-token", Prefixes: []string{"*"}, Classes: []auth.OpClass{auth.OpRead, auth.OpWrite}})
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithMonitor(hub),
+	))
+	defer ts.Close()
+
+	// monitoring is admin-only
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/admin/monitor", nil)
+	req.Header.Set("Authorization", "Bearer writer-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET monitor: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-admin token, got %d", resp.StatusCode)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	admin, _ := client.NewClient(ts.URL, "my-secret-token")
+	events, err := admin.(client.AdminClient).Monitor(ctx)
+	if err != nil {
+		t.Fatalf("Monitor: %v", err)
+	}
+	if !hub.Active() {
+		t.Fatal("expected the hub to be active while a monitor is connected")
+	}
+
+	writer, _ := client.NewClient(ts.URL, "writer-token")
+	long := strings.Repeat("x", 1000)
+	writer.SetString(ctx, "greeting", long, time.Minute)
+	writer.GetString(ctx, "missing")
+
+	next := func() client.MonitorEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for a monitor event")
+		}
+		return client.MonitorEvent{}
+	}
+	set := next()
+	if set.Command != "SetString" || set.Key != "greeting" || set.Identity != "writer" || set.ArgCount != 1 {
+		t.Fatalf("unexpected set event %+v", set)
+	}
+	if len(set.Args) != 1 || len(set.Args[0]) >= len(long) || !strings.HasPrefix(set.Args[0], long[:monitor.MaxArgLen]) {
+		t.Fatalf("expected a truncated value, got %q", set.Args)
+	}
+	if get := next(); get.Command != "GetString" || get.Error == "" {
+		t.Fatalf("expected the failed GetString with its error, got %+v", get)
+	}
+
+	cancel()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Active() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hub.Active() {
+		t.Fatal("expected the hub to go idle after the monitor disconnected")
+	}
+}
+
+func TestMonitor_DropsForSlowSubscribers(t *testing.T) {
+	hub := monitor.NewHub()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := hub.Subscribe(ctx)
+
+	// nobody reads: publishing must neither block nor grow without bound
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5000; i++ {
+			hub.Publish(monitor.Event{Command: "SetString"})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Publish blocked on a slow subscriber")
+	}
+	if sub.Dropped() == 0 || int(sub.Dropped())+len(sub.C) != 5000 {
+		t.Fatalf("expected every event to be either buffered or counted as dropped, dropped=%d buffered=%d", sub.Dropped(), len(sub.C))
+	}
+}
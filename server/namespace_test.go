@@ -0,0 +1,210 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters/resp"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestNamespaces_IsolationAndAdmin(t *testing.T) {
+	acl := writeFile(t, "acl.json", `{"tokens":[
+		{"name":"team-a","token":"a-token","prefixes":["*"],"classes":["read","write","delete","admin"],"namespaces":["team-a"]},
+		{"name":"team-b","token":"b-token","prefixes":["*"],"classes":["read","write","delete"],"namespaces":["team-b"]}
+	]}`)
+	cfg := testConfig("")
+	cfg.ACLFile = acl
+	srv, err := New(cfg, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	base := "http://" + srv.Addr()
+	ctx := context.Background()
+
+	// each team writes the same key into its own namespace
+	teamA, _ := client.NewClient(base, "a-token")
+	teamB, _ := client.NewClient(base, "b-token")
+	if err := teamA.SetString(ctx, "shared", "from-a", time.Hour); err != nil {
+		t.Fatalf("team-a SetString: %v", err)
+	}
+	if err := teamB.SetString(ctx, "shared", "from-b", time.Hour); err != nil {
+		t.Fatalf("team-b SetString: %v", err)
+	}
+	if got, _ := teamA.GetString(ctx, "shared"); got != "from-a" {
+		t.Errorf("team-a read %q", got)
+	}
+	if got, _ := teamB.GetString(ctx, "shared"); got != "from-b" {
+		t.Errorf("team-b read %q", got)
+	}
+
+	do := func(token, method, path, ns, body string) (int, string) {
+		t.Helper()
+		req, _ := http.NewRequest(method, base+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ns != "" {
+			req.Header.Set("X-Namespace", ns)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		defer resp.Body.Close()
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(data)
+	}
+
+	// the default token may use every namespace, by path or header
+	if code, body := do("my-secret-token", "GET", "/v1/ns/team-a/string/shared", "", ""); code != http.StatusOK || !strings.Contains(body, "from-a") {
+		t.Errorf("expected team-a's value by path, got %d %s", code, body)
+	}
+	if code, body := do("my-secret-token", "GET", "/v1/string/shared", "team-b", ""); code != http.StatusOK || !strings.Contains(body, "from-b") {
+		t.Errorf("expected team-b's value by header, got %d %s", code, body)
+	}
+	if code, _ := do("my-secret-token", "GET", "/v1/string/shared", "", ""); code == http.StatusOK {
+		t.Errorf("expected nothing in the default namespace, got %d", code)
+	}
+
+	// tokens are bound to their namespace
+	if code, _ := do("a-token", "GET", "/v1/ns/team-b/string/shared", "", ""); code != http.StatusForbidden {
+		t.Errorf("expected team-a to be kept out of team-b by path, got %d", code)
+	}
+	if code, _ := do("a-token", "GET", "/v1/string/shared", "team-b", ""); code != http.StatusForbidden {
+		t.Errorf("expected team-a to be kept out of team-b by header, got %d", code)
+	}
+	if code, _ := do("my-secret-token", "GET", "/v1/ns/bad!name/string/shared", "", ""); code != http.StatusBadRequest {
+		t.Errorf("expected an invalid namespace to be rejected, got %d", code)
+	}
+
+	// key counts, filtered to the namespaces a token may use
+	var all []struct {
+		Name string `json:"name"`
+		Keys int    `json:"keys"`
+	}
+	_, body := do("my-secret-token", "GET", "/v1/admin/namespaces", "", "")
+	json.Unmarshal([]byte(body), &all)
+	if len(all) != 2 || all[0].Name != "team-a" || all[0].Keys != 1 || all[1].Name != "team-b" {
+		t.Errorf("unexpected namespaces %s", body)
+	}
+	_, body = do("a-token", "GET", "/v1/admin/namespaces", "", "")
+	if !strings.Contains(body, "team-a") || strings.Contains(body, "team-b") {
+		t.Errorf("expected team-a to only see its namespace, got %s", body)
+	}
+	if code, _ := do("a-token", "POST", "/v1/admin/namespaces/team-b/flush", "", ""); code != http.StatusForbidden {
+		t.Errorf("expected team-a not to flush team-b, got %d", code)
+	}
+
+	// flushing one namespace leaves the others alone
+	if code, _ := do("my-secret-token", "POST", "/v1/admin/namespaces/team-b/flush", "", ""); code != http.StatusNoContent {
+		t.Fatalf("flush team-b: %d", code)
+	}
+	if _, err := teamB.GetString(ctx, "shared"); err == nil {
+		t.Error("expected team-b to be empty after the flush")
+	}
+	if got, _ := teamA.GetString(ctx, "shared"); got != "from-a" {
+		t.Errorf("expected team-a to keep its key, got %q", got)
+	}
+
+	// a default TTL override applies to writes without a TTL
+	if code, body := do("a-token", "PUT", "/v1/admin/namespaces/team-a", "", `{"default_ttl_seconds":1}`); code != http.StatusOK || !strings.Contains(body, `"default_ttl_seconds":1`) {
+		t.Fatalf("set team-a TTL: %d %s", code, body)
+	}
+	if err := teamA.SetString(ctx, "short", "v", 0); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+	if err := teamB.SetString(ctx, "long", "v", 0); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := teamA.GetString(ctx, "short"); err == nil {
+		t.Error("expected team-a's key to expire after its namespace's default TTL")
+	}
+	if _, err := teamB.GetString(ctx, "long"); err != nil {
+		t.Errorf("expected team-b to keep the server default TTL, got %v", err)
+	}
+}
+
+func TestNamespaces_RESPSelect(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	if _, err := tokens.Put(auth.Token{Name: "team-a", Token: "a-token", Prefixes: []string{"*"}, Classes: auth.AllClasses, Namespaces: []string{"team-a", "shared"}}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := resp.NewServer(svc, tokens)
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+
+	send := func(args ...string) string {
+		t.Helper()
+		var b strings.Builder
+		b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+		for _, a := range args {
+			b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+		}
+		io.WriteString(conn, b.String())
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, "$") && line != "$-1" {
+			value, _ := r.ReadString('\n')
+			return strings.TrimRight(value, "\r\n")
+		}
+		return line
+	}
+
+	// bound to two namespaces, the token starts in the first one
+	send("AUTH", "a-token")
+	send("SET", "k", "in-team-a")
+	if got := send("SELECT", "other"); !strings.HasPrefix(got, "-NOPERM") {
+		t.Errorf("expected SELECT of a foreign namespace to fail, got %q", got)
+	}
+	if got := send("SELECT", "shared"); got != "+OK" {
+		t.Fatalf("SELECT shared: %q", got)
+	}
+	send("SET", "k", "in-shared")
+	if got := send("SELECT", "team-a"); got != "+OK" {
+		t.Fatalf("SELECT team-a: %q", got)
+	}
+	if got := send("GET", "k"); got != "in-team-a" {
+		t.Errorf("expected team-a's k, got %q", got)
+	}
+	if got := send("SELECT", "shared"); got != "+OK" {
+		t.Fatalf("SELECT shared: %q", got)
+	}
+	if got := send("GET", "k"); got != "in-shared" {
+		t.Errorf("expected k in shared, got %q", got)
+	}
+}
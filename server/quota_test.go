@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+)
+
+func TestQuotas_DefaultAndNamespaceOverride(t *testing.T) {
+	cfg := testConfig("")
+	cfg.QuotaMaxKeys = 2
+	srv, err := New(cfg, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+	ctx := context.Background()
+
+	c, _ := client.NewClient("http://"+srv.Addr(), "my-secret-token")
+	quotaExceeded := func(err error) bool {
+		var he *client.HTTPError
+		return errors.As(err, &he) && he.Code == http.StatusInsufficientStorage
+	}
+
+	// the server-wide default applies to every namespace
+	for _, key := range []string{"a", "b"} {
+		if err := c.SetString(ctx, key, "v", time.Hour); err != nil {
+			t.Fatalf("SetString %s: %v", key, err)
+		}
+	}
+	if err := c.SetString(ctx, "c", "v", time.Hour); !quotaExceeded(err) {
+		t.Fatalf("expected the third key to be refused with 507, got %v", err)
+	}
+	if err := c.SetString(ctx, "a", "overwritten", time.Hour); err != nil {
+		t.Errorf("expected an existing key to stay writable, got %v", err)
+	}
+
+	// a namespace raises its key limit and caps list length
+	ns, err := c.(client.AdminClient).SetNamespaceQuota(ctx, "default", client.Quota{MaxKeys: 10, MaxListLength: 3})
+	if err != nil {
+		t.Fatalf("SetNamespaceQuota: %v", err)
+	}
+	if ns.Keys != 2 || ns.Quota != (client.Quota{MaxKeys: 10, MaxListLength: 3}) {
+		t.Errorf("unexpected usage and quota %+v", ns)
+	}
+	if err := c.LPush(ctx, "list", "1", "2", "3"); err != nil {
+		t.Fatalf("LPush: %v", err)
+	}
+	if err := c.LPush(ctx, "list", "4"); !quotaExceeded(err) {
+		t.Errorf("expected the fourth item to be refused, got %v", err)
+	}
+	if _, err := c.RPop(ctx, "list"); err != nil {
+		t.Fatalf("RPop: %v", err)
+	}
+	if err := c.LPush(ctx, "list", "4"); err != nil {
+		t.Errorf("expected room after a pop, got %v", err)
+	}
+
+	// other namespaces keep the default
+	other, _ := client.NewClient("http://"+srv.Addr(), "my-secret-token", client.WithNamespace("other"))
+	other.SetString(ctx, "x", "v", time.Hour)
+	other.SetString(ctx, "y", "v", time.Hour)
+	if err := other.SetString(ctx, "z", "v", time.Hour); !quotaExceeded(err) {
+		t.Errorf("expected the default quota in another namespace, got %v", err)
+	}
+
+	// the default quota is reloadable
+	next := testConfig("")
+	next.QuotaMaxKeys = 5
+	if err := srv.Reload(next); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if err := other.SetString(ctx, "z", "v", time.Hour); err != nil {
+		t.Errorf("expected the reloaded quota to apply, got %v", err)
+	}
+}
+
+func TestQuotas_Bytes(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	repo.SetQuota(domain.DefaultNamespace, storage.Quota{MaxBytes: 1024})
+	ctx := context.Background()
+
+	if err := repo.Set(ctx, "small", domain.NewStringEntry("v", 0)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	err := repo.Set(ctx, "big", domain.NewStringEntry(strings.Repeat("x", 2048), 0))
+	if !errors.Is(err, domain.ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+	if _, err := repo.Get(ctx, "big"); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("expected the refused key not to be stored, got %v", err)
+	}
+
+	// usage is reported with the effective limits
+	stats := repo.Namespace(domain.DefaultNamespace)
+	if stats.Keys[domain.TypeString] != 1 || stats.Bytes == 0 || stats.Quota.MaxBytes != 1024 {
+		t.Errorf("unexpected namespace stats %+v", stats)
+	}
+}
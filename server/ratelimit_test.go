@@ -0,0 +1,350 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/adapters/grpcapi"
+	"data_storage/server/adapters/memcache"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/adapters/resp"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_RateLimit(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "batch-job", Token: "batch-token", Prefixes: []string{"*"}, Classes: auth.AllClasses})
+	limiter := middleware.NewRateLimiter(map[auth.OpClass]middleware.Limit{
+		auth.OpRead:  {Rate: 1, Burst: 2},
+		auth.OpWrite: {Rate: 1, Burst: 1},
+	})
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithRateLimiter(limiter),
+	))
+	defer ts.Close()
+
+	do := func(token, method, path, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, ts.URL+path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// 1) Writes and reads draw from separate buckets
+	if resp := do("batch-token", http.MethodPost, "/v1/string/job", `{"value":"1"}`); resp.StatusCode != http.StatusOK {
+		t.Fatalf("first write: status %d", resp.StatusCode)
+	}
+	if resp := do("batch-token", http.MethodPost, "/v1/string/job", `{"value":"2"}`); resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("second write: status %d, want 429", resp.StatusCode)
+	}
+
+	// 2) The read burst is spent, then throttled with the advertised headers
+	for i := 0; i < 2; i++ {
+		resp := do("batch-token", http.MethodGet, "/v1/string/job", "")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("read %d: status %d", i, resp.StatusCode)
+		}
+		if resp.Header.Get("X-RateLimit-Limit") != "2" {
+			t.Fatalf("X-RateLimit-Limit = %q", resp.Header.Get("X-RateLimit-Limit"))
+		}
+	}
+	resp := do("batch-token", http.MethodGet, "/v1/string/job", "")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("third read: status %d, want 429", resp.StatusCode)
+	}
+	if resp.Header.Get("Retry-After") != "1" || resp.Header.Get("X-RateLimit-Remaining") != "0" || resp.Header.Get("X-RateLimit-Reset") == "" {
+		t.Fatalf("unexpected throttling headers %v", resp.Header)
+	}
+
+	// 3) Other callers are unaffected
+	if resp := do("my-secret-token", http.MethodGet, "/v1/string/job", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("other token: status %d", resp.StatusCode)
+	}
+
+	// 4) The SDK waits out Retry-After instead of failing
+	batch, _ := client.NewClient(ts.URL, "batch-token")
+	if v, err := batch.GetString(context.Background(), "job"); err != nil || v != "1" {
+		t.Fatalf("GetString through throttling = %q, %v", v, err)
+	}
+}
+
+func TestIntegration_AuthFailureLimit(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	limiter := middleware.NewRateLimiter(map[auth.OpClass]middleware.Limit{
+		middleware.AuthFailures: {Rate: 0.01, Burst: 3},
+	})
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token", adapters.WithRateLimiter(limiter)))
+	defer ts.Close()
+
+	get := func(token string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/string/k", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET: %v", err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	// valid requests do not spend the failure budget
+	for i := 0; i < 5; i++ {
+		if resp := get("my-secret-token"); resp.StatusCode == http.StatusTooManyRequests {
+			t.Fatalf("valid request %d throttled", i)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		if resp := get("guess-" + string(rune('a'+i))); resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("bad token %d: status %d, want 401", i, resp.StatusCode)
+		}
+	}
+	resp := get("guess-d")
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After after repeated failures, got %d", resp.StatusCode)
+	}
+	// the IP is locked out before credentials are checked
+	if resp := get("my-secret-token"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("expected the locked-out IP to get 429, got %d", resp.StatusCode)
+	}
+}
+
+func TestIntegration_RateLimitOtherTransports(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	newLimiter := func() *middleware.RateLimiter {
+		return middleware.NewRateLimiter(map[auth.OpClass]middleware.Limit{
+			auth.OpRead:             {Rate: 0.01, Burst: 2},
+			auth.OpWrite:            {Rate: 0.01, Burst: 1},
+			middleware.AuthFailures: {Rate: 0.01, Burst: 2},
+		})
+	}
+	listen := func() net.Listener {
+		t.Helper()
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		return ln
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	t.Run("websocket", func(t *testing.T) {
+		ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token", adapters.WithRateLimiter(newLimiter())))
+		defer ts.Close()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/ws?access_token=my-secret-token", nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		frame := func(f string) map[string]interface{} {
+			t.Helper()
+			if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			var msg map[string]interface{}
+			if err := conn.ReadJSON(&msg); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			return msg
+		}
+		code := func(msg map[string]interface{}) float64 {
+			errObj, _ := msg["error"].(map[string]interface{})
+			code, _ := errObj["code"].(float64)
+			return code
+		}
+
+		if msg := frame(`{"id":"1","op":"set","key":"ws","value":"v"}`); msg["ok"] != true {
+			t.Fatalf("first set: %v", msg)
+		}
+		if msg := frame(`{"id":"2","op":"set","key":"ws","value":"v"}`); code(msg) != http.StatusTooManyRequests {
+			t.Fatalf("second set: %v, want 429", msg)
+		}
+		// ops that touch no data are not limited
+		if msg := frame(`{"id":"3","op":"ping"}`); msg["ok"] != true {
+			t.Fatalf("ping: %v", msg)
+		}
+	})
+
+	t.Run("websocket subscriptions", func(t *testing.T) {
+		ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token"))
+		defer ts.Close()
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/v1/ws?access_token=my-secret-token", nil)
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		var last map[string]interface{}
+		for i := 0; i <= 64; i++ {
+			if err := conn.WriteJSON(map[string]string{"id": "w" + strconv.Itoa(i), "op": "watch", "pattern": "k"}); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			last = nil
+			if err := conn.ReadJSON(&last); err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if i < 64 && last["ok"] != true {
+				t.Fatalf("watch %d: %v", i, last)
+			}
+		}
+		if errObj, _ := last["error"].(map[string]interface{}); errObj["code"] != float64(http.StatusTooManyRequests) {
+			t.Fatalf("watch over the cap: %v, want 429", last)
+		}
+	})
+
+	t.Run("resp", func(t *testing.T) {
+		ln := listen()
+		srv := resp.NewServer(svc, tokens, resp.WithRateLimiter(newLimiter()))
+		go srv.Serve(ln)
+		defer srv.Close()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(conn)
+		cmd := func(line string) string {
+			t.Helper()
+			io.WriteString(conn, line+"\r\n")
+			reply, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("%s: %v", line, err)
+			}
+			return strings.TrimRight(reply, "\r\n")
+		}
+
+		for i := 0; i < 2; i++ {
+			if got := cmd("AUTH guess"); !strings.HasPrefix(got, "-WRONGPASS") {
+				t.Fatalf("bad AUTH %d: %q", i, got)
+			}
+		}
+		// locked out before the token is checked, valid ones included
+		if got := cmd("AUTH my-secret-token"); !strings.HasPrefix(got, "-ERR too many failed authentication attempts") {
+			t.Fatalf("AUTH after failures: %q", got)
+		}
+
+		// a fresh limiter, as the IP is now locked out of this one
+		srv2 := resp.NewServer(svc, tokens, resp.WithRateLimiter(newLimiter()))
+		ln2 := listen()
+		go srv2.Serve(ln2)
+		defer srv2.Close()
+		conn, err = net.Dial("tcp", ln2.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r = bufio.NewReader(conn)
+		if got := cmd("AUTH my-secret-token"); got != "+OK" {
+			t.Fatalf("AUTH: %q", got)
+		}
+		if got := cmd("SET k v"); got != "+OK" {
+			t.Fatalf("first SET: %q", got)
+		}
+		if got := cmd("SET k v"); !strings.HasPrefix(got, "-ERR rate limit exceeded") {
+			t.Fatalf("second SET: %q", got)
+		}
+		if got := cmd("PING"); got != "+PONG" {
+			t.Fatalf("PING: %q", got)
+		}
+	})
+
+	t.Run("grpc", func(t *testing.T) {
+		ln := listen()
+		srv := grpcapi.NewServer(svc, tokens, grpcapi.WithRateLimiter(newLimiter()))
+		go srv.Serve(ln)
+		defer srv.Stop()
+
+		bad, _ := client.NewGRPCClient(ln.Addr().String(), "guess")
+		defer bad.Close()
+		good, _ := client.NewGRPCClient(ln.Addr().String(), "my-secret-token")
+		defer good.Close()
+
+		var he *client.HTTPError
+		if err := good.SetString(ctx, "g", "v", 0); err != nil {
+			t.Fatalf("first SetString: %v", err)
+		}
+		if err := good.SetString(ctx, "g", "v", 0); !errors.As(err, &he) || he.Code != http.StatusTooManyRequests {
+			t.Fatalf("second SetString: %v, want 429", err)
+		}
+		for i := 0; i < 2; i++ {
+			if _, err := bad.GetString(ctx, "g"); !errors.As(err, &he) || he.Code != http.StatusUnauthorized {
+				t.Fatalf("bad token %d: %v, want 401", i, err)
+			}
+		}
+		if _, err := good.GetString(ctx, "g"); !errors.As(err, &he) || he.Code != http.StatusTooManyRequests {
+			t.Fatalf("GetString after failures: %v, want 429", err)
+		}
+	})
+
+	t.Run("memcache", func(t *testing.T) {
+		ln := listen()
+		srv := memcache.NewServer(svc, memcache.WithRateLimiter(newLimiter()))
+		go srv.Serve(ln)
+		defer srv.Close()
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			t.Fatalf("dial: %v", err)
+		}
+		defer conn.Close()
+		conn.SetDeadline(time.Now().Add(5 * time.Second))
+		r := bufio.NewReader(conn)
+		cmd := func(line string) string {
+			t.Helper()
+			io.WriteString(conn, line)
+			reply, err := r.ReadString('\n')
+			if err != nil {
+				t.Fatalf("%q: %v", line, err)
+			}
+			return strings.TrimRight(reply, "\r\n")
+		}
+
+		if got := cmd("set m 0 0 1\r\nv\r\n"); got != "STORED" {
+			t.Fatalf("first set: %q", got)
+		}
+		// the data block is still consumed, so framing survives
+		if got := cmd("set m 0 0 1\r\nv\r\n"); !strings.HasPrefix(got, "SERVER_ERROR rate limit exceeded") {
+			t.Fatalf("second set: %q", got)
+		}
+		if got := cmd("version\r\n"); !strings.HasPrefix(got, "VERSION") {
+			t.Fatalf("version: %q", got)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/server/adapters/resp"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestRESP_PipelinedCommands(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := resp.NewServer(svc, auth.NewStaticRegistry("my-secret-token"))
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	cmd := func(args ...string) string {
+		var b strings.Builder
+		b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+		for _, a := range args {
+			b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+		}
+		return b.String()
+	}
+
+	// All commands are written in one go to exercise pipelining.
+	pipeline := cmd("GET", "foo") +
+		cmd("AUTH", "my-secret-token") +
+		cmd("PING") +
+		cmd("SET", "foo", "bar", "EX", "100") +
+		cmd("GET", "foo") +
+		cmd("TTL", "foo") +
+		cmd("LPUSH", "mylist", "a", "b") +
+		cmd("RPOP", "mylist") +
+		cmd("GET", "mylist") +
+		cmd("EXPIRE", "missing", "10") +
+		cmd("DEL", "foo", "missing") +
+		cmd("GET", "foo") +
+		cmd("HELLO", "3") +
+		cmd("GET", "foo") +
+		cmd("FLUSHALL")
+	if _, err := io.WriteString(conn, pipeline); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	want := []string{
+		"-NOAUTH Authentication required.",
+		"+OK",
+		"+PONG",
+		"+OK",
+		"$3", "bar",
+		":100",
+		":2",
+		"$1", "a",
+		"-WRONGTYPE Operation against a key holding the wrong kind of value",
+		":0",
+		":1",
+		"$-1",
+		"%3",
+		"$6", "server", "$12", "data_storage",
+		"$5", "proto", ":3",
+		"$4", "mode", "$10", "standalone",
+		"_",
+		"-ERR unknown command 'FLUSHALL'",
+	}
+	r := bufio.NewReader(conn)
+	for i, w := range want {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reply line %d: %v", i, err)
+		}
+		if got := strings.TrimRight(line, "\r\n"); got != w {
+			t.Errorf("reply line %d: got %q, want %q", i, got, w)
+		}
+	}
+}
+
+func TestRESP_InputLimits(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := resp.NewServer(svc, auth.NewStaticRegistry("my-secret-token"),
+		resp.WithLimits(30000, domain.Limits{MaxValueBytes: 20000, MaxPushItems: 2}))
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	cmd := func(args ...string) string {
+		var b strings.Builder
+		b.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+		for _, a := range args {
+			b.WriteString("$" + strconv.Itoa(len(a)) + "\r\n" + a + "\r\n")
+		}
+		return b.String()
+	}
+	const login = "*2\r\n$4\r\nAUTH\r\n$15\r\nmy-secret-token\r\n"
+
+	cases := []struct {
+		name, input string
+		want        []string
+	}{
+		{"args before AUTH", "*9\r\n", []string{"-ERR protocol error: invalid multibulk length"}},
+		{"bulk before AUTH", "*2\r\n$4\r\nAUTH\r\n$20000\r\n", []string{"-ERR protocol error: invalid bulk length"}},
+		{"inline line", strings.Repeat("x", 70000) + "\r\n", []string{"-ERR protocol error: too big inline request"}},
+		{"bulk over the value limit", login + "*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$20001\r\n",
+			[]string{"+OK", "-ERR protocol error: invalid bulk length"}},
+		{"args over the push limit", login + "*9\r\n", []string{"+OK", "-ERR protocol error: invalid multibulk length"}},
+		{"command over the request limit", login + "*4\r\n$5\r\nLPUSH\r\n$1\r\nl\r\n$20000\r\n" + strings.Repeat("a", 20000) + "\r\n$20000\r\n",
+			[]string{"+OK", "-ERR protocol error: command too large"}},
+		{"within the limits", login + cmd("SET", "k", strings.Repeat("v", 20000)) + cmd("LPUSH", "l", "a", "b"),
+			[]string{"+OK", "+OK", ":2"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			if _, err := io.WriteString(conn, tc.input); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+			r := bufio.NewReader(conn)
+			for i, w := range tc.want {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					t.Fatalf("reply line %d: %v", i, err)
+				}
+				if got := strings.TrimRight(line, "\r\n"); got != w {
+					t.Errorf("reply line %d: got %q, want %q", i, got, w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+// Package server assembles the store, its service and every configured
+// listener into one process that can be started and stopped as a unit.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"data_storage/config"
+	"data_storage/server/adapters"
+	"data_storage/server/adapters/grpcapi"
+	"data_storage/server/adapters/memcache"
+	"data_storage/server/adapters/middleware"
+	"data_storage/server/adapters/resp"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	"data_storage/server/domain"
+	"data_storage/server/health"
+	"data_storage/server/logging"
+	"data_storage/server/metrics"
+	"data_storage/server/monitor"
+	"data_storage/server/slowlog"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+	"data_storage/server/tlsconfig"
+	"data_storage/server/webhooks"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Server owns the repository, its background workers and the HTTP,
+// metrics, RESP, gRPC and memcached listeners built from a Config.
+type Server struct {
+	repo      *storage.Data
+	hooks     *webhooks.Dispatcher
+	readiness *health.Readiness
+	auditFile *audit.RotatingFile // nil without AUDIT_FILE
+	auditLog  *audit.Logger       // records RESP, gRPC and memcache mutations
+	tlsCfg    *tls.Config         // nil without TLS_CERT_FILE
+
+	// reloadable settings, see Reload
+	mu        sync.Mutex     // serialises Reload and guards cfg and requested
+	cfg       *config.Server // in effect: restart-only settings as started
+	requested *config.Server // last one passed to New or Reload
+	tokens    *auth.Registry
+	limiter   *middleware.RateLimiter
+	slow      *slowlog.Log
+	logLevel  *slog.LevelVar // nil unless WithLogLevel
+
+	httpSrv    *http.Server
+	metricsSrv *http.Server     // nil unless METRICS_ADDR is set
+	respSrv    *resp.Server     // nil unless RESP_ADDR is set
+	grpcSrv    *grpc.Server     // nil unless GRPC_ADDR is set
+	mcSrv      *memcache.Server // nil unless MEMCACHE_ADDR is set
+
+	httpLn   net.Listener
+	started  chan struct{}  // closed once the snapshot is loaded
+	draining chan struct{}  // closed when shutdown starts
+	sockets  sync.WaitGroup // running WebSocket handlers
+	errs     chan error
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// Option customises a Server.
+type Option func(*Server)
+
+// WithLogLevel lets Reload change the level of the logger built on
+// level.
+func WithLogLevel(level *slog.LevelVar) Option {
+	return func(s *Server) {
+		s.logLevel = level
+	}
+}
+
+// New wires the store and every listener enabled in cfg. Nothing is
+// listening, and the snapshot is not loaded, until Start.
+func New(cfg *config.Server, version string, opts ...Option) (*Server, error) {
+	started := time.Now()
+	s := &Server{
+		cfg:       cfg,
+		requested: cfg,
+		repo:      storage.NewDataRepo(cfg.CleanUpInterval),
+		readiness: health.NewReadiness(),
+		started:   make(chan struct{}),
+		draining:  make(chan struct{}),
+		errs:      make(chan error, 5),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.readiness.NotReady("startup", "starting")
+	s.hooks = webhooks.NewDispatcher(s.repo, cfg.WebhookMaxAttempts, cfg.WebhookBackoff)
+	if err := s.build(version, started); err != nil {
+		s.stopBackground()
+		return nil, err
+	}
+	return s, nil
+}
+
+// build does the part of New that can fail.
+func (s *Server) build(version string, started time.Time) error {
+	cfg := s.cfg
+
+	s.repo.SetCompression(compression(cfg))
+	s.repo.SetDefaultQuota(defaultQuota(cfg))
+	s.slow = slowlog.New(cfg.SlowLogThreshold, cfg.SlowLogMaxLen)
+	monitors := monitor.NewHub()
+	svc := store_service.NewStoreService(s.repo, cfg.DefaultTTL,
+		store_service.WithNotifier(s.hooks),
+		store_service.WithSlowLog(s.slow),
+		store_service.WithMonitor(monitors),
+		store_service.WithNamespaceTTLs(s.repo),
+		store_service.WithEncodedReads(s.repo),
+		store_service.WithLimits(valueLimits(cfg)),
+	)
+
+	// STORE_API_TOKEN stays a full-access "default" token; ACL_FILE adds
+	// named tokens that are also managed via /v1/admin/tokens
+	s.tokens = auth.NewStaticRegistry(cfg.APIToken)
+	if cfg.ACLFile != "" {
+		if err := s.tokens.LoadFile(cfg.ACLFile); err != nil {
+			return err
+		}
+	}
+	tokens := s.tokens
+
+	// AUTH_MODE picks static tokens, JWTs from the identity provider, or both
+	var authn auth.Authenticator = tokens
+	if cfg.AuthMode != "token" {
+		keys, err := auth.LoadJWKSFile(cfg.JWKSFile)
+		if err != nil {
+			return err
+		}
+		jwts := auth.NewJWTValidator(keys, cfg.JWTIssuer, cfg.JWTAudience, cfg.JWTLeeway)
+		authn = jwts
+		if cfg.AuthMode == "both" {
+			authn = auth.Chain(tokens, jwts)
+		}
+	}
+
+	// Prometheus metrics, on the API port for admin tokens unless
+	// METRICS_ADDR gives them a private listener
+	reg := metrics.NewRegistry()
+	metrics.RegisterRuntime(reg)
+	s.repo.RegisterMetrics(reg)
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", reg.Handler())
+		s.metricsSrv = &http.Server{Addr: cfg.MetricsAddr, Handler: mux}
+	}
+
+	// Router + middleware
+	handlerOpts := []adapters.HandlerOption{
+		adapters.WithWebhooks(s.hooks),
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithAuthenticator(authn),
+		adapters.WithMetrics(reg, cfg.MetricsAddr == ""),
+		adapters.WithReadiness(s.readiness),
+		adapters.WithStartup(s.started),
+		adapters.WithSlowLog(s.slow),
+		adapters.WithMonitor(monitors),
+		adapters.WithNamespaces(s.repo),
+		adapters.WithDrain(s.draining, &s.sockets),
+		adapters.WithAllowedOrigins(cfg.WSAllowedOrigins),
+		adapters.WithLimits(cfg.MaxBodyBytes, valueLimits(cfg)),
+		adapters.WithServerInfo(adapters.ServerInfo{
+			Version: version,
+			Started: started,
+			Repo:    s.repo,
+			Config:  func() map[string]string { return settings(s.config()) },
+		}),
+	}
+	if cfg.RequestSigning != "off" {
+		verifier := auth.NewRequestVerifier(tokens, cfg.SignatureSkew)
+		handlerOpts = append(handlerOpts, adapters.WithRequestSigning(verifier, cfg.RequestSigning == "required"))
+	}
+	// always installed so Reload can turn limits on; unlimited classes
+	// pass straight through
+	s.limiter = middleware.NewRateLimiter(rateLimits(cfg))
+	handlerOpts = append(handlerOpts, adapters.WithRateLimiter(s.limiter))
+	if cfg.AuditFile != "" {
+		auditFile, err := audit.OpenRotatingFile(cfg.AuditFile, cfg.AuditMaxSize, cfg.AuditMaxAge, cfg.AuditMaxBackups)
+		if err != nil {
+			return err
+		}
+		s.auditFile = auditFile
+		s.auditLog = audit.NewLogger(auditFile, cfg.AuditIncludeValues)
+		handlerOpts = append(handlerOpts, adapters.WithAuditLog(s.auditLog))
+	}
+
+	// Optional TLS for the HTTP and gRPC listeners
+	if cfg.TLSCertFile != "" {
+		minVersion, err := tlsconfig.ParseVersion(cfg.TLSMinVersion)
+		if err != nil {
+			return err
+		}
+		s.tlsCfg, err = tlsconfig.New(tlsconfig.Options{
+			CertFile:          cfg.TLSCertFile,
+			KeyFile:           cfg.TLSKeyFile,
+			MinVersion:        minVersion,
+			ClientCAFile:      cfg.TLSClientCAFile,
+			RequireClientCert: cfg.TLSRequireClientCert,
+			Report:            s.reportCertificate,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	s.httpSrv = &http.Server{
+		Addr:      cfg.HTTPAddr,
+		Handler:   adapters.NewHandler(svc, cfg.APIToken, handlerOpts...),
+		TLSConfig: s.tlsCfg,
+		ErrorLog:  slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+	}
+
+	// Optional Redis, gRPC and memcached listeners sharing the same service
+	if cfg.RESPAddr != "" {
+		s.respSrv = resp.NewServer(s.audited(svc, "resp"), authn,
+			resp.WithLimits(cfg.MaxBodyBytes, valueLimits(cfg)),
+			resp.WithRateLimiter(s.limiter))
+	}
+	if cfg.GRPCAddr != "" {
+		grpcOpts := []grpcapi.Option{grpcapi.WithClientCerts(tokens), grpcapi.WithRateLimiter(s.limiter)}
+		if s.tlsCfg != nil {
+			grpcOpts = append(grpcOpts, grpcapi.WithServerOptions(grpc.Creds(credentials.NewTLS(s.tlsCfg))))
+		}
+		if cfg.MaxBodyBytes > 0 {
+			grpcOpts = append(grpcOpts, grpcapi.WithServerOptions(grpc.MaxRecvMsgSize(int(cfg.MaxBodyBytes))))
+		}
+		s.grpcSrv = grpcapi.NewServer(s.audited(svc, "grpc"), authn, grpcOpts...)
+	}
+	if cfg.MemcacheAddr != "" {
+		// the memcached protocol is unauthenticated: commands run as the
+		// configured token, or else only loopback clients get in
+		mcOpts := []memcache.Option{memcache.WithRateLimiter(s.limiter)}
+		if cfg.MemcacheIdentity != "" {
+			if _, err := tokens.Lookup(cfg.MemcacheIdentity); err != nil {
+				return fmt.Errorf("MEMCACHE_IDENTITY %q: %w", cfg.MemcacheIdentity, err)
+			}
+			mcOpts = append(mcOpts, memcache.WithIdentity(tokens, cfg.MemcacheIdentity, cfg.MemcacheNamespace))
+		}
+		s.mcSrv = memcache.NewServer(s.audited(svc, "memcache"), mcOpts...)
+	}
+	return nil
+}
+
+// audited records the mutations made through svc over transport when
+// the audit log is on. The HTTP handler audits its own requests.
+func (s *Server) audited(svc store_service.StoreServiceRepo, transport string) store_service.StoreServiceRepo {
+	if s.auditLog == nil {
+		return svc
+	}
+	return store_service.Audited(svc, s.auditLog, transport)
+}
+
+// reportCertificate fails readiness while the TLS key pair cannot be
+// loaded or has expired.
+func (s *Server) reportCertificate(err error) {
+	if err != nil {
+		slog.Error("TLS certificate unusable", "err", err)
+		s.readiness.NotReady("tls", err.Error())
+		return
+	}
+	slog.Info("TLS certificate usable again")
+	s.readiness.Ready("tls")
+}
+
+// Start binds every listener, loads the snapshot and serves in the
+// background. While the snapshot loads only the HTTP listener answers:
+// /readyz reports it and other requests get 503. If any address cannot
+// be bound or the snapshot cannot be loaded, nothing is served and the
+// error is returned. Errors that stop a listener later arrive on Err.
+func (s *Server) Start() error {
+	type binding struct {
+		name  string
+		addr  string
+		serve func(net.Listener) error
+	}
+	cfg := s.config()
+	bindings := []binding{{"HTTP", cfg.HTTPAddr, s.serveHTTP}}
+	if s.metricsSrv != nil {
+		bindings = append(bindings, binding{"metrics", cfg.MetricsAddr, s.metricsSrv.Serve})
+	}
+	if s.respSrv != nil {
+		bindings = append(bindings, binding{"RESP", cfg.RESPAddr, s.respSrv.Serve})
+	}
+	if s.grpcSrv != nil {
+		bindings = append(bindings, binding{"gRPC", cfg.GRPCAddr, s.grpcSrv.Serve})
+	}
+	if s.mcSrv != nil {
+		bindings = append(bindings, binding{"memcached protocol", cfg.MemcacheAddr, s.mcSrv.Serve})
+	}
+
+	listeners := make([]net.Listener, 0, len(bindings))
+	for _, b := range bindings {
+		ln, err := net.Listen("tcp", b.addr)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return fmt.Errorf("%s listen: %w", b.name, err)
+		}
+		listeners = append(listeners, ln)
+	}
+	s.httpLn = listeners[0]
+
+	serve := func(b binding, ln net.Listener) {
+		slog.Info(b.name+" listening", "addr", ln.Addr().String())
+		go func() {
+			if err := b.serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, grpc.ErrServerStopped) {
+				s.errs <- fmt.Errorf("%s server: %w", b.name, err)
+			}
+		}()
+	}
+	serve(bindings[0], listeners[0])
+	if err := s.loadSnapshot(); err != nil {
+		s.httpSrv.Close()
+		for _, l := range listeners[1:] {
+			l.Close()
+		}
+		return err
+	}
+	close(s.started)
+	for i, b := range bindings[1:] {
+		serve(b, listeners[i+1])
+	}
+	s.readiness.Ready("startup")
+	return nil
+}
+
+// loadSnapshot loads SNAPSHOT_FILE, if set, with /readyz reporting
+// "loading" meanwhile.
+func (s *Server) loadSnapshot() error {
+	file := s.config().SnapshotFile
+	if file == "" {
+		return nil
+	}
+	s.readiness.NotReady("snapshot", "loading")
+	n, err := s.repo.LoadSnapshot(file)
+	if err != nil {
+		s.readiness.NotReady("snapshot", err.Error())
+		return err
+	}
+	s.readiness.Ready("snapshot")
+	slog.Info("snapshot loaded", "file", file, "keys", n)
+	return nil
+}
+
+// serveHTTP serves the API on ln, with TLS when configured.
+func (s *Server) serveHTTP(ln net.Listener) error {
+	if s.tlsCfg != nil {
+		// the certificate comes from TLSConfig.GetCertificate
+		return s.httpSrv.ServeTLS(ln, "", "")
+	}
+	return s.httpSrv.Serve(ln)
+}
+
+// Addr returns the address the HTTP API listens on, which differs from
+// HTTP_ADDR when that asks for any free port (":0"). Call it after Start.
+func (s *Server) Addr() string {
+	return s.httpLn.Addr().String()
+}
+
+// Err reports listeners that stopped on their own; the server should
+// then be shut down.
+func (s *Server) Err() <-chan error {
+	return s.errs
+}
+
+// Reload applies the settings of next that are safe to change while
+// serving: the tokens (STORE_API_TOKEN and ACL_FILE), rate limits, log
+// level, slow log threshold, default quota and compression. Other
+// changed settings are logged, once, as needing a restart. If the ACL
+// file cannot be loaded nothing changes.
+func (s *Server) Reload(next *config.Server) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.tokens.Reload(next.APIToken, next.ACLFile); err != nil {
+		return err
+	}
+	s.limiter.SetLimits(rateLimits(next))
+	s.slow.SetThreshold(next.SlowLogThreshold)
+	s.repo.SetDefaultQuota(defaultQuota(next))
+	s.repo.SetCompression(compression(next))
+	if s.logLevel != nil {
+		// LoadServer has validated the level
+		if level, err := logging.ParseLevel(next.LogLevel); err == nil {
+			s.logLevel.Set(level)
+		}
+	}
+
+	if names := s.requested.RestartRequired(next); len(names) > 0 {
+		slog.Warn("config changes need a restart to take effect", "settings", names)
+	}
+	s.cfg = s.cfg.Reloaded(next)
+	s.requested = next
+	slog.Info("config reloaded")
+	return nil
+}
+
+// config returns the configuration in effect.
+func (s *Server) config() *config.Server {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.cfg
+}
+
+// Shutdown stops the server in order: readiness fails, watch and other
+// long-lived streams end, listeners stop accepting while in-flight
+// requests finish, background workers stop and finally the snapshot is
+// written. Requests still running when ctx ends are cut off. Later
+// calls return the first call's result.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		s.shutdownErr = s.shutdown(ctx)
+	})
+	return s.shutdownErr
+}
+
+func (s *Server) shutdown(ctx context.Context) error {
+	var errs []error
+
+	// 1) tell load balancers to stop sending traffic, end streams
+	s.readiness.NotReady("shutdown", "draining")
+	close(s.draining)
+
+	// 2) stop accepting and drain in-flight requests; WebSockets are
+	// hijacked, so Shutdown does not wait for them
+	if err := s.httpSrv.Shutdown(ctx); err != nil {
+		s.httpSrv.Close()
+		errs = append(errs, fmt.Errorf("HTTP: %w", err))
+	}
+	sockets := make(chan struct{})
+	go func() {
+		s.sockets.Wait()
+		close(sockets)
+	}()
+	select {
+	case <-sockets:
+	case <-ctx.Done():
+		errs = append(errs, fmt.Errorf("WebSocket: %w", ctx.Err()))
+	}
+	if s.metricsSrv != nil {
+		if err := s.metricsSrv.Shutdown(ctx); err != nil {
+			s.metricsSrv.Close()
+			errs = append(errs, fmt.Errorf("metrics: %w", err))
+		}
+	}
+	if s.grpcSrv != nil {
+		stopped := make(chan struct{})
+		go func() {
+			s.grpcSrv.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			s.grpcSrv.Stop()
+			<-stopped
+			errs = append(errs, fmt.Errorf("gRPC: %w", ctx.Err()))
+		}
+	}
+	// RESP and memcached replies are written before the next command is
+	// read, so closing their connections only interrupts idle clients
+	if s.respSrv != nil {
+		s.respSrv.Close()
+	}
+	if s.mcSrv != nil {
+		s.mcSrv.Close()
+	}
+
+	// 3) nothing writes to the store any more
+	s.stopBackground()
+
+	// 4) final snapshot, unless Start never loaded the previous one,
+	// which the empty store would overwrite
+	if file := s.config().SnapshotFile; file != "" && s.loaded() {
+		n, err := s.repo.SaveSnapshot(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("snapshot: %w", err))
+		} else {
+			slog.Info("snapshot saved", "file", file, "keys", n)
+		}
+	}
+	if s.auditFile != nil {
+		if err := s.auditFile.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("audit log: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// loaded reports whether Start got past loading the snapshot.
+func (s *Server) loaded() bool {
+	select {
+	case <-s.started:
+		return true
+	default:
+		return false
+	}
+}
+
+// rateLimits converts the configured limits for the rate limiter.
+func rateLimits(cfg *config.Server) map[auth.OpClass]middleware.Limit {
+	return map[auth.OpClass]middleware.Limit{
+		auth.OpRead:             {Rate: cfg.ReadRateLimit.PerSecond, Burst: cfg.ReadRateLimit.Burst},
+		auth.OpWrite:            {Rate: cfg.WriteRateLimit.PerSecond, Burst: cfg.WriteRateLimit.Burst},
+		middleware.AuthFailures: {Rate: cfg.AuthFailureRateLimit.PerSecond, Burst: cfg.AuthFailureRateLimit.Burst},
+	}
+}
+
+// stopBackground stops the webhook workers and the expiry sweep.
+func (s *Server) stopBackground() {
+	s.hooks.Close()
+	s.repo.ShutDownInvalidation()
+}
+
+// settings lists the effective configuration for /v1/admin/info,
+// leaving out tokens and other secrets.
+func settings(cfg *config.Server) map[string]string {
+	return map[string]string{
+		"STORE_DEFAULT_TTL":        cfg.DefaultTTL.String(),
+		"CLEANUP_INTERVAL":         cfg.CleanUpInterval.String(),
+		"MAX_BODY_BYTES":           strconv.FormatInt(cfg.MaxBodyBytes, 10),
+		"MAX_KEY_LENGTH":           strconv.Itoa(cfg.MaxKeyLength),
+		"MAX_VALUE_BYTES":          strconv.Itoa(cfg.MaxValueBytes),
+		"MAX_PUSH_ITEMS":           strconv.Itoa(cfg.MaxPushItems),
+		"MAX_LIST_LENGTH":          strconv.Itoa(cfg.MaxListLength),
+		"QUOTA_MAX_KEYS":           strconv.Itoa(cfg.QuotaMaxKeys),
+		"QUOTA_MAX_BYTES":          strconv.FormatInt(cfg.QuotaMaxBytes, 10),
+		"QUOTA_MAX_LIST_LENGTH":    strconv.Itoa(cfg.QuotaMaxListLength),
+		"COMPRESSION_MIN_BYTES":    strconv.Itoa(cfg.CompressionMinBytes),
+		"COMPRESSION_LEVEL":        strconv.Itoa(cfg.CompressionLevel),
+		"AUTH_MODE":                cfg.AuthMode,
+		"ACL_FILE":                 cfg.ACLFile,
+		"TLS":                      strconv.FormatBool(cfg.TLSCertFile != ""),
+		"TLS_CLIENT_CA_FILE":       cfg.TLSClientCAFile,
+		"REQUEST_SIGNING":          cfg.RequestSigning,
+		"RATE_LIMIT_READS":         formatRateLimit(cfg.ReadRateLimit),
+		"RATE_LIMIT_WRITES":        formatRateLimit(cfg.WriteRateLimit),
+		"RATE_LIMIT_AUTH_FAILURES": formatRateLimit(cfg.AuthFailureRateLimit),
+		"AUDIT_FILE":               cfg.AuditFile,
+		"AUDIT_INCLUDE_VALUES":     strconv.FormatBool(cfg.AuditIncludeValues),
+		"LOG_FORMAT":               cfg.LogFormat,
+		"LOG_LEVEL":                cfg.LogLevel,
+		"TRACE_EXPORTER":           cfg.TraceExporter,
+		"SLOWLOG_THRESHOLD":        cfg.SlowLogThreshold.String(),
+		"SLOWLOG_MAX_LEN":          strconv.Itoa(cfg.SlowLogMaxLen),
+		"METRICS_ADDR":             cfg.MetricsAddr,
+		"HTTP_ADDR":                cfg.HTTPAddr,
+		"RESP_ADDR":                cfg.RESPAddr,
+		"GRPC_ADDR":                cfg.GRPCAddr,
+		"MEMCACHE_ADDR":            cfg.MemcacheAddr,
+		"MEMCACHE_IDENTITY":        cfg.MemcacheIdentity,
+		"MEMCACHE_NAMESPACE":       cfg.MemcacheNamespace,
+		"WEBHOOK_MAX_ATTEMPTS":     strconv.Itoa(cfg.WebhookMaxAttempts),
+		"SHUTDOWN_TIMEOUT":         cfg.ShutdownTimeout.String(),
+		"SNAPSHOT_FILE":            cfg.SnapshotFile,
+	}
+}
+
+func valueLimits(cfg *config.Server) domain.Limits {
+	return domain.Limits{
+		MaxKeyLength:  cfg.MaxKeyLength,
+		MaxValueBytes: cfg.MaxValueBytes,
+		MaxPushItems:  cfg.MaxPushItems,
+		MaxListLength: cfg.MaxListLength,
+	}
+}
+
+func defaultQuota(cfg *config.Server) storage.Quota {
+	return storage.Quota{
+		MaxKeys:       cfg.QuotaMaxKeys,
+		MaxBytes:      cfg.QuotaMaxBytes,
+		MaxListLength: cfg.QuotaMaxListLength,
+	}
+}
+
+func compression(cfg *config.Server) storage.Compression {
+	return storage.Compression{
+		MinSize: cfg.CompressionMinBytes,
+		Level:   cfg.CompressionLevel,
+	}
+}
+
+func formatRateLimit(l config.RateLimit) string {
+	if l.PerSecond == 0 {
+		return "unlimited"
+	}
+	rate := strconv.FormatFloat(l.PerSecond, 'g', -1, 64)
+	if l.Burst == 0 {
+		return rate
+	}
+	return rate + ":" + strconv.Itoa(l.Burst)
+}
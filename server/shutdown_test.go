@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"data_storage/client"
+	"data_storage/config"
+)
+
+func testConfig(snapshot string) *config.Server {
+	return &config.Server{
+		DefaultTTL:         time.Minute,
+		CleanUpInterval:    time.Minute,
+		APIToken:           "my-secret-token",
+		AuthMode:           "token",
+		RequestSigning:     "off",
+		WebhookMaxAttempts: 1,
+		SlowLogThreshold:   time.Second,
+		SlowLogMaxLen:      8,
+		HTTPAddr:           "127.0.0.1:0",
+		ShutdownTimeout:    5 * time.Second,
+		SnapshotFile:       snapshot,
+	}
+}
+
+func TestServer_ShutdownDrainsStreamsAndSnapshots(t *testing.T) {
+	snapshot := filepath.Join(t.TempDir(), "store.json")
+	srv, err := New(testConfig(snapshot), "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	base := "http://" + srv.Addr()
+
+	ctx := context.Background()
+	cli, _ := client.NewClient(base, "my-secret-token")
+	if err := cli.SetString(ctx, "kept", "value", time.Hour); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+	if err := cli.SetString(ctx, "short", "value", time.Second); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+	if err := cli.LPush(ctx, "queue", "a", "b"); err != nil {
+		t.Fatalf("LPush: %v", err)
+	}
+
+	// a watch never ends on its own; shutdown must not wait for it
+	req, _ := http.NewRequest(http.MethodGet, base+"/v1/watch?pattern=*", nil)
+	req.Header.Set("Authorization", "Bearer my-secret-token")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET watch: %v", err)
+	}
+	streamDone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		close(streamDone)
+	}()
+
+	// nor for an open WebSocket, though it waits for its handler to
+	// end before taking the snapshot
+	ws, _, err := websocket.DefaultDialer.Dial("ws://"+srv.Addr()+"/v1/ws?access_token=my-secret-token", nil)
+	if err != nil {
+		t.Fatalf("dial WebSocket: %v", err)
+	}
+	defer ws.Close()
+	if err := ws.WriteMessage(websocket.TextMessage, []byte(`{"id":"1","op":"lpush","key":"pushed","items":["c"]}`)); err != nil {
+		t.Fatalf("write frame: %v", err)
+	}
+	var reply map[string]interface{}
+	if err := ws.ReadJSON(&reply); err != nil || reply["ok"] != true {
+		t.Fatalf("lpush over WebSocket: %v %v", reply, err)
+	}
+
+	time.Sleep(1100 * time.Millisecond) // let "short" expire before the snapshot
+	start := time.Now()
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if took := time.Since(start); took > 2*time.Second {
+		t.Fatalf("shutdown waited %v for open streams", took)
+	}
+	// the handler has finished, so its close frame is already on the wire
+	ws.SetReadDeadline(time.Now().Add(time.Second))
+	if _, _, err := ws.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Fatalf("expected the WebSocket to be closed by shutdown, got %v", err)
+	}
+	select {
+	case <-streamDone:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the watch stream to end")
+	}
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+	if _, err := http.Get(base + "/healthz"); err == nil {
+		t.Fatal("expected the listener to be closed")
+	}
+
+	// a new server picks up the snapshot
+	srv2, err := New(testConfig(snapshot), "test")
+	if err != nil {
+		t.Fatalf("New from snapshot: %v", err)
+	}
+	if err := srv2.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv2.Shutdown(context.Background())
+	cli2, _ := client.NewClient("http://"+srv2.Addr(), "my-secret-token")
+
+	if got, err := cli2.GetString(ctx, "kept"); err != nil || got != "value" {
+		t.Fatalf("expected kept=value from the snapshot, got %q, %v", got, err)
+	}
+	if _, err := cli2.GetString(ctx, "short"); err == nil {
+		t.Fatal("expected the expired key to be left out of the snapshot")
+	}
+	if got, err := cli2.RPop(ctx, "queue"); err != nil || got != "b" {
+		t.Fatalf("expected the list to survive, RPop got %q, %v", got, err)
+	}
+	if got, err := cli2.RPop(ctx, "pushed"); err != nil || got != "c" {
+		t.Fatalf("expected the WebSocket push in the snapshot, RPop got %q, %v", got, err)
+	}
+}
+
+func TestServer_ShutdownDeadline(t *testing.T) {
+	srv, err := New(testConfig(""), "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	// an idle keep-alive connection is closed, not waited for
+	resp, err := http.Get("http://" + srv.Addr() + "/healthz")
+	if err != nil {
+		t.Fatalf("GET healthz: %v", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_SignedRequests(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	tokens.Put(auth.Token{Name: "orders-service", Token: "orders-secret", Prefixes: []string{"orders:"}, Classes: auth.AllClasses})
+	verifier := auth.NewRequestVerifier(tokens, time.Minute)
+
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithRequestSigning(verifier, false),
+	))
+	defer ts.Close()
+	ctx := context.Background()
+
+	// 1) The SDK signs requests instead of sending the secret
+	signed, _ := client.NewClient(ts.URL, "", client.WithHMACSigning("orders-service", "orders-secret"))
+	if err := signed.SetString(ctx, "orders:1", "shipped", time.Minute); err != nil {
+		t.Fatalf("signed SetString: %v", err)
+	}
+	if v, err := signed.GetString(ctx, "orders:1"); err != nil || v != "shipped" {
+		t.Fatalf("signed GetString = %q, %v", v, err)
+	}
+	// the namespace and content type it sends are signed along
+	scoped, _ := client.NewClient(ts.URL, "", client.WithHMACSigning("orders-service", "orders-secret"), client.WithNamespace("default"))
+	if err := scoped.(client.BlobClient).SetBlob(ctx, "orders:label", []byte{0xff, 0x00}, "image/png", time.Minute); err != nil {
+		t.Fatalf("signed SetBlob in a namespace: %v", err)
+	}
+	var he *client.HTTPError
+	if err := signed.SetString(ctx, "users:1", "alice", time.Minute); !errors.As(err, &he) || he.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 outside the key's prefixes, got %v", err)
+	}
+
+	// 2) Bearer tokens keep working while signing is optional
+	bearer, _ := client.NewClient(ts.URL, "my-secret-token")
+	if _, err := bearer.GetString(ctx, "orders:1"); err != nil {
+		t.Fatalf("bearer GetString: %v", err)
+	}
+
+	// 3) Replays, stale timestamps and altered requests are rejected
+	// send posts body but signs signedBody, which differ for tampering;
+	// tamper, if set, alters the request after it is signed
+	send := func(signedBody, body, nonce string, at time.Time, tamper func(*http.Request)) int {
+		sum := sha256.Sum256([]byte(signedBody))
+		sr := auth.SignedRequest{
+			Method:      http.MethodPost,
+			Path:        "/v1/string/orders:2?ttl=60",
+			ContentType: "application/json",
+			Timestamp:   strconv.FormatInt(at.Unix(), 10),
+			Nonce:       nonce,
+			BodyHash:    hex.EncodeToString(sum[:]),
+		}
+		req, _ := http.NewRequest(sr.Method, ts.URL+sr.Path, strings.NewReader(body))
+		req.Header.Set("Content-Type", sr.ContentType)
+		req.Header.Set(auth.SignatureKeyIDHeader, "orders-service")
+		req.Header.Set(auth.SignatureTimestampHeader, sr.Timestamp)
+		req.Header.Set(auth.SignatureNonceHeader, sr.Nonce)
+		req.Header.Set(auth.SignatureHeader, auth.Sign("orders-secret", sr.StringToSign()))
+		if tamper != nil {
+			tamper(req)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	body := `{"value":"packed"}`
+	if got := send(body, body, "n-1", time.Now(), nil); got != http.StatusOK {
+		t.Fatalf("fresh signed request: status %d", got)
+	}
+	if got := send(body, body, "n-1", time.Now(), nil); got != http.StatusUnauthorized {
+		t.Fatalf("replayed nonce: status %d, want 401", got)
+	}
+	if got := send(body, body, "n-2", time.Now().Add(-2*time.Minute), nil); got != http.StatusUnauthorized {
+		t.Fatalf("stale timestamp: status %d, want 401", got)
+	}
+	if got := send(body, `{"value":"stolen"}`, "n-3", time.Now(), nil); got != http.StatusUnauthorized {
+		t.Fatalf("altered body: status %d, want 401", got)
+	}
+	for what, tamper := range map[string]func(*http.Request){
+		"namespace":        func(r *http.Request) { r.Header.Set("X-Namespace", "billing") },
+		"content type":     func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") },
+		"content encoding": func(r *http.Request) { r.Header.Set("Content-Encoding", "gzip") },
+		"query":            func(r *http.Request) { r.URL.RawQuery = "ttl=0" },
+	} {
+		if got := send(body, body, "n-"+what, time.Now(), tamper); got != http.StatusUnauthorized {
+			t.Fatalf("altered %s: status %d, want 401", what, got)
+		}
+	}
+
+	// 4) Once required, bearer tokens alone are refused
+	strict := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithTokenRegistry(tokens),
+		adapters.WithRequestSigning(verifier, true),
+	))
+	defer strict.Close()
+	bearer, _ = client.NewClient(strict.URL, "my-secret-token")
+	if _, err := bearer.GetString(ctx, "orders:1"); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected 401 for bearer token in required mode, got %v", err)
+	}
+	signed, _ = client.NewClient(strict.URL, "", client.WithHMACSigning("orders-service", "orders-secret"))
+	if _, err := signed.GetString(ctx, "orders:1"); err != nil {
+		t.Fatalf("signed GetString in required mode: %v", err)
+	}
+}
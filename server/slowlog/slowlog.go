@@ -0,0 +1,109 @@
+// Package slowlog keeps the most recent store operations that took
+// longer than a threshold, like Redis SLOWLOG.
+package slowlog
+
+import (
+	"sync"
+	"time"
+)
+
+// Entry is one slow operation.
+type Entry struct {
+	ID         uint64    `json:"id"`
+	Time       time.Time `json:"time"`
+	DurationMS float64   `json:"duration_ms"`
+	Command    string    `json:"command"`
+	Namespace  string    `json:"namespace"`
+	Key        string    `json:"key,omitempty"`
+	ArgCount   int       `json:"arg_count"` // values or items passed
+	ArgBytes   int       `json:"arg_bytes"` // their total size
+	Identity   string    `json:"identity,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Log is a fixed-size ring buffer of slow operations.
+type Log struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	ring      []Entry
+	head      int // index of the oldest entry once the ring is full
+	size      int
+	nextID    uint64
+}
+
+// New keeps the last capacity operations that took at least threshold.
+func New(threshold time.Duration, capacity int) *Log {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Log{threshold: threshold, ring: make([]Entry, capacity)}
+}
+
+// Threshold returns the current threshold.
+func (l *Log) Threshold() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.threshold
+}
+
+// SetThreshold changes the threshold for later operations.
+func (l *Log) SetThreshold(d time.Duration) {
+	l.mu.Lock()
+	l.threshold = d
+	l.mu.Unlock()
+}
+
+// Observe records e if took is at least the threshold, filling in its
+// ID and duration. It reports whether e was recorded.
+func (l *Log) Observe(e Entry, took time.Duration) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if took < l.threshold {
+		return false
+	}
+	l.nextID++
+	e.ID = l.nextID
+	e.DurationMS = float64(took.Microseconds()) / 1000
+
+	idx := (l.head + l.size) % len(l.ring)
+	l.ring[idx] = e
+	if l.size < len(l.ring) {
+		l.size++
+	} else {
+		l.head = (l.head + 1) % len(l.ring)
+	}
+	return true
+}
+
+// Entries returns up to n entries, newest first; n <= 0 returns all.
+func (l *Log) Entries(n int) []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n <= 0 || n > l.size {
+		n = l.size
+	}
+	out := make([]Entry, 0, n)
+	for i := 0; i < n; i++ {
+		out = append(out, l.ring[(l.head+l.size-1-i)%len(l.ring)])
+	}
+	return out
+}
+
+// Len returns the number of entries held.
+func (l *Log) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.size
+}
+
+// Capacity returns the most entries the log keeps.
+func (l *Log) Capacity() int {
+	return len(l.ring)
+}
+
+// Reset drops every entry; IDs keep increasing.
+func (l *Log) Reset() {
+	l.mu.Lock()
+	l.head, l.size = 0, 0
+	l.mu.Unlock()
+}
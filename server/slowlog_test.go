@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/slowlog"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_SlowLog(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+
+	// a zero threshold records every call; three fit in the ring
+	slow := slowlog.New(0, 3)
+	svc := store_service.NewStoreService(repo, time.Minute, store_service.WithSlowLog(slow))
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token", adapters.WithSlowLog(slow)))
+	defer ts.Close()
+
+	cl, _ := client.NewClient(ts.URL, "my-secret-token")
+	admin := cl.(client.AdminClient)
+	ctx := context.Background()
+
+	cl.SetString(ctx, "a", "hello", time.Minute)
+	cl.LPush(ctx, "jobs", "x", "yy", "zzz")
+	cl.GetString(ctx, "missing")
+	cl.RPop(ctx, "jobs")
+
+	log, err := admin.SlowLog(ctx, 0)
+	if err != nil {
+		t.Fatalf("SlowLog: %v", err)
+	}
+	if log.Len != 3 || log.Capacity != 3 || len(log.Entries) != 3 {
+		t.Fatalf("expected a full ring of 3, got %+v", log)
+	}
+	// newest first; the SetString fell out of the ring
+	if log.Entries[0].Command != "RPop" || log.Entries[2].Command != "LPush" {
+		t.Fatalf("unexpected order %+v", log.Entries)
+	}
+	push := log.Entries[2]
+	if push.Key != "jobs" || push.ArgCount != 3 || push.ArgBytes != 6 || push.Identity != "default" {
+		t.Fatalf("unexpected LPush entry %+v", push)
+	}
+	if log.Entries[1].Error == "" {
+		t.Fatalf("expected the failed GetString to carry its error: %+v", log.Entries[1])
+	}
+
+	newest, _ := admin.SlowLog(ctx, 1)
+	if len(newest.Entries) != 1 || newest.Entries[0].ID != log.Entries[0].ID {
+		t.Fatalf("count=1 should return only the newest entry, got %+v", newest.Entries)
+	}
+
+	// after a reset, fast calls stay out once the threshold is raised
+	if err := admin.ResetSlowLog(ctx); err != nil {
+		t.Fatalf("ResetSlowLog: %v", err)
+	}
+	slow.SetThreshold(time.Hour)
+	cl.SetString(ctx, "b", "fast", time.Minute)
+	if log, _ := admin.SlowLog(ctx, 0); log.Len != 0 {
+		t.Fatalf("expected an empty slow log, got %+v", log)
+	}
+}
+
+func TestIntegration_SlowLogBlobSize(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+
+	slow := slowlog.New(0, 8)
+	svc := store_service.NewStoreService(repo, time.Minute, store_service.WithSlowLog(slow))
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token", adapters.WithSlowLog(slow)))
+	defer ts.Close()
+
+	cl, _ := client.NewClient(ts.URL, "my-secret-token")
+	ctx := context.Background()
+
+	// a blob is reported by its size, not that of its media type
+	data := make([]byte, 1000)
+	data[0] = 0xff
+	if err := cl.(client.BlobClient).SetBlob(ctx, "logo", data, "image/png", time.Minute); err != nil {
+		t.Fatalf("SetBlob: %v", err)
+	}
+	log, err := cl.(client.AdminClient).SlowLog(ctx, 1)
+	if err != nil || len(log.Entries) != 1 {
+		t.Fatalf("SlowLog: %+v, %v", log, err)
+	}
+	if e := log.Entries[0]; e.Command != "SetBlob" || e.ArgBytes != len(data) {
+		t.Fatalf("expected SetBlob with %d bytes, got %+v", len(data), e)
+	}
+}
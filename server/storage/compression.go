@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"compress/gzip"
+	"data_storage/server/domain"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Compression configures how the repository stores large values. String
+// values and list items of at least MinSize bytes are kept
+// gzip-compressed when that saves space; a MinSize of 0 disables
+// compression. Memory accounting and quotas see the compressed sizes.
+type Compression struct {
+	MinSize int
+	Level   int // gzip level from 1 (fastest) to 9; 0 means gzip.BestSpeed
+}
+
+// codec compresses values as configured by a Compression.
+type codec struct {
+	Compression
+	writers sync.Pool // *gzip.Writer at Level
+}
+
+func newCodec(c Compression) *codec {
+	if c.Level == 0 {
+		c.Level = gzip.BestSpeed
+	}
+	cd := &codec{Compression: c}
+	cd.writers.New = func() any {
+		w, err := gzip.NewWriterLevel(nil, c.Level)
+		if err != nil {
+			// an invalid level falls back to the default one
+			w = gzip.NewWriter(nil)
+		}
+		return w
+	}
+	return cd
+}
+
+// compress returns s gzip-compressed if it is long enough and shrinks.
+// Values that already start with the gzip header are always compressed,
+// so that every such value of a packed entry decodes unambiguously.
+func (c *codec) compress(s string) (string, bool) {
+	forced := domain.IsGzip(s)
+	if len(s) < c.MinSize && !forced {
+		return "", false
+	}
+	var buf strings.Builder
+	w := c.writers.Get().(*gzip.Writer)
+	w.Reset(&buf)
+	io.WriteString(w, s)
+	w.Close()
+	c.writers.Put(w)
+	if buf.Len() >= len(s) && !forced {
+		return "", false
+	}
+	return buf.String(), true
+}
+
+var readers sync.Pool // *gzip.Reader
+
+// decompress inflates the gzip-compressed s.
+func decompress(s string) (string, error) {
+	r, _ := readers.Get().(*gzip.Reader)
+	var err error
+	if r == nil {
+		r, err = gzip.NewReader(strings.NewReader(s))
+	} else {
+		err = r.Reset(strings.NewReader(s))
+	}
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if _, err := io.Copy(&buf, r); err != nil {
+		return "", err
+	}
+	readers.Put(r)
+	return buf.String(), nil
+}
+
+// SetCompression changes how values written from now on are stored;
+// values already stored keep their form until they are rewritten.
+func (d *Data) SetCompression(c Compression) {
+	if c.MinSize <= 0 {
+		d.codec.Store(nil)
+		return
+	}
+	d.codec.Store(newCodec(c))
+}
+
+// pack returns entry as the repository holds it, along with the bytes
+// compression saves. Entries that are already encoded, or that have
+// nothing worth compressing, are returned as is.
+func (d *Data) pack(entry *domain.Entry) (*domain.Entry, int64) {
+	c := d.codec.Load()
+	if c == nil || entry.Encoding != "" {
+		return entry, 0
+	}
+	var packed *domain.Entry
+	var saved int64
+	copied := false // packed.Items is no longer shared with entry
+	if s, ok := c.compress(entry.Str); ok {
+		cp := *entry
+		packed = &cp
+		packed.Str = s
+		saved += int64(len(entry.Str) - len(s))
+	}
+	for i, item := range entry.Items {
+		s, ok := c.compress(item)
+		if !ok {
+			continue
+		}
+		if packed == nil {
+			cp := *entry
+			packed = &cp
+		}
+		if !copied {
+			packed.Items = append([]string(nil), entry.Items...)
+			copied = true
+		}
+		packed.Items[i] = s
+		saved += int64(len(item) - len(s))
+	}
+	if packed == nil {
+		return entry, 0
+	}
+	packed.Encoding = domain.EncodingGzip
+	return packed, saved
+}
+
+// unpack returns a decoded copy of an encoded entry, or entry itself.
+func unpack(entry *domain.Entry) (*domain.Entry, error) {
+	if entry.Encoding == "" {
+		return entry, nil
+	}
+	if entry.Encoding != domain.EncodingGzip {
+		return nil, fmt.Errorf("%w: %q", domain.ErrUnsupportedEncoding, entry.Encoding)
+	}
+	out := *entry
+	out.Encoding = ""
+	var err error
+	if domain.IsGzip(out.Str) {
+		if out.Str, err = decompress(out.Str); err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidEncoding, err)
+		}
+	}
+	if len(entry.Items) > 0 {
+		out.Items = make([]string, len(entry.Items))
+		for i, item := range entry.Items {
+			if !domain.IsGzip(item) {
+				out.Items[i] = item
+				continue
+			}
+			if out.Items[i], err = decompress(item); err != nil {
+				return nil, fmt.Errorf("%w: %v", domain.ErrInvalidEncoding, err)
+			}
+		}
+	}
+	return &out, nil
+}
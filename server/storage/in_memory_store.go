@@ -3,36 +3,163 @@ package storage
 import (
 	"context"
 	"data_storage/server/domain"
+	"data_storage/server/events"
+	"data_storage/server/metrics"
+	"data_storage/server/tracing"
+	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
-// Data is a thread-safe, TTL-backed in-memory repository.
+// Data is a thread-safe, TTL-backed in-memory repository. Every
+// namespace has its own keyspace; calls act on the namespace of their
+// context (see domain.WithNamespace).
 type Data struct {
 	mu       sync.RWMutex
-	data     map[string]*domain.Entry
+	spaces   map[string]*keyspace
 	interval time.Duration
 	stop     chan struct{}
+	stopOnce sync.Once
+	events   *events.Hub
+	cas      uint64 // last CAS value handed out; guarded by mu
+
+	// usage accounting over every namespace, guarded by mu
+	keys  map[domain.ValueType]int
+	bytes int64
+	saved int64 // bytes compression keeps out of bytes
+
+	defaultQuota Quota                 // applies where a namespace sets no limit; guarded by mu
+	codec        atomic.Pointer[codec] // nil stores values uncompressed
+
+	expired  atomic.Uint64
+	rejected atomic.Uint64 // writes refused by a quota
+	sweep    SweepStats    // guarded by mu
+}
+
+// SweepStats describes the background expiry sweeps.
+type SweepStats struct {
+	Interval     time.Duration
+	Runs         uint64
+	LastRun      time.Time     // zero before the first sweep
+	LastDuration time.Duration // how long the last sweep took, lock wait included
+	LastExpired  int           // keys the last sweep removed
+}
+
+// keyspace holds one namespace's entries and their usage accounting.
+type keyspace struct {
+	data       map[string]*domain.Entry
+	footprints map[string]footprint
+	keys       map[domain.ValueType]int
+	bytes      int64
+	defaultTTL time.Duration // overrides the service default when non-zero
+	quota      Quota         // per-limit overrides of the default quota
+}
+
+func newKeyspace() *keyspace {
+	return &keyspace{
+		data:       make(map[string]*domain.Entry),
+		footprints: make(map[string]footprint),
+		keys:       make(map[domain.ValueType]int),
+	}
+}
+
+// footprint is what an entry was accounted as when it was stored, since
+// callers may modify entries in place before writing them back.
+type footprint struct {
+	typ   domain.ValueType
+	size  int64
+	items int
+	saved int64 // by compression, on top of size
+}
+
+// Quota limits what a namespace may hold. A zero limit is unlimited.
+type Quota struct {
+	MaxKeys       int
+	MaxBytes      int64 // approximate, as accounted in Stats.Bytes
+	MaxListLength int
+}
+
+// or fills the limits q leaves unset from def.
+func (q Quota) or(def Quota) Quota {
+	if q.MaxKeys == 0 {
+		q.MaxKeys = def.MaxKeys
+	}
+	if q.MaxBytes == 0 {
+		q.MaxBytes = def.MaxBytes
+	}
+	if q.MaxListLength == 0 {
+		q.MaxListLength = def.MaxListLength
+	}
+	return q
+}
+
+// Stats is a snapshot of the repository's contents and counters.
+type Stats struct {
+	Keys    map[domain.ValueType]int // live and not yet invalidated keys per type
+	Bytes   int64                    // approximate memory held by keys and values
+	Saved   int64                    // bytes compression keeps out of Bytes
+	Expired uint64                   // keys removed by invalidate since start
+	Sweep   SweepStats
+}
+
+// NamespaceStats describes one namespace.
+type NamespaceStats struct {
+	Name       string
+	Keys       map[domain.ValueType]int
+	Bytes      int64
+	DefaultTTL time.Duration // zero uses the server's default TTL
+	Quota      Quota         // effective limits, the default quota included
 }
 
 // NewDataRepo creates the in-memory store and immediately
 // starts a background goroutine that evicts expired entries.
 func NewDataRepo(invTimeInterval time.Duration) *Data {
 	d := &Data{
-		data:     make(map[string]*domain.Entry),
+		spaces:   make(map[string]*keyspace),
 		interval: invTimeInterval,
 		stop:     make(chan struct{}),
+		events:   events.NewHub(events.DefaultHistory),
+		keys:     make(map[domain.ValueType]int),
+		sweep:    SweepStats{Interval: invTimeInterval},
 	}
 	go d.invalidate()
 	return d
 }
 
-// Get retrieves an entry by key.
+// Get retrieves an entry by key, decoding compressed values.
 // Returns ErrNotFound if the key is missing,
 // or ErrExpiredEntry once time.Now() ≥ Expiry.
 func (d *Data) Get(ctx context.Context, key string) (*domain.Entry, error) {
-	d.mu.RLock()
-	entry, ok := d.data[key]
+	_, span := tracing.Start(ctx, "storage.Get")
+	defer span.End()
+	span.SetAttr("key", key)
+	entry, err := d.lookup(ctx, span, key)
+	if err != nil {
+		return nil, err
+	}
+	return unpack(entry)
+}
+
+// GetEncoded is Get without decoding: values stay as the repository
+// holds them, see domain.Entry.Encoding. The entry must not be modified.
+func (d *Data) GetEncoded(ctx context.Context, key string) (*domain.Entry, error) {
+	_, span := tracing.Start(ctx, "storage.GetEncoded")
+	defer span.End()
+	span.SetAttr("key", key)
+	return d.lookup(ctx, span, key)
+}
+
+// lookup returns the live entry at key in the context's namespace.
+func (d *Data) lookup(ctx context.Context, span *tracing.Span, key string) (*domain.Entry, error) {
+	ns := domain.NamespaceFrom(ctx)
+	d.rlock(span)
+	var entry *domain.Entry
+	ok := false
+	if ks := d.spaces[ns]; ks != nil {
+		entry, ok = ks.data[key]
+	}
 	d.mu.RUnlock()
 
 	if !ok {
@@ -45,9 +172,13 @@ func (d *Data) Get(ctx context.Context, key string) (*domain.Entry, error) {
 	return entry, nil
 }
 
-// Set inserts or updates an entry.
-// Returns ErrEmptyKey if key is empty, ErrEmptyEntry if entry is nil.
+// Set inserts or updates an entry and emits EventSet.
+// Returns ErrEmptyKey if key is empty, ErrEmptyEntry if entry is nil,
+// or ErrQuotaExceeded if the namespace has no room for it.
 func (d *Data) Set(ctx context.Context, key string, entry *domain.Entry) error {
+	_, span := tracing.Start(ctx, "storage.Set")
+	defer span.End()
+	span.SetAttr("key", key)
 	if key == "" {
 		return domain.ErrEmptyKey
 	}
@@ -55,27 +186,142 @@ func (d *Data) Set(ctx context.Context, key string, entry *domain.Entry) error {
 		return domain.ErrEmptyEntry
 	}
 
-	d.mu.Lock()
-	d.data[key] = entry
+	packed, saved := d.pack(entry)
+	ns := domain.NamespaceFrom(ctx)
+	d.lock(span)
+	ks := d.space(ns)
+	if err := d.admit(ks, key, packed); err != nil {
+		d.prune(ns)
+		d.mu.Unlock()
+		return err
+	}
+	d.cas++
+	entry.CAS, packed.CAS = d.cas, d.cas
+	d.store(ks, key, packed, saved)
+	d.mu.Unlock()
+
+	d.events.Publish(domain.EventSet, ns, key)
+	return nil
+}
+
+// Update runs fn under the write lock so read-modify-write sequences
+// (add, replace, cas, incr) are atomic. Expired entries are passed to
+// fn as nil and others decoded. Emits EventSet or EventDel depending on
+// the outcome. A replacement the namespace has no room for fails with
+// ErrQuotaExceeded.
+func (d *Data) Update(ctx context.Context, key string, fn func(current *domain.Entry) (*domain.Entry, error)) error {
+	_, span := tracing.Start(ctx, "storage.Update")
+	defer span.End()
+	span.SetAttr("key", key)
+	if key == "" {
+		return domain.ErrEmptyKey
+	}
+
+	ns := domain.NamespaceFrom(ctx)
+	d.lock(span)
+	ks := d.space(ns)
+	stored, ok := ks.data[key]
+	var current, next, packed *domain.Entry
+	var saved int64
+	var err error
+	if ok && (stored.Expiry.IsZero() || time.Now().Before(stored.Expiry)) {
+		current, err = unpack(stored)
+	}
+	if err == nil {
+		next, err = fn(current)
+	}
+	if err == nil && next != nil {
+		packed, saved = d.pack(next)
+		err = d.admit(ks, key, packed)
+	}
+	if err != nil {
+		d.prune(ns)
+		d.mu.Unlock()
+		return err
+	}
+	if next == nil {
+		d.drop(ks, key)
+		d.prune(ns)
+	} else {
+		d.cas++
+		next.CAS, packed.CAS = d.cas, d.cas
+		d.store(ks, key, packed, saved)
+	}
+	d.mu.Unlock()
+
+	switch {
+	case next != nil:
+		d.events.Publish(domain.EventSet, ns, key)
+	case ok:
+		d.events.Publish(domain.EventDel, ns, key)
+	}
+	return nil
+}
+
+// Flush removes every entry of the context's namespace, emitting
+// EventDel for each key. The namespace's settings are kept.
+func (d *Data) Flush(ctx context.Context) error {
+	_, span := tracing.Start(ctx, "storage.Flush")
+	defer span.End()
+	ns := domain.NamespaceFrom(ctx)
+	span.SetAttr("namespace", ns)
+	d.lock(span)
+	var keys []string
+	if ks := d.spaces[ns]; ks != nil {
+		keys = make([]string, 0, len(ks.data))
+		for k := range ks.data {
+			keys = append(keys, k)
+			d.drop(ks, k)
+		}
+		d.prune(ns)
+	}
 	d.mu.Unlock()
+
+	for _, k := range keys {
+		d.events.Publish(domain.EventDel, ns, k)
+	}
 	return nil
 }
 
-// Remove deletes the entry for the given key.
-// Returns ErrEmptyKey if key is empty.
+// Remove deletes the entry for the given key, emitting EventDel
+// if it existed. Returns ErrEmptyKey if key is empty.
 func (d *Data) Remove(ctx context.Context, key string) error {
+	_, span := tracing.Start(ctx, "storage.Remove")
+	defer span.End()
+	span.SetAttr("key", key)
 	if key == "" {
 		return domain.ErrEmptyKey
 	}
 
-	d.mu.Lock()
-	delete(d.data, key)
+	ns := domain.NamespaceFrom(ctx)
+	d.lock(span)
+	existed := false
+	if ks := d.spaces[ns]; ks != nil {
+		_, existed = ks.data[key]
+		d.drop(ks, key)
+		d.prune(ns)
+	}
 	d.mu.Unlock()
+
+	if existed {
+		d.events.Publish(domain.EventDel, ns, key)
+	}
 	return nil
 }
 
+// Watch streams keyspace events of the context's namespace for keys
+// matching the glob pattern.
+func (d *Data) Watch(ctx context.Context, pattern string, afterID uint64) (<-chan domain.Event, error) {
+	return d.events.Subscribe(ctx, domain.NamespaceFrom(ctx), pattern, afterID)
+}
+
+// Events exposes the keyspace event hub.
+func (d *Data) Events() *events.Hub {
+	return d.events
+}
+
 // invalidate runs every d.interval and removes any entries
-// whose Expiry ≤ the tick time.
+// whose Expiry ≤ the tick time, emitting EventExpired for each.
 func (d *Data) invalidate() {
 	ticker := time.NewTicker(d.interval)
 	defer ticker.Stop()
@@ -83,21 +329,282 @@ func (d *Data) invalidate() {
 	for {
 		select {
 		case now := <-ticker.C:
-			d.mu.Lock()
-			for k, entry := range d.data {
-				if !entry.Expiry.IsZero() && !now.Before(entry.Expiry) {
-					delete(d.data, k)
+			_, span := tracing.Start(context.Background(), "storage.invalidate")
+			var expired []nsKey
+			d.lock(span)
+			for ns, ks := range d.spaces {
+				for k, entry := range ks.data {
+					if !entry.Expiry.IsZero() && !now.Before(entry.Expiry) {
+						d.drop(ks, k)
+						expired = append(expired, nsKey{ns, k})
+					}
 				}
+				d.prune(ns)
 			}
+			d.sweep.Runs++
+			d.sweep.LastRun = now
+			d.sweep.LastDuration = time.Since(now)
+			d.sweep.LastExpired = len(expired)
 			d.mu.Unlock()
+			d.expired.Add(uint64(len(expired)))
+			span.SetAttr("expired", len(expired))
+			span.End()
+
+			for _, e := range expired {
+				d.events.Publish(domain.EventExpired, e.ns, e.key)
+			}
 		case <-d.stop:
 			return
 		}
 	}
 }
 
+// lock takes the write lock, recording the time spent waiting for it on
+// span.
+func (d *Data) lock(span *tracing.Span) {
+	if span == nil {
+		d.mu.Lock()
+		return
+	}
+	start := time.Now()
+	d.mu.Lock()
+	span.SetAttr("lock_wait_ms", float64(time.Since(start).Microseconds())/1000)
+}
+
+// rlock is lock for the read lock.
+func (d *Data) rlock(span *tracing.Span) {
+	if span == nil {
+		d.mu.RLock()
+		return
+	}
+	start := time.Now()
+	d.mu.RLock()
+	span.SetAttr("lock_wait_ms", float64(time.Since(start).Microseconds())/1000)
+}
+
+// Stats returns the current key counts, approximate memory use, the
+// expired key counter and the sweep statistics.
+func (d *Data) Stats() Stats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	keys := make(map[domain.ValueType]int, len(d.keys))
+	for typ, n := range d.keys {
+		keys[typ] = n
+	}
+	return Stats{
+		Keys:    keys,
+		Bytes:   d.bytes,
+		Saved:   d.saved,
+		Expired: d.expired.Load(),
+		Sweep:   d.sweep,
+	}
+}
+
+// RegisterMetrics exposes Stats on reg: key counts per type, approximate
+// memory and the expired key counter.
+func (d *Data) RegisterMetrics(reg *metrics.Registry) {
+	reg.GaugeVecFunc("store_keys", "Keys currently stored, by value type.", []string{"type"},
+		func(emit func(float64, ...string)) {
+			keys := d.Stats().Keys
+			for _, typ := range []domain.ValueType{domain.TypeString, domain.TypeList} {
+				emit(float64(keys[typ]), typ.String())
+			}
+		})
+	reg.GaugeVecFunc("store_namespace_keys", "Keys currently stored, by namespace.", []string{"namespace"},
+		func(emit func(float64, ...string)) {
+			for _, ns := range d.Namespaces() {
+				total := 0
+				for _, n := range ns.Keys {
+					total += n
+				}
+				emit(float64(total), ns.Name)
+			}
+		})
+	reg.GaugeFunc("store_memory_bytes", "Approximate memory held by keys and values.",
+		func() float64 { return float64(d.Stats().Bytes) })
+	reg.GaugeFunc("store_compression_saved_bytes", "Approximate memory saved by compressing values.",
+		func() float64 { return float64(d.Stats().Saved) })
+	reg.CounterFunc("store_expired_keys_total", "Keys removed after their TTL passed.",
+		func() float64 { return float64(d.expired.Load()) })
+	reg.CounterFunc("store_quota_rejected_writes_total", "Writes refused because a namespace quota was reached.",
+		func() float64 { return float64(d.rejected.Load()) })
+}
+
+// nsKey names a key in a namespace.
+type nsKey struct {
+	ns, key string
+}
+
+// space returns the keyspace of namespace ns, creating it if needed. The
+// caller holds mu for writing.
+func (d *Data) space(ns string) *keyspace {
+	ks, ok := d.spaces[ns]
+	if !ok {
+		ks = newKeyspace()
+		d.spaces[ns] = ks
+	}
+	return ks
+}
+
+// prune forgets namespace ns once it holds neither keys nor settings, so
+// namespaces that were only read from do not pile up. The caller holds
+// mu for writing.
+func (d *Data) prune(ns string) {
+	if ks, ok := d.spaces[ns]; ok && len(ks.data) == 0 && ks.defaultTTL == 0 && ks.quota == (Quota{}) {
+		delete(d.spaces, ns)
+	}
+}
+
+// store puts the packed entry at key in ks and updates the usage
+// accounting, saved being what compression saved on it. The caller
+// holds mu.
+func (d *Data) store(ks *keyspace, key string, entry *domain.Entry, saved int64) {
+	d.drop(ks, key)
+	fp := footprint{typ: entry.Type, size: entrySize(key, entry), items: len(entry.Items), saved: saved}
+	ks.data[key] = entry
+	ks.footprints[key] = fp
+	ks.keys[fp.typ]++
+	ks.bytes += fp.size
+	d.keys[fp.typ]++
+	d.bytes += fp.size
+	d.saved += fp.saved
+}
+
+// admit checks that storing entry at key keeps ks within its quota.
+// Only growth is refused, so a namespace over a lowered quota can still
+// shrink, overwrite in place and delete. The caller holds mu.
+func (d *Data) admit(ks *keyspace, key string, entry *domain.Entry) error {
+	q := ks.quota.or(d.defaultQuota)
+	if q == (Quota{}) {
+		return nil
+	}
+	old, exists := ks.footprints[key]
+	var err error
+	switch size := entrySize(key, entry); {
+	case q.MaxKeys > 0 && !exists && len(ks.data) >= q.MaxKeys:
+		err = fmt.Errorf("%w: namespace is limited to %d keys", domain.ErrQuotaExceeded, q.MaxKeys)
+	case q.MaxBytes > 0 && size > old.size && ks.bytes-old.size+size > q.MaxBytes:
+		err = fmt.Errorf("%w: namespace is limited to %d bytes", domain.ErrQuotaExceeded, q.MaxBytes)
+	case q.MaxListLength > 0 && len(entry.Items) > old.items && len(entry.Items) > q.MaxListLength:
+		err = fmt.Errorf("%w: lists are limited to %d items", domain.ErrQuotaExceeded, q.MaxListLength)
+	}
+	if err != nil {
+		d.rejected.Add(1)
+	}
+	return err
+}
+
+// drop removes key from ks along with its usage accounting. The caller
+// holds mu.
+func (d *Data) drop(ks *keyspace, key string) {
+	fp, ok := ks.footprints[key]
+	if !ok {
+		return
+	}
+	delete(ks.data, key)
+	delete(ks.footprints, key)
+	ks.keys[fp.typ]--
+	ks.bytes -= fp.size
+	d.keys[fp.typ]--
+	d.bytes -= fp.size
+	d.saved -= fp.saved
+}
+
+// Namespaces describes every namespace that holds keys or settings,
+// ordered by name.
+func (d *Data) Namespaces() []NamespaceStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	out := make([]NamespaceStats, 0, len(d.spaces))
+	for ns, ks := range d.spaces {
+		out = append(out, d.stats(ns, ks))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Namespace describes namespace ns; an unused namespace is empty.
+func (d *Data) Namespace(ns string) NamespaceStats {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if ks, ok := d.spaces[ns]; ok {
+		return d.stats(ns, ks)
+	}
+	return NamespaceStats{Name: ns, Keys: map[domain.ValueType]int{}, Quota: d.defaultQuota}
+}
+
+// SetDefaultTTL makes keys written to ns without a TTL expire after ttl
+// instead of the server default; zero removes the override.
+func (d *Data) SetDefaultTTL(ns string, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.space(ns).defaultTTL = ttl
+	d.prune(ns)
+}
+
+// DefaultTTL returns the default TTL override of ns, if any.
+func (d *Data) DefaultTTL(ns string) (time.Duration, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if ks, ok := d.spaces[ns]; ok && ks.defaultTTL != 0 {
+		return ks.defaultTTL, true
+	}
+	return 0, false
+}
+
+// SetQuota overrides the limits of ns that q sets; its zero limits fall
+// back to the default quota. Existing keys are kept even if they exceed
+// the new limits.
+func (d *Data) SetQuota(ns string, q Quota) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.space(ns).quota = q
+	d.prune(ns)
+}
+
+// SetDefaultQuota sets the limits of every namespace that does not
+// override them.
+func (d *Data) SetDefaultQuota(q Quota) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.defaultQuota = q
+}
+
+// QuotaOverride returns the limits ns sets itself, without the default
+// quota.
+func (d *Data) QuotaOverride(ns string) Quota {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if ks, ok := d.spaces[ns]; ok {
+		return ks.quota
+	}
+	return Quota{}
+}
+
+// stats snapshots ks as namespace ns. The caller holds mu.
+func (d *Data) stats(ns string, ks *keyspace) NamespaceStats {
+	keys := make(map[domain.ValueType]int, len(ks.keys))
+	for typ, n := range ks.keys {
+		keys[typ] = n
+	}
+	return NamespaceStats{Name: ns, Keys: keys, Bytes: ks.bytes, DefaultTTL: ks.defaultTTL, Quota: ks.quota.or(d.defaultQuota)}
+}
+
+// entryOverhead approximates the map slot, Entry struct and slice
+// headers that every key costs on top of its bytes.
+const entryOverhead = 128
+
+// entrySize approximates the memory held by key and entry.
+func entrySize(key string, e *domain.Entry) int64 {
+	size := int64(entryOverhead + len(key) + len(e.Str) + len(e.ContentType))
+	for _, item := range e.Items {
+		size += int64(16 + len(item))
+	}
+	return size
+}
+
 // ShutDownInvalidation stops the background cleanup goroutine.
-// Call this during graceful shutdown.
+// Call this during graceful shutdown; later calls do nothing.
 func (d *Data) ShutDownInvalidation() {
-	close(d.stop)
+	d.stopOnce.Do(func() { close(d.stop) })
 }
@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"data_storage/server/domain"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+)
+
+// snapshotVersion is bumped whenever the file layout changes
+// incompatibly. Version 1 predates namespaces; its entries load into
+// the default namespace.
+const snapshotVersion = 2
+
+// snapshotFile is the on-disk form of the repository.
+type snapshotFile struct {
+	Version    int                          `json:"version"`
+	Taken      time.Time                    `json:"taken"`
+	Namespaces map[string]snapshotNamespace `json:"namespaces,omitempty"`
+	Entries    map[string]snapshotEntry     `json:"entries,omitempty"` // version 1
+}
+
+type snapshotNamespace struct {
+	DefaultTTL time.Duration            `json:"default_ttl,omitempty"`
+	Quota      *snapshotQuota           `json:"quota,omitempty"`
+	Entries    map[string]snapshotEntry `json:"entries"`
+}
+
+type snapshotQuota struct {
+	MaxKeys       int   `json:"max_keys,omitempty"`
+	MaxBytes      int64 `json:"max_bytes,omitempty"`
+	MaxListLength int   `json:"max_list_length,omitempty"`
+}
+
+type snapshotEntry struct {
+	Type   domain.ValueType `json:"type"`
+	Str    string           `json:"str,omitempty"`
+	Items  []string         `json:"items,omitempty"`
+	Expiry time.Time        `json:"expiry"` // zero never expires
+	Flags  uint32           `json:"flags,omitempty"`
+
+	// Raw holds Str instead when it is not valid UTF-8, which JSON
+	// strings cannot carry; RawItems does the same for the items, by
+	// index, leaving those slots of Items empty.
+	Raw         []byte         `json:"raw,omitempty"`
+	RawItems    map[int][]byte `json:"raw_items,omitempty"`
+	ContentType string         `json:"content_type,omitempty"`
+}
+
+// SaveSnapshot writes every live entry and namespace setting to path.
+// Values are saved decoded. The file is written next to path and
+// renamed over it, so a crash mid-write leaves the previous snapshot
+// intact. It returns the number of keys saved.
+func (d *Data) SaveSnapshot(path string) (int, error) {
+	now := time.Now()
+	snap := snapshotFile{Version: snapshotVersion, Taken: now}
+
+	saved := 0
+	d.mu.RLock()
+	snap.Namespaces = make(map[string]snapshotNamespace, len(d.spaces))
+	for ns, ks := range d.spaces {
+		space := snapshotNamespace{DefaultTTL: ks.defaultTTL, Entries: make(map[string]snapshotEntry, len(ks.data))}
+		if ks.quota != (Quota{}) {
+			q := snapshotQuota(ks.quota)
+			space.Quota = &q
+		}
+		for k, stored := range ks.data {
+			if !stored.Expiry.IsZero() && !now.Before(stored.Expiry) {
+				continue
+			}
+			e, err := unpack(stored)
+			if err != nil {
+				d.mu.RUnlock()
+				return 0, fmt.Errorf("snapshot %q: %w", k, err)
+			}
+			se := snapshotEntry{
+				Type:        e.Type,
+				Str:         e.Str,
+				Items:       append([]string(nil), e.Items...),
+				Expiry:      e.Expiry,
+				Flags:       e.Flags,
+				ContentType: e.ContentType,
+			}
+			if !utf8.ValidString(e.Str) {
+				se.Str, se.Raw = "", []byte(e.Str)
+			}
+			for i, item := range se.Items {
+				if utf8.ValidString(item) {
+					continue
+				}
+				if se.RawItems == nil {
+					se.RawItems = make(map[int][]byte)
+				}
+				se.Items[i], se.RawItems[i] = "", []byte(item)
+			}
+			space.Entries[k] = se
+		}
+		snap.Namespaces[ns] = space
+		saved += len(space.Entries)
+	}
+	d.mu.RUnlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if err := json.NewEncoder(tmp).Encode(snap); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return saved, nil
+}
+
+// LoadSnapshot stores the entries and namespace settings saved at path,
+// skipping entries that expired in the meantime, and returns how many
+// were loaded. Values are compressed as set by SetCompression. A missing
+// file loads nothing. No keyspace events are emitted, so call it before
+// serving.
+func (d *Data) LoadSnapshot(path string) (int, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var snap snapshotFile
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return 0, fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	switch snap.Version {
+	case 1:
+		snap.Namespaces = map[string]snapshotNamespace{domain.DefaultNamespace: {Entries: snap.Entries}}
+	case snapshotVersion:
+	default:
+		return 0, fmt.Errorf("snapshot %s: unsupported version %d", path, snap.Version)
+	}
+
+	now := time.Now()
+	loaded := 0
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for ns, space := range snap.Namespaces {
+		ks := d.space(ns)
+		ks.defaultTTL = space.DefaultTTL
+		if space.Quota != nil {
+			ks.quota = Quota(*space.Quota)
+		}
+		for k, e := range space.Entries {
+			if !e.Expiry.IsZero() && !now.Before(e.Expiry) {
+				continue
+			}
+			if e.Raw != nil {
+				e.Str = string(e.Raw)
+			}
+			for i, raw := range e.RawItems {
+				if i < 0 || i >= len(e.Items) {
+					return loaded, fmt.Errorf("snapshot %s: %q: raw item %d out of range", path, k, i)
+				}
+				e.Items[i] = string(raw)
+			}
+			d.cas++
+			packed, saved := d.pack(&domain.Entry{
+				Type:        e.Type,
+				Str:         e.Str,
+				Items:       e.Items,
+				Expiry:      e.Expiry,
+				Flags:       e.Flags,
+				CAS:         d.cas,
+				ContentType: e.ContentType,
+			})
+			d.store(ks, k, packed, saved)
+			loaded++
+		}
+		d.prune(ns)
+	}
+	return loaded, nil
+}
@@ -0,0 +1,137 @@
+package store_service
+
+import (
+	"context"
+	"data_storage/server/audit"
+	"data_storage/server/auth"
+	domain2 "data_storage/server/domain"
+	"encoding/json"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// audited records every mutation that reaches the service in an audit
+// log. Reads pass straight through.
+type audited struct {
+	StoreServiceRepo
+	log       *audit.Logger
+	transport string
+}
+
+// Audited returns s with its mutations recorded in l as made over
+// transport, e.g. "resp", or s itself if l is nil. The caller comes
+// from the context (auth.WithIdentity, audit.WithRemoteAddr). HTTP and
+// WebSocket requests are audited by their middleware instead, which
+// also records the requests authorization turns away.
+func Audited(s StoreServiceRepo, l *audit.Logger, transport string) StoreServiceRepo {
+	if l == nil {
+		return s
+	}
+	return &audited{StoreServiceRepo: s, log: l, transport: transport}
+}
+
+// begin starts recording op on key; call the returned func with the
+// call's error once it returns.
+func (a *audited) begin(ctx context.Context, op, key, value string) func(error) {
+	start := time.Now()
+	return func(err error) {
+		outcome := audit.OutcomeOK
+		if err != nil {
+			outcome = audit.OutcomeFailed
+		}
+		var identity string
+		if id, ok := auth.FromContext(ctx); ok {
+			identity = id.Name
+		}
+		a.log.Log(audit.Record{
+			Time:       start,
+			Identity:   identity,
+			RemoteAddr: audit.RemoteAddr(ctx),
+			Transport:  a.transport,
+			Operation:  op,
+			Namespace:  domain2.NamespaceFrom(ctx),
+			Key:        key,
+			Value:      value,
+			Outcome:    outcome,
+			LatencyMS:  float64(time.Since(start).Microseconds()) / 1000,
+		})
+	}
+}
+
+func (a *audited) SetString(ctx context.Context, key string, value string, ttl time.Duration) (err error) {
+	done := a.begin(ctx, "set", key, value)
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.SetString(ctx, key, value, ttl)
+}
+
+func (a *audited) DeleteString(ctx context.Context, key string) (err error) {
+	done := a.begin(ctx, "del", key, "")
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.DeleteString(ctx, key)
+}
+
+func (a *audited) StoreString(ctx context.Context, key string, w domain2.StringWrite) (_ uint64, err error) {
+	done := a.begin(ctx, "store", key, w.Value)
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.StoreString(ctx, key, w)
+}
+
+func (a *audited) SetBlob(ctx context.Context, key string, data []byte, contentType, encoding string, ttl time.Duration) (err error) {
+	// binary blobs would garble the log, and the copy is only worth
+	// making if the log keeps values
+	value := audit.Redacted
+	if a.log.IncludesValues() && encoding == "" && utf8.Valid(data) {
+		value = string(data)
+	}
+	done := a.begin(ctx, "set", key, value)
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.SetBlob(ctx, key, data, contentType, encoding, ttl)
+}
+
+func (a *audited) Incr(ctx context.Context, key string, delta uint64) (_ uint64, err error) {
+	done := a.begin(ctx, "incr", key, strconv.FormatUint(delta, 10))
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.Incr(ctx, key, delta)
+}
+
+func (a *audited) Decr(ctx context.Context, key string, delta uint64) (_ uint64, err error) {
+	done := a.begin(ctx, "decr", key, strconv.FormatUint(delta, 10))
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.Decr(ctx, key, delta)
+}
+
+func (a *audited) LPush(ctx context.Context, key string, items ...string) (err error) {
+	value := audit.Redacted
+	if a.log.IncludesValues() {
+		items, _ := json.Marshal(items)
+		value = string(items)
+	}
+	done := a.begin(ctx, "lpush", key, value)
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.LPush(ctx, key, items...)
+}
+
+func (a *audited) RPop(ctx context.Context, key string) (_ string, err error) {
+	done := a.begin(ctx, "rpop", key, "")
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.RPop(ctx, key)
+}
+
+func (a *audited) Expire(ctx context.Context, key string, ttl time.Duration) (err error) {
+	done := a.begin(ctx, "expire", key, "")
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.Expire(ctx, key, ttl)
+}
+
+func (a *audited) Touch(ctx context.Context, key string, expiry time.Time) (err error) {
+	done := a.begin(ctx, "touch", key, "")
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.Touch(ctx, key, expiry)
+}
+
+func (a *audited) FlushAll(ctx context.Context) (err error) {
+	done := a.begin(ctx, "flush_all", "", "")
+	defer func() { done(err) }()
+	return a.StoreServiceRepo.FlushAll(ctx)
+}
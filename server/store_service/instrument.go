@@ -0,0 +1,204 @@
+package store_service
+
+import (
+	"context"
+	"data_storage/server/auth"
+	domain2 "data_storage/server/domain"
+	"data_storage/server/monitor"
+	"data_storage/server/slowlog"
+	"data_storage/server/tracing"
+	"time"
+)
+
+// instrumented wraps every StoreServiceRepo call in a span named
+// "StoreService.<Method>", reports calls slower than the slow log's
+// threshold and publishes every call to connected monitors.
+type instrumented struct {
+	next    StoreServiceRepo
+	slow    *slowlog.Log
+	monitor *monitor.Hub
+}
+
+// call is one StoreServiceRepo invocation being observed.
+type call struct {
+	svc     *instrumented
+	command string
+	key     string
+	args    []string
+	bytes   int  // size of the values passed, reported to the slow log
+	sized   bool // bytes is set; otherwise it is the size of args
+	span    *tracing.Span
+	ctx     context.Context
+	start   time.Time
+}
+
+// begin starts observing command on key with the given value arguments.
+// Nothing is built for the span, slow log or monitors until they are
+// known to want it.
+func (s *instrumented) begin(ctx context.Context, command, key string, args ...string) (context.Context, *call) {
+	var span *tracing.Span
+	if tracing.Enabled() {
+		ctx, span = tracing.Start(ctx, "StoreService."+command)
+		if key != "" {
+			span.SetAttr("key", key)
+		}
+	}
+	return ctx, &call{svc: s, command: command, key: key, args: args, span: span, ctx: ctx, start: time.Now()}
+}
+
+// done finishes the call with its outcome.
+func (c *call) done(err error) {
+	took := time.Since(c.start)
+	c.span.RecordError(err)
+	c.span.End()
+
+	slow := c.svc.slow != nil && took >= c.svc.slow.Threshold()
+	monitored := c.svc.monitor != nil && c.svc.monitor.Active()
+	if !slow && !monitored {
+		return
+	}
+
+	var identity, errMsg string
+	if id, ok := auth.FromContext(c.ctx); ok {
+		identity = id.Name
+	}
+	ns := domain2.NamespaceFrom(c.ctx)
+	if err != nil {
+		errMsg = err.Error()
+	}
+	if slow {
+		if !c.sized {
+			for _, a := range c.args {
+				c.bytes += len(a)
+			}
+		}
+		e := slowlog.Entry{Time: c.start, Command: c.command, Namespace: ns, Key: c.key, ArgCount: len(c.args), ArgBytes: c.bytes, Identity: identity, Error: errMsg}
+		c.svc.slow.Observe(e, took)
+	}
+	if monitored {
+		c.svc.monitor.Publish(monitor.Event{
+			Time:       c.start,
+			Command:    c.command,
+			Namespace:  ns,
+			Key:        c.key,
+			Args:       monitor.TruncateArgs(c.args),
+			ArgCount:   len(c.args),
+			Identity:   identity,
+			DurationMS: float64(took.Microseconds()) / 1000,
+			Error:      errMsg,
+		})
+	}
+}
+
+func (s *instrumented) SetString(ctx context.Context, key string, data string, ttl time.Duration) (err error) {
+	ctx, c := s.begin(ctx, "SetString", key, data)
+	defer func() { c.done(err) }()
+	return s.next.SetString(ctx, key, data, ttl)
+}
+
+func (s *instrumented) GetString(ctx context.Context, key string) (_ string, err error) {
+	ctx, c := s.begin(ctx, "GetString", key)
+	defer func() { c.done(err) }()
+	return s.next.GetString(ctx, key)
+}
+
+func (s *instrumented) DeleteString(ctx context.Context, key string) (err error) {
+	ctx, c := s.begin(ctx, "DeleteString", key)
+	defer func() { c.done(err) }()
+	return s.next.DeleteString(ctx, key)
+}
+
+func (s *instrumented) StoreString(ctx context.Context, key string, w domain2.StringWrite) (_ uint64, err error) {
+	ctx, c := s.begin(ctx, "StoreString", key, w.Value)
+	defer func() { c.done(err) }()
+	return s.next.StoreString(ctx, key, w)
+}
+
+func (s *instrumented) GetStringEntry(ctx context.Context, key string) (_ *domain2.Entry, err error) {
+	ctx, c := s.begin(ctx, "GetStringEntry", key)
+	defer func() { c.done(err) }()
+	return s.next.GetStringEntry(ctx, key)
+}
+
+func (s *instrumented) SetBlob(ctx context.Context, key string, data []byte, contentType, encoding string, ttl time.Duration) (err error) {
+	// the data may be binary, so monitors only see its media type and
+	// the slow log only its size
+	ctx, c := s.begin(ctx, "SetBlob", key, contentType)
+	c.bytes, c.sized = len(data), true
+	defer func() { c.done(err) }()
+	return s.next.SetBlob(ctx, key, data, contentType, encoding, ttl)
+}
+
+func (s *instrumented) GetBlob(ctx context.Context, key string) (_ *domain2.Entry, err error) {
+	ctx, c := s.begin(ctx, "GetBlob", key)
+	defer func() { c.done(err) }()
+	return s.next.GetBlob(ctx, key)
+}
+
+func (s *instrumented) GetBlobEncoded(ctx context.Context, key string) (_ *domain2.Entry, err error) {
+	ctx, c := s.begin(ctx, "GetBlobEncoded", key)
+	defer func() { c.done(err) }()
+	return s.next.GetBlobEncoded(ctx, key)
+}
+
+func (s *instrumented) Incr(ctx context.Context, key string, delta uint64) (_ uint64, err error) {
+	ctx, c := s.begin(ctx, "Incr", key)
+	defer func() { c.done(err) }()
+	return s.next.Incr(ctx, key, delta)
+}
+
+func (s *instrumented) Decr(ctx context.Context, key string, delta uint64) (_ uint64, err error) {
+	ctx, c := s.begin(ctx, "Decr", key)
+	defer func() { c.done(err) }()
+	return s.next.Decr(ctx, key, delta)
+}
+
+func (s *instrumented) LPush(ctx context.Context, key string, items ...string) (err error) {
+	ctx, c := s.begin(ctx, "LPush", key, items...)
+	defer func() { c.done(err) }()
+	return s.next.LPush(ctx, key, items...)
+}
+
+func (s *instrumented) RPop(ctx context.Context, key string) (_ string, err error) {
+	ctx, c := s.begin(ctx, "RPop", key)
+	defer func() { c.done(err) }()
+	return s.next.RPop(ctx, key)
+}
+
+func (s *instrumented) LLen(ctx context.Context, key string) (_ int, err error) {
+	ctx, c := s.begin(ctx, "LLen", key)
+	defer func() { c.done(err) }()
+	return s.next.LLen(ctx, key)
+}
+
+func (s *instrumented) Expire(ctx context.Context, key string, ttl time.Duration) (err error) {
+	ctx, c := s.begin(ctx, "Expire", key)
+	defer func() { c.done(err) }()
+	return s.next.Expire(ctx, key, ttl)
+}
+
+func (s *instrumented) TTL(ctx context.Context, key string) (_ time.Duration, err error) {
+	ctx, c := s.begin(ctx, "TTL", key)
+	defer func() { c.done(err) }()
+	return s.next.TTL(ctx, key)
+}
+
+func (s *instrumented) Touch(ctx context.Context, key string, expiry time.Time) (err error) {
+	ctx, c := s.begin(ctx, "Touch", key)
+	defer func() { c.done(err) }()
+	return s.next.Touch(ctx, key, expiry)
+}
+
+func (s *instrumented) FlushAll(ctx context.Context) (err error) {
+	ctx, c := s.begin(ctx, "FlushAll", "")
+	defer func() { c.done(err) }()
+	return s.next.FlushAll(ctx)
+}
+
+// Watch observes only the subscription, not the stream that follows.
+func (s *instrumented) Watch(ctx context.Context, pattern string, afterID uint64) (_ <-chan domain2.Event, err error) {
+	_, c := s.begin(ctx, "Watch", "", pattern)
+	c.span.SetAttr("pattern", pattern)
+	defer func() { c.done(err) }()
+	return s.next.Watch(ctx, pattern, afterID)
+}
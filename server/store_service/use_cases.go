@@ -1,10 +1,18 @@
 package store_service
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	domain2 "data_storage/server/domain"
+	"data_storage/server/monitor"
+	"data_storage/server/slowlog"
 	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,22 +22,145 @@ type StoreServiceRepo interface {
 	GetString(ctx context.Context, key string) (string, error)
 	DeleteString(ctx context.Context, key string) error
 
+	StoreString(ctx context.Context, key string, w domain2.StringWrite) (uint64, error)
+	GetStringEntry(ctx context.Context, key string) (*domain2.Entry, error)
+	SetBlob(ctx context.Context, key string, data []byte, contentType, encoding string, ttl time.Duration) error
+	GetBlob(ctx context.Context, key string) (*domain2.Entry, error)
+	GetBlobEncoded(ctx context.Context, key string) (*domain2.Entry, error)
+	Incr(ctx context.Context, key string, delta uint64) (uint64, error)
+	Decr(ctx context.Context, key string, delta uint64) (uint64, error)
+
 	LPush(ctx context.Context, key string, items ...string) error
 	RPop(ctx context.Context, key string) (string, error)
+	LLen(ctx context.Context, key string) (int, error)
+
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+	TTL(ctx context.Context, key string) (time.Duration, error)
+	Touch(ctx context.Context, key string, expiry time.Time) error
+	FlushAll(ctx context.Context) error
+
+	Watch(ctx context.Context, pattern string, afterID uint64) (<-chan domain2.Event, error)
+}
+
+// MutationNotifier is told about every successful mutation.
+// Notify must not block the calling request.
+type MutationNotifier interface {
+	Notify(m domain2.Mutation)
+}
+
+// DefaultTTLs overrides the service's default TTL per namespace, e.g.
+// *storage.Data.
+type DefaultTTLs interface {
+	DefaultTTL(namespace string) (time.Duration, bool)
+}
+
+// EncodedReader returns entries with compressed values left compressed,
+// e.g. *storage.Data.
+type EncodedReader interface {
+	GetEncoded(ctx context.Context, key string) (*domain2.Entry, error)
+}
+
+// Option customises a StoreService.
+type Option func(*StoreService)
+
+// WithNotifier registers a MutationNotifier, e.g. the webhook dispatcher.
+func WithNotifier(n MutationNotifier) Option {
+	return func(s *StoreService) {
+		s.notifiers = append(s.notifiers, n)
+	}
+}
+
+// WithSlowLog records calls that take at least l's threshold in l.
+func WithSlowLog(l *slowlog.Log) Option {
+	return func(s *StoreService) {
+		s.slowlog = l
+	}
+}
+
+// WithNamespaceTTLs lets t override the default TTL of keys written to a
+// namespace without a TTL.
+func WithNamespaceTTLs(t DefaultTTLs) Option {
+	return func(s *StoreService) {
+		s.ttls = t
+	}
+}
+
+// WithEncodedReads serves GetBlobEncoded from r; without it blobs are
+// always read decoded.
+func WithEncodedReads(r EncodedReader) Option {
+	return func(s *StoreService) {
+		s.encoded = r
+	}
+}
+
+// WithLimits rejects keys, values and list pushes over l with the
+// matching domain error.
+func WithLimits(l domain2.Limits) Option {
+	return func(s *StoreService) {
+		s.limits = l
+	}
+}
+
+// WithMonitor publishes every call to h's subscribers.
+func WithMonitor(h *monitor.Hub) Option {
+	return func(s *StoreService) {
+		s.monitor = h
+	}
 }
 
 // StoreService implements business logic.
 type StoreService struct {
 	domainRepo domain2.EntryRepository
 	defaultTTL time.Duration
+	ttls       DefaultTTLs
+	encoded    EncodedReader
+	limits     domain2.Limits
+	notifiers  []MutationNotifier
+	slowlog    *slowlog.Log
+	monitor    *monitor.Hub
 }
 
-// NewStoreService wires repo + default TTL.
-func NewStoreService(d domain2.EntryRepository, defaultTTL time.Duration) StoreServiceRepo {
-	return &StoreService{
+// NewStoreService wires repo + default TTL. Calls are traced once a
+// tracing exporter is installed, slow calls go to the slow log and all
+// calls to connected monitors.
+func NewStoreService(d domain2.EntryRepository, defaultTTL time.Duration, opts ...Option) StoreServiceRepo {
+	s := &StoreService{
 		domainRepo: d,
 		defaultTTL: defaultTTL,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return &instrumented{next: s, slow: s.slowlog, monitor: s.monitor}
+}
+
+// ttlFor returns the default TTL of the context's namespace.
+func (s *StoreService) ttlFor(ctx context.Context) time.Duration {
+	if s.ttls != nil {
+		if ttl, ok := s.ttls.DefaultTTL(domain2.NamespaceFrom(ctx)); ok {
+			return ttl
+		}
+	}
+	return s.defaultTTL
+}
+
+// checkString applies the key and value limits to a string write.
+func (s *StoreService) checkString(key, value string) error {
+	if err := s.limits.CheckKey(key); err != nil {
+		return err
+	}
+	return s.limits.CheckValue(value)
+}
+
+// notify reports a successful mutation to all registered notifiers.
+func (s *StoreService) notify(ctx context.Context, op domain2.Operation, key string) {
+	if len(s.notifiers) == 0 {
+		return
+	}
+	m := domain2.Mutation{Op: op, Namespace: domain2.NamespaceFrom(ctx), Key: key, Time: time.Now()}
+	for _, n := range s.notifiers {
+		n.Notify(m)
+	}
 }
 
 // SetString validates inputs and stores a string.
@@ -43,11 +174,14 @@ func (s *StoreService) SetString(ctx context.Context, key string, value string,
 	if value == "" {
 		return fmt.Errorf("SetString %q: %w", key, domain2.ErrEmptyValue)
 	}
+	if err := s.checkString(key, value); err != nil {
+		return fmt.Errorf("SetString %q: %w", key, err)
+	}
 
 	expiry := ttl
 
 	if ttl == 0 {
-		expiry = s.defaultTTL
+		expiry = s.ttlFor(ctx)
 	}
 
 	entry := domain2.NewStringEntry(value, expiry)
@@ -56,6 +190,7 @@ func (s *StoreService) SetString(ctx context.Context, key string, value string,
 		return fmt.Errorf("SetString %q: %w", key, err)
 	}
 
+	s.notify(ctx, domain2.OpSet, key)
 	return nil
 }
 
@@ -86,30 +221,275 @@ func (s *StoreService) DeleteString(ctx context.Context, key string) error {
 		return fmt.Errorf("DeleteString: %q: %w", key, err)
 	}
 
+	s.notify(ctx, domain2.OpDel, key)
+	return nil
+}
+
+// StoreString performs a conditional string write (set, add, replace or
+// cas semantics depending on w.Mode) and returns the entry's new CAS.
+func (s *StoreService) StoreString(ctx context.Context, key string, w domain2.StringWrite) (uint64, error) {
+	if key == "" {
+		return 0, fmt.Errorf("StoreString: %q: %w", key, domain2.ErrEmptyKey)
+	}
+	if w.Value == "" {
+		return 0, fmt.Errorf("StoreString %q: %w", key, domain2.ErrEmptyValue)
+	}
+	if err := s.checkString(key, w.Value); err != nil {
+		return 0, fmt.Errorf("StoreString %q: %w", key, err)
+	}
+
+	var stored *domain2.Entry
+	err := s.domainRepo.Update(ctx, key, func(current *domain2.Entry) (*domain2.Entry, error) {
+		switch w.Mode {
+		case domain2.SetIfAbsent:
+			if current != nil {
+				return nil, domain2.ErrKeyExists
+			}
+		case domain2.SetIfPresent:
+			if current == nil {
+				return nil, domain2.ErrNotFound
+			}
+		case domain2.SetIfCAS:
+			if current == nil {
+				return nil, domain2.ErrNotFound
+			}
+			if current.CAS != w.CAS {
+				return nil, domain2.ErrCASMismatch
+			}
+		}
+		stored = &domain2.Entry{Type: domain2.TypeString, Str: w.Value, Flags: w.Flags, Expiry: w.Expiry}
+		return stored, nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("StoreString: %q: %w", key, err)
+	}
+
+	s.notify(ctx, domain2.OpSet, key)
+	// the repository stamps the CAS on the stored entry
+	return stored.CAS, nil
+}
+
+// GetStringEntry returns the string entry including flags and CAS.
+func (s *StoreService) GetStringEntry(ctx context.Context, key string) (*domain2.Entry, error) {
+	if key == "" {
+		return nil, fmt.Errorf("GetStringEntry: %q: %w", key, domain2.ErrEmptyKey)
+	}
+	entry, err := s.domainRepo.Get(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("GetStringEntry %q: %w", key, err)
+	}
+	if entry.Type != domain2.TypeString {
+		return nil, fmt.Errorf("GetStringEntry: %q: %w", key, domain2.ErrWrongType)
+	}
+
+	clone := *entry
+	return &clone, nil
+}
+
+// SetBlob stores data as a string with its media type, which defaults
+// to application/octet-stream. An encoding of "gzip" stores the
+// compressed data as is, after checking that it decodes within the
+// value limit; "" and "identity" mean data is not encoded.
+func (s *StoreService) SetBlob(ctx context.Context, key string, data []byte, contentType, encoding string, ttl time.Duration) error {
+	if key == "" {
+		return fmt.Errorf("SetBlob: %q: %w", key, domain2.ErrEmptyKey)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("SetBlob %q: %w", key, domain2.ErrEmptyValue)
+	}
+	if contentType == "" {
+		contentType = domain2.DefaultContentType
+	} else if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return fmt.Errorf("SetBlob %q: %w: %q", key, domain2.ErrInvalidContentType, contentType)
+	}
+	value := string(data)
+	switch strings.ToLower(encoding) {
+	case "", "identity":
+		encoding = ""
+		if err := s.checkString(key, value); err != nil {
+			return fmt.Errorf("SetBlob %q: %w", key, err)
+		}
+	case domain2.EncodingGzip:
+		encoding = domain2.EncodingGzip
+		if err := s.limits.CheckKey(key); err != nil {
+			return fmt.Errorf("SetBlob %q: %w", key, err)
+		}
+		if err := s.checkGzip(data); err != nil {
+			return fmt.Errorf("SetBlob %q: %w", key, err)
+		}
+	default:
+		return fmt.Errorf("SetBlob %q: %w: %q", key, domain2.ErrUnsupportedEncoding, encoding)
+	}
+
+	if ttl == 0 {
+		ttl = s.ttlFor(ctx)
+	}
+	entry := domain2.NewStringEntry(value, ttl)
+	entry.ContentType = contentType
+	entry.Encoding = encoding
+	if err := s.domainRepo.Set(ctx, key, entry); err != nil {
+		return fmt.Errorf("SetBlob %q: %w", key, err)
+	}
+
+	s.notify(ctx, domain2.OpSet, key)
+	return nil
+}
+
+// GetBlob returns the string entry at key with its media type; plain
+// strings report text/plain.
+func (s *StoreService) GetBlob(ctx context.Context, key string) (*domain2.Entry, error) {
+	entry, err := s.GetStringEntry(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlob: %w", err)
+	}
+	if entry.ContentType == "" {
+		entry.ContentType = "text/plain; charset=utf-8"
+	}
+	return entry, nil
+}
+
+// GetBlobEncoded is GetBlob with the value as stored: entry.Gzipped
+// reports whether Str is gzip-compressed.
+func (s *StoreService) GetBlobEncoded(ctx context.Context, key string) (*domain2.Entry, error) {
+	if s.encoded == nil {
+		return s.GetBlob(ctx, key)
+	}
+	if key == "" {
+		return nil, fmt.Errorf("GetBlobEncoded: %q: %w", key, domain2.ErrEmptyKey)
+	}
+	entry, err := s.encoded.GetEncoded(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("GetBlobEncoded %q: %w", key, err)
+	}
+	if entry.Type != domain2.TypeString {
+		return nil, fmt.Errorf("GetBlobEncoded: %q: %w", key, domain2.ErrWrongType)
+	}
+
+	clone := *entry
+	if clone.ContentType == "" {
+		clone.ContentType = "text/plain; charset=utf-8"
+	}
+	return &clone, nil
+}
+
+// checkGzip checks that data is a gzip stream whose decoded value is
+// neither empty nor over the value limit.
+func (s *StoreService) checkGzip(data []byte) error {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", domain2.ErrInvalidEncoding, err)
+	}
+	var src io.Reader = r
+	if s.limits.MaxValueBytes > 0 {
+		src = io.LimitReader(r, int64(s.limits.MaxValueBytes)+1)
+	}
+	n, err := io.Copy(io.Discard, src)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: %v", domain2.ErrInvalidEncoding, err)
+	case n == 0:
+		return domain2.ErrEmptyValue
+	case s.limits.MaxValueBytes > 0 && n > int64(s.limits.MaxValueBytes):
+		return fmt.Errorf("%w: decodes to more than %d bytes", domain2.ErrValueTooLarge, s.limits.MaxValueBytes)
+	}
 	return nil
 }
 
+// Incr adds delta to the unsigned decimal counter at key, wrapping
+// on overflow like memcached. The key must already exist.
+func (s *StoreService) Incr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	n, err := s.addCounter(ctx, key, func(v uint64) uint64 { return v + delta })
+	if err != nil {
+		return 0, fmt.Errorf("Incr: %q: %w", key, err)
+	}
+	return n, nil
+}
+
+// Decr subtracts delta from the unsigned decimal counter at key,
+// saturating at zero like memcached. The key must already exist.
+func (s *StoreService) Decr(ctx context.Context, key string, delta uint64) (uint64, error) {
+	n, err := s.addCounter(ctx, key, func(v uint64) uint64 {
+		if delta > v {
+			return 0
+		}
+		return v - delta
+	})
+	if err != nil {
+		return 0, fmt.Errorf("Decr: %q: %w", key, err)
+	}
+	return n, nil
+}
+
+// addCounter atomically applies op to the counter at key, keeping its
+// flags and expiry.
+func (s *StoreService) addCounter(ctx context.Context, key string, op func(uint64) uint64) (uint64, error) {
+	if key == "" {
+		return 0, domain2.ErrEmptyKey
+	}
+
+	var result uint64
+	err := s.domainRepo.Update(ctx, key, func(current *domain2.Entry) (*domain2.Entry, error) {
+		if current == nil {
+			return nil, domain2.ErrNotFound
+		}
+		if current.Type != domain2.TypeString {
+			return nil, domain2.ErrWrongType
+		}
+		v, err := strconv.ParseUint(current.Str, 10, 64)
+		if err != nil {
+			return nil, domain2.ErrNotInteger
+		}
+		result = op(v)
+		next := *current
+		next.Str = strconv.FormatUint(result, 10)
+		return &next, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.notify(ctx, domain2.OpSet, key)
+	return result, nil
+}
+
 // LPush pushes items onto list head.
 func (s *StoreService) LPush(ctx context.Context, key string, items ...string) error {
 	if key == "" {
 		return fmt.Errorf("LPush: %q: %w", key, domain2.ErrEmptyKey)
 	}
-
-	existingList, err := s.domainRepo.Get(ctx, key)
-	if errors.Is(err, domain2.ErrNotFound) {
-		existingList = domain2.NewListEntry(items, s.defaultTTL)
-	} else if err != nil {
+	if err := s.limits.CheckKey(key); err != nil {
+		return fmt.Errorf("LPush: %q: %w", key, err)
+	}
+	if err := s.limits.CheckPush(items); err != nil {
 		return fmt.Errorf("LPush: %q: %w", key, err)
-	} else {
-		if existingList.Type != domain2.TypeList {
-			return fmt.Errorf("LPush: %q: %w", key, domain2.ErrWrongType)
-		}
-		existingList.Items = append(items, existingList.Items...)
 	}
 
-	if err = s.domainRepo.Set(ctx, key, existingList); err != nil {
-		return fmt.Errorf("LPush %q: %w", key, err)
+	// the list is replaced under the repository lock, so concurrent
+	// pushes never lose each other's items
+	ttl := s.ttlFor(ctx) // looked up before Update takes the lock
+	err := s.domainRepo.Update(ctx, key, func(current *domain2.Entry) (*domain2.Entry, error) {
+		var next *domain2.Entry
+		if current == nil {
+			next = domain2.NewListEntry(items, ttl)
+		} else {
+			if current.Type != domain2.TypeList {
+				return nil, domain2.ErrWrongType
+			}
+			grown := *current
+			grown.Items = make([]string, 0, len(items)+len(current.Items))
+			grown.Items = append(append(grown.Items, items...), current.Items...)
+			next = &grown
+		}
+		if err := s.limits.CheckListLength(len(next.Items)); err != nil {
+			return nil, err
+		}
+		return next, nil
+	})
+	if err != nil {
+		return fmt.Errorf("LPush: %q: %w", key, err)
 	}
+
+	s.notify(ctx, domain2.OpLPush, key)
 	return nil
 }
 
@@ -119,27 +499,142 @@ func (s *StoreService) RPop(ctx context.Context, key string) (string, error) {
 		return "", fmt.Errorf("RPop: %q: %w", key, domain2.ErrEmptyKey)
 	}
 
+	var value string
+	err := s.domainRepo.Update(ctx, key, func(current *domain2.Entry) (*domain2.Entry, error) {
+		if current == nil {
+			return nil, domain2.ErrNotFound
+		}
+		if current.Type != domain2.TypeList {
+			return nil, domain2.ErrWrongType
+		}
+		n := len(current.Items)
+		if n == 0 {
+			return nil, domain2.ErrEmptyEntry
+		}
+		value = current.Items[n-1]
+		// copied, as readers may still hold the stored slice
+		next := *current
+		next.Items = append([]string(nil), current.Items[:n-1]...)
+		return &next, nil
+	})
+	if errors.Is(err, domain2.ErrEmptyEntry) {
+		return "", err
+	} else if err != nil {
+		return "", fmt.Errorf("RPop: %q: %w", key, err)
+	}
+
+	s.notify(ctx, domain2.OpRPop, key)
+	return value, nil
+
+}
+
+// LLen returns the length of the list at key; a missing key has length 0.
+func (s *StoreService) LLen(ctx context.Context, key string) (int, error) {
+	if key == "" {
+		return 0, fmt.Errorf("LLen: %q: %w", key, domain2.ErrEmptyKey)
+	}
+
 	existingList, err := s.domainRepo.Get(ctx, key)
-	if err != nil {
-		return "", fmt.Errorf("RPop %q: %w", key, err)
+	if errors.Is(err, domain2.ErrNotFound) || errors.Is(err, domain2.ErrExpiredEntry) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("LLen: %q: %w", key, err)
 	}
 
 	if existingList.Type != domain2.TypeList {
-		return "", fmt.Errorf("RPop: %q: %w", key, domain2.ErrWrongType)
+		return 0, fmt.Errorf("LLen: %q: %w", key, domain2.ErrWrongType)
 	}
+	return len(existingList.Items), nil
+}
 
-	n := len(existingList.Items)
-	if n == 0 {
-		return "", domain2.ErrEmptyEntry
+// Expire resets the TTL of an existing key of any type.
+// A non-positive ttl deletes the key, mirroring Redis EXPIRE.
+func (s *StoreService) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	if key == "" {
+		return fmt.Errorf("Expire: %q: %w", key, domain2.ErrEmptyKey)
 	}
 
-	value := existingList.Items[n-1]
-	existingList.Items = existingList.Items[:n-1]
+	// a non-positive ttl makes fn return nil, which removes the key
+	err := s.domainRepo.Update(ctx, key, func(current *domain2.Entry) (*domain2.Entry, error) {
+		if current == nil {
+			return nil, domain2.ErrNotFound
+		}
+		if ttl <= 0 {
+			return nil, nil
+		}
+		next := *current
+		next.Expiry = time.Now().Add(ttl)
+		return &next, nil
+	})
+	if err != nil {
+		return fmt.Errorf("Expire: %q: %w", key, err)
+	}
 
-	if err = s.domainRepo.Set(ctx, key, existingList); err != nil {
-		return "", fmt.Errorf("RPop: %q: %w", key, err)
+	if ttl <= 0 {
+		s.notify(ctx, domain2.OpDel, key)
+		return nil
 	}
+	s.notify(ctx, domain2.OpExpire, key)
+	return nil
+}
 
-	return value, nil
+// TTL returns the remaining lifetime of key, or a negative
+// duration if the key never expires.
+func (s *StoreService) TTL(ctx context.Context, key string) (time.Duration, error) {
+	if key == "" {
+		return 0, fmt.Errorf("TTL: %q: %w", key, domain2.ErrEmptyKey)
+	}
+
+	entry, err := s.domainRepo.Get(ctx, key)
+	if err != nil {
+		return 0, fmt.Errorf("TTL: %q: %w", key, err)
+	}
+
+	if entry.Expiry.IsZero() {
+		return -1, nil
+	}
+	return time.Until(entry.Expiry), nil
+}
 
+// Touch sets an absolute expiry on an existing key; a zero expiry
+// makes the key persistent.
+func (s *StoreService) Touch(ctx context.Context, key string, expiry time.Time) error {
+	if key == "" {
+		return fmt.Errorf("Touch: %q: %w", key, domain2.ErrEmptyKey)
+	}
+
+	err := s.domainRepo.Update(ctx, key, func(current *domain2.Entry) (*domain2.Entry, error) {
+		if current == nil {
+			return nil, domain2.ErrNotFound
+		}
+		next := *current
+		next.Expiry = expiry
+		return &next, nil
+	})
+	if err != nil {
+		return fmt.Errorf("Touch: %q: %w", key, err)
+	}
+
+	s.notify(ctx, domain2.OpExpire, key)
+	return nil
+}
+
+// FlushAll removes every key of the context's namespace.
+func (s *StoreService) FlushAll(ctx context.Context) error {
+	if err := s.domainRepo.Flush(ctx); err != nil {
+		return fmt.Errorf("FlushAll: %w", err)
+	}
+
+	s.notify(ctx, domain2.OpFlush, "")
+	return nil
+}
+
+// Watch streams keyspace events for keys matching pattern,
+// resuming after afterID when the event is still buffered.
+func (s *StoreService) Watch(ctx context.Context, pattern string, afterID uint64) (<-chan domain2.Event, error) {
+	events, err := s.domainRepo.Watch(ctx, pattern, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("Watch: %q: %w", pattern, err)
+	}
+	return events, nil
 }
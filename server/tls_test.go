@@ -0,0 +1,273 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/adapters/grpcapi"
+	"data_storage/server/auth"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+	"data_storage/server/tlsconfig"
+)
+
+// testCert issues a certificate signed by parent (self-signed if nil)
+// and writes it and its key as PEM files into dir.
+func testCert(t *testing.T, dir, name string, serial int64, tmpl *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	tmpl.SerialNumber = big.NewInt(serial)
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	os.WriteFile(filepath.Join(dir, name+".pem"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600)
+	os.WriteFile(filepath.Join(dir, name+"-key.pem"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+	return cert, key
+}
+
+func TestIntegration_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testCert(t, dir, "ca", 1, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "test CA"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}, nil, nil)
+	serverTmpl := func() *x509.Certificate {
+		return &x509.Certificate{
+			Subject: pkix.Name{CommonName: "store"}, DNSNames: []string{"localhost"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+	}
+	testCert(t, dir, "server", 2, serverTmpl(), ca, caKey)
+	testCert(t, dir, "client", 3, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "orders-service"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+	// names a token but is bound to none
+	testCert(t, dir, "unbound", 5, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "default"},
+		DNSNames:    []string{"orders"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca, caKey)
+
+	tlsCfg, err := tlsconfig.New(tlsconfig.Options{
+		CertFile:     filepath.Join(dir, "server.pem"),
+		KeyFile:      filepath.Join(dir, "server-key.pem"),
+		ClientCAFile: filepath.Join(dir, "ca.pem"),
+	})
+	if err != nil {
+		t.Fatalf("tlsconfig.New: %v", err)
+	}
+
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+	tokens := auth.NewStaticRegistry("my-secret-token")
+	// the certificate CN selects this ACL; its secret is never used
+	if _, err := tokens.Put(auth.Token{Name: "orders", Prefixes: []string{"orders:"}, Classes: auth.AllClasses,
+		CertSubjects: []string{"orders-service"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{
+		Handler:   adapters.NewHandler(svc, "my-secret-token", adapters.WithTokenRegistry(tokens)),
+		TLSConfig: tlsCfg,
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+	baseURL := "https://" + ln.Addr().String()
+	ctx := context.Background()
+
+	// 1) The client certificate alone identifies the caller
+	withCert, err := client.NewClient(baseURL, "",
+		client.WithCAFile(filepath.Join(dir, "ca.pem")),
+		client.WithClientCert(filepath.Join(dir, "client.pem"), filepath.Join(dir, "client-key.pem")),
+		client.WithServerName("localhost"),
+	)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if err := withCert.SetString(ctx, "orders:1", "shipped", time.Minute); err != nil {
+		t.Fatalf("SetString with client cert: %v", err)
+	}
+	var he *client.HTTPError
+	if err := withCert.SetString(ctx, "users:1", "alice", time.Minute); !errors.As(err, &he) || he.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 outside the certificate's prefixes, got %v", err)
+	}
+
+	// 1b) Certificate names are never matched against token names, so a
+	// CN of "default" gets no access
+	unbound, _ := client.NewClient(baseURL, "",
+		client.WithCAFile(filepath.Join(dir, "ca.pem")),
+		client.WithClientCert(filepath.Join(dir, "unbound.pem"), filepath.Join(dir, "unbound-key.pem")),
+		client.WithServerName("localhost"),
+	)
+	if _, err := unbound.GetString(ctx, "orders:1"); err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Fatalf("expected 401 for a certificate bound to no token, got %v", err)
+	}
+
+	// 2) Without a client certificate the bearer token still works
+	tokenOnly, _ := client.NewClient(baseURL, "my-secret-token",
+		client.WithCAFile(filepath.Join(dir, "ca.pem")),
+		client.WithServerName("localhost"),
+	)
+	if v, err := tokenOnly.GetString(ctx, "orders:1"); err != nil || v != "shipped" {
+		t.Fatalf("GetString with token = %q, %v", v, err)
+	}
+
+	// 2b) Over gRPC the certificate alone identifies the caller too
+	gln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	gsrv := grpcapi.NewServer(svc, tokens, grpcapi.WithClientCerts(tokens),
+		grpcapi.WithServerOptions(grpc.Creds(credentials.NewTLS(tlsCfg))))
+	go gsrv.Serve(gln)
+	defer gsrv.Stop()
+	grpcWithCert := func(name string) *client.GRPCClient {
+		t.Helper()
+		pair, err := tls.LoadX509KeyPair(filepath.Join(dir, name+".pem"), filepath.Join(dir, name+"-key.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(ca)
+		creds := credentials.NewTLS(&tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: []tls.Certificate{pair}})
+		cli, err := client.NewGRPCClient(gln.Addr().String(), "", grpc.WithTransportCredentials(creds))
+		if err != nil {
+			t.Fatalf("NewGRPCClient: %v", err)
+		}
+		t.Cleanup(func() { cli.Close() })
+		return cli
+	}
+	if v, err := grpcWithCert("client").GetString(ctx, "orders:1"); err != nil || v != "shipped" {
+		t.Fatalf("gRPC GetString with client cert = %q, %v", v, err)
+	}
+	if err := grpcWithCert("client").SetString(ctx, "users:1", "alice", 0); !errors.As(err, &he) || he.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 over gRPC outside the certificate's prefixes, got %v", err)
+	}
+	if _, err := grpcWithCert("unbound").GetString(ctx, "orders:1"); !errors.As(err, &he) || he.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 over gRPC for a certificate bound to no token, got %v", err)
+	}
+
+	// 3) A renewed server certificate is served without a restart
+	testCert(t, dir, "server", 4, serverTmpl(), ca, caKey)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "server.pem"), later, later)
+	os.Chtimes(filepath.Join(dir, "server-key.pem"), later, later)
+	time.Sleep(1100 * time.Millisecond)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots, ServerName: "localhost"})
+	if err != nil {
+		t.Fatalf("dial after renewal: %v", err)
+	}
+	defer conn.Close()
+	if serial := conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(); serial != 4 {
+		t.Fatalf("expected renewed certificate serial 4, got %d", serial)
+	}
+}
+
+func TestServer_ReadinessFollowsCertificate(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := testCert(t, dir, "ca", 1, &x509.Certificate{
+		Subject: pkix.Name{CommonName: "test CA"}, IsCA: true, BasicConstraintsValid: true,
+		KeyUsage: x509.KeyUsageCertSign,
+	}, nil, nil)
+	serverTmpl := func() *x509.Certificate {
+		return &x509.Certificate{
+			Subject: pkix.Name{CommonName: "store"}, DNSNames: []string{"localhost"},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+	}
+	testCert(t, dir, "server", 2, serverTmpl(), ca, caKey)
+
+	cfg := testConfig("")
+	cfg.TLSCertFile = filepath.Join(dir, "server.pem")
+	cfg.TLSKeyFile = filepath.Join(dir, "server-key.pem")
+	srv, err := New(cfg, "test")
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer srv.Shutdown(context.Background())
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	https := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, ServerName: "localhost"},
+		DisableKeepAlives: true, // every probe is a handshake
+	}}
+	readyz := func() (int, string) {
+		t.Helper()
+		resp, err := https.Get("https://" + srv.Addr() + "/readyz")
+		if err != nil {
+			t.Fatalf("GET /readyz: %v", err)
+		}
+		defer resp.Body.Close()
+		var body struct{ Reasons map[string]string }
+		json.NewDecoder(resp.Body).Decode(&body)
+		return resp.StatusCode, body.Reasons["tls"]
+	}
+	// touch moves the key pair's modification time past the last check
+	touch := func(d time.Duration) {
+		later := time.Now().Add(d)
+		os.Chtimes(cfg.TLSCertFile, later, later)
+		os.Chtimes(cfg.TLSKeyFile, later, later)
+		time.Sleep(1100 * time.Millisecond)
+	}
+
+	// 1) Ready with a valid certificate
+	if status, reason := readyz(); status != http.StatusOK {
+		t.Fatalf("readyz with a valid certificate: %d %q", status, reason)
+	}
+
+	// 2) A broken renewal keeps serving the old certificate, but not ready
+	os.WriteFile(cfg.TLSCertFile, []byte("not a certificate"), 0o600)
+	touch(time.Minute)
+	if status, reason := readyz(); status != http.StatusServiceUnavailable || !strings.Contains(reason, "key pair") {
+		t.Fatalf("readyz with a broken certificate: %d %q", status, reason)
+	}
+
+	// 3) Ready again once a valid certificate is in place
+	testCert(t, dir, "server", 3, serverTmpl(), ca, caKey)
+	touch(2 * time.Minute)
+	if status, reason := readyz(); status != http.StatusOK {
+		t.Fatalf("readyz after the fix: %d %q", status, reason)
+	}
+}
@@ -0,0 +1,183 @@
+// Package tlsconfig builds server TLS configurations whose certificate
+// is reloaded from disk when the files change, with optional client
+// certificate verification.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Options describes the server side of a TLS listener.
+type Options struct {
+	CertFile   string
+	KeyFile    string
+	MinVersion uint16 // e.g. tls.VersionTLS12; zero defaults to TLS 1.2
+
+	// ClientCAFile is a PEM bundle used to verify client certificates.
+	// Without it clients are not asked for a certificate.
+	ClientCAFile string
+	// RequireClientCert rejects handshakes without a valid client
+	// certificate; otherwise one is verified only if presented.
+	RequireClientCert bool
+
+	// Report, if set, is told when the key pair becomes unusable, because
+	// a changed file cannot be loaded or the certificate has expired, and
+	// again with nil once it is usable.
+	Report func(error)
+}
+
+// New returns a *tls.Config serving the certificate in opts. The
+// certificate and key are re-read when either file changes, so renewed
+// certificates are picked up by new connections without a restart.
+func New(opts Options) (*tls.Config, error) {
+	if opts.CertFile == "" || opts.KeyFile == "" {
+		return nil, errors.New("tls: certificate and key files are required")
+	}
+	reloader := &certReloader{certFile: opts.CertFile, keyFile: opts.KeyFile, report: opts.Report}
+	if err := reloader.reload(); err != nil {
+		return nil, err
+	}
+	reloader.check(reloader.expired())
+
+	cfg := &tls.Config{
+		MinVersion:     opts.MinVersion,
+		GetCertificate: reloader.getCertificate,
+	}
+	if cfg.MinVersion == 0 {
+		cfg.MinVersion = tls.VersionTLS12
+	}
+
+	if opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(opts.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates in %s", opts.ClientCAFile)
+		}
+		cfg.ClientCAs = pool
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+		if opts.RequireClientCert {
+			cfg.ClientAuth = tls.RequireAndVerifyClientCert
+		}
+	} else if opts.RequireClientCert {
+		return nil, errors.New("tls: requiring client certificates needs a client CA bundle")
+	}
+	return cfg, nil
+}
+
+// ParseVersion maps "1.0" … "1.3" onto tls.Version* constants.
+func ParseVersion(s string) (uint16, error) {
+	switch s {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.0":
+		return tls.VersionTLS10, nil
+	}
+	return 0, fmt.Errorf("unknown TLS version %q", s)
+}
+
+// certReloader caches a key pair and reloads it when the files change.
+type certReloader struct {
+	certFile, keyFile string
+
+	mu              sync.RWMutex
+	cert            *tls.Certificate
+	notAfter        time.Time
+	certMod, keyMod time.Time
+	lastCheck       time.Time
+
+	report  func(error)
+	failing string // the last error reported, empty while usable
+}
+
+// checkInterval bounds how often the files are stat'ed during handshakes.
+const checkInterval = time.Second
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	due := time.Since(r.lastCheck) >= checkInterval
+	r.mu.RUnlock()
+	if due {
+		// a half-written renewal keeps serving the previous certificate
+		err := r.reload()
+		if err == nil {
+			err = r.expired()
+		}
+		r.check(err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// reload re-reads the key pair if either file's modification time moved.
+func (r *certReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = time.Now()
+	if r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("tls: load key pair: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("tls: parse certificate: %w", err)
+	}
+	r.cert = &cert
+	r.notAfter = leaf.NotAfter
+	r.certMod = certInfo.ModTime()
+	r.keyMod = keyInfo.ModTime()
+	return nil
+}
+
+// expired returns an error once the certificate served is past its
+// validity period.
+func (r *certReloader) expired() error {
+	r.mu.RLock()
+	notAfter := r.notAfter
+	r.mu.RUnlock()
+	if time.Now().After(notAfter) {
+		return fmt.Errorf("tls: certificate expired at %s", notAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// check passes err to Report when it differs from the last one reported.
+func (r *certReloader) check(err error) {
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	}
+	r.mu.Lock()
+	changed := msg != r.failing
+	r.failing = msg
+	r.mu.Unlock()
+	if changed && r.report != nil {
+		r.report(err)
+	}
+}
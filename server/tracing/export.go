@@ -0,0 +1,51 @@
+package tracing
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+)
+
+// WriterExporter writes each span as a JSON line, e.g. to stdout or a
+// file, for local debugging.
+type WriterExporter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	c   io.Closer
+}
+
+// NewWriterExporter writes spans to w.
+func NewWriterExporter(w io.Writer) *WriterExporter {
+	return &WriterExporter{enc: json.NewEncoder(w)}
+}
+
+// OpenFileExporter appends spans to the file at path; Close it on
+// shutdown.
+func OpenFileExporter(path string) (*WriterExporter, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("open trace file: %w", err)
+	}
+	return &WriterExporter{enc: json.NewEncoder(f), c: f}, nil
+}
+
+// ExportSpan implements Exporter.
+func (e *WriterExporter) ExportSpan(s SpanData) {
+	e.mu.Lock()
+	err := e.enc.Encode(s)
+	e.mu.Unlock()
+	if err != nil {
+		slog.Error("trace export failed", "err", err)
+	}
+}
+
+// Close closes the underlying file, if the exporter opened one.
+func (e *WriterExporter) Close() error {
+	if e.c == nil {
+		return nil
+	}
+	return e.c.Close()
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+	"data_storage/server/tracing"
+)
+
+// spanRecorder collects exported spans.
+type spanRecorder struct {
+	mu    sync.Mutex
+	spans []tracing.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(s tracing.SpanData) {
+	r.mu.Lock()
+	r.spans = append(r.spans, s)
+	r.mu.Unlock()
+}
+
+func (r *spanRecorder) byName() map[string]tracing.SpanData {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[string]tracing.SpanData)
+	for _, s := range r.spans {
+		out[s.Name] = s
+	}
+	return out
+}
+
+func TestIntegration_Tracing(t *testing.T) {
+	rec := &spanRecorder{}
+	tracing.SetExporter(rec)
+	defer tracing.SetExporter(nil)
+
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token"))
+	defer ts.Close()
+
+	cl, err := client.NewClient(ts.URL, "my-secret-token")
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	const traceID, callerSpan = "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	ctx := client.ContextWithTraceparent(context.Background(), "00-"+traceID+"-"+callerSpan+"-01")
+	if err := cl.SetString(ctx, "traced", "v", time.Minute); err != nil {
+		t.Fatalf("SetString: %v", err)
+	}
+
+	spans := rec.byName()
+	server, ok := spans["HTTP POST /v1/string/{key}"]
+	if !ok {
+		t.Fatalf("no server span in %v", spans)
+	}
+	if server.TraceID != traceID || server.ParentID != callerSpan {
+		t.Fatalf("server span did not continue the caller's trace: %+v", server)
+	}
+	if server.Attributes["http.status_code"] != 200 || server.Attributes["identity"] != "default" {
+		t.Fatalf("unexpected server span attributes %v", server.Attributes)
+	}
+
+	// each layer is a child of the one above it
+	chain := []string{"HTTP POST /v1/string/{key}", "handler string.set", "StoreService.SetString", "storage.Set"}
+	for i := 1; i < len(chain); i++ {
+		child, ok := spans[chain[i]]
+		if !ok {
+			t.Fatalf("no %q span in %v", chain[i], spans)
+		}
+		if child.TraceID != traceID || child.ParentID != spans[chain[i-1]].SpanID {
+			t.Fatalf("%q is not a child of %q: %+v", chain[i], chain[i-1], child)
+		}
+	}
+	if auth := spans["auth.bearer"]; auth.ParentID != server.SpanID {
+		t.Fatalf("auth span should be a child of the server span: %+v", auth)
+	}
+	if _, ok := spans["storage.Set"].Attributes["lock_wait_ms"]; !ok {
+		t.Fatalf("storage span lacks the lock wait: %+v", spans["storage.Set"])
+	}
+
+	// errors are recorded on the service span
+	if _, err := cl.GetString(context.Background(), "missing"); err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+	get := rec.byName()["StoreService.GetString"]
+	if get.Error == "" || get.TraceID == traceID {
+		t.Fatalf("expected a failed span in a new trace, got %+v", get)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/client"
+	"data_storage/server/adapters"
+	"data_storage/server/domain"
+	"data_storage/server/events"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestIntegration_Watch(t *testing.T) {
+	repo := storage.NewDataRepo(10 * time.Millisecond)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token"))
+	defer ts.Close()
+
+	cli, err := client.NewClient(ts.URL, "my-secret-token")
+	if err != nil {
+		t.Fatalf("client setup: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	events, err := cli.Watch(ctx, "user:*")
+	if err != nil {
+		t.Fatalf("Watch failed: %v", err)
+	}
+
+	if err := cli.SetString(ctx, "other", "x", 0); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	if err := cli.SetString(ctx, "user:1", "alice", 0); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+	if err := cli.DeleteString(ctx, "user:1"); err != nil {
+		t.Fatalf("DeleteString failed: %v", err)
+	}
+	if err := cli.SetString(ctx, "user:2", "bob", time.Second); err != nil {
+		t.Fatalf("SetString failed: %v", err)
+	}
+
+	want := []struct{ typ, key string }{
+		{"set", "user:1"},
+		{"del", "user:1"},
+		{"set", "user:2"},
+		{"expired", "user:2"},
+	}
+	for i, w := range want {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed after %d events", i)
+			}
+			if ev.Type != w.typ || ev.Key != w.key {
+				t.Errorf("event %d: got %s %s, want %s %s", i, ev.Type, ev.Key, w.typ, w.key)
+			}
+		case <-ctx.Done():
+			t.Fatalf("timed out waiting for event %d", i)
+		}
+	}
+}
+
+func TestHub_PatternMatchingAndLimits(t *testing.T) {
+	cases := []struct {
+		pattern, key string
+		want         bool
+	}{
+		{"user:*", "user:1", true},
+		{"user:*", "order:1", false},
+		{"u?er:[0-9]", "user:7", true},
+		{"u?er:[^0-9]", "user:7", false},
+		{`a\*b`, "a*b", true},
+		{`a\*b`, "axb", false},
+		{"*:*:*", "a:b:c", true},
+		{"", "anything", true},
+		{"*a*a*a*a*a*a*a*a*a*a*a*a*b", strings.Repeat("a", 4096), false},
+	}
+	for _, c := range cases {
+		start := time.Now()
+		if got := events.Match(c.pattern, c.key); got != c.want {
+			t.Errorf("Match(%q, %.20q) = %v, want %v", c.pattern, c.key, got, c.want)
+		}
+		if d := time.Since(start); d > 100*time.Millisecond {
+			t.Errorf("Match(%q) took %v", c.pattern, d)
+		}
+	}
+
+	hub := events.NewHub(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tooMany := strings.Repeat("a*", events.MaxPatternStars+1)
+	tooLong := strings.Repeat("a", events.MaxPatternLength+1)
+	for _, p := range []string{tooMany, tooLong, "[abc", `trailing\`} {
+		if _, err := hub.Subscribe(ctx, "", p, 0); !errors.Is(err, domain.ErrInvalidPattern) {
+			t.Errorf("Subscribe(%.20q) error = %v, want ErrInvalidPattern", p, err)
+		}
+	}
+	// Runs of stars count once.
+	if _, err := hub.Subscribe(ctx, "", strings.Repeat("*", 100), 0); err != nil {
+		t.Errorf("Subscribe(run of stars): %v", err)
+	}
+}
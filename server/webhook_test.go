@@ -0,0 +1,155 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"data_storage/server/adapters"
+	"data_storage/server/domain"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+	"data_storage/server/webhooks"
+)
+
+func TestIntegration_Webhooks(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	hooks := webhooks.NewDispatcher(repo, 3, 10*time.Millisecond)
+	defer hooks.Close()
+	svc := store_service.NewStoreService(repo, time.Minute, store_service.WithNotifier(hooks))
+
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token", adapters.WithWebhooks(hooks)))
+	defer ts.Close()
+
+	// 1) Healthy receiver verifies the signature
+	received := make(chan webhooks.Payload, 4)
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if got, want := r.Header.Get("X-Store-Signature"), webhooks.Sign("s3cret", body); got != want {
+			t.Errorf("signature %q, want %q", got, want)
+		}
+		var p webhooks.Payload
+		json.Unmarshal(body, &p)
+		received <- p
+	}))
+	defer good.Close()
+
+	// 2) Broken receiver always fails
+	var failures int
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failures++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+
+	register := func(body string) {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/webhooks", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer my-secret-token")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("register: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("register: status %d", resp.StatusCode)
+		}
+	}
+	register(`{"pattern":"order:*","events":["set"],"url":"` + good.URL + `","secret":"s3cret"}`)
+	register(`{"pattern":"fail","url":"` + bad.URL + `"}`)
+
+	ctx := context.Background()
+	svc.SetString(ctx, "order:1", "paid", 0)
+	svc.DeleteString(ctx, "order:1") // filtered out by event type
+
+	select {
+	case p := <-received:
+		if p.Op != "set" || p.Key != "order:1" {
+			t.Errorf("unexpected payload %+v", p)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	// 3) Failed delivery ends up in the dead-letter list
+	svc.SetString(ctx, "fail", "x", 0)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		rec, err := svc.RPop(ctx, webhooks.DeadLetterKey)
+		if err == nil {
+			if !bytes.Contains([]byte(rec), []byte(`"attempts":3`)) {
+				t.Errorf("unexpected dead letter %s", rec)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("no dead letter recorded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if failures != 3 {
+		t.Errorf("expected 3 attempts, got %d", failures)
+	}
+	select {
+	case p := <-received:
+		t.Errorf("unexpected extra delivery %+v", p)
+	default:
+	}
+}
+
+// slowReads widens the window between reading and writing an entry.
+type slowReads struct{ domain.EntryRepository }
+
+func (r slowReads) Get(ctx context.Context, key string) (*domain.Entry, error) {
+	entry, err := r.EntryRepository.Get(ctx, key)
+	time.Sleep(5 * time.Millisecond)
+	return entry, err
+}
+
+func TestWebhooks_ConcurrentFailuresKeepEveryDeadLetter(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	hooks := webhooks.NewDispatcher(slowReads{repo}, 1, time.Millisecond)
+	defer hooks.Close()
+
+	// every worker fails its delivery at the same moment
+	release := make(chan struct{})
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer bad.Close()
+	if _, err := hooks.Register(webhooks.Webhook{Pattern: "*", URL: bad.URL}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	const n = 50
+	for i := 0; i < n; i++ {
+		hooks.Notify(domain.Mutation{Op: domain.OpSet, Key: "k"})
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	ctx := context.Background()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		entry, err := repo.Get(ctx, webhooks.DeadLetterKey)
+		if err == nil && len(entry.Items) == n {
+			break
+		}
+		if time.Now().After(deadline) {
+			got := 0
+			if err == nil {
+				got = len(entry.Items)
+			}
+			t.Fatalf("expected %d dead letters, got %d", n, got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,305 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"data_storage/server/domain"
+	"data_storage/server/events"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DeadLetterKey is the list key that receives deliveries which
+// exhausted their retries.
+const DeadLetterKey = "__webhooks:deadletter"
+
+const (
+	queueSize       = 1024
+	workerCount     = 4
+	deliveryTimeout = 10 * time.Second
+	maxBackoff      = 30 * time.Second
+)
+
+// Webhook is a registered delivery target.
+type Webhook struct {
+	ID      string             `json:"id"`
+	Pattern string             `json:"pattern"`
+	Events  []domain.Operation `json:"events,omitempty"`
+	URL     string             `json:"url"`
+	Secret  string             `json:"secret,omitempty"`
+}
+
+// matches reports whether the hook wants m.
+func (w *Webhook) matches(m domain.Mutation) bool {
+	if !events.Match(w.Pattern, m.Key) {
+		return false
+	}
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, op := range w.Events {
+		if op == m.Op {
+			return true
+		}
+	}
+	return false
+}
+
+// Payload is the JSON body POSTed to a webhook target.
+type Payload struct {
+	DeliveryID string `json:"delivery_id"`
+	WebhookID  string `json:"webhook_id"`
+	domain.Mutation
+}
+
+// deadLetter is stored in DeadLetterKey for failed deliveries.
+type deadLetter struct {
+	Webhook   string    `json:"webhook_id"`
+	URL       string    `json:"url"`
+	Payload   Payload   `json:"payload"`
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error"`
+	FailedAt  time.Time `json:"failed_at"`
+}
+
+type delivery struct {
+	hook    Webhook
+	payload Payload
+}
+
+// Dispatcher matches service mutations against registered webhooks and
+// delivers signed JSON payloads with bounded, exponentially backed-off
+// retries. Deliveries that still fail are appended to DeadLetterKey.
+type Dispatcher struct {
+	repo        domain.EntryRepository
+	client      *http.Client
+	maxAttempts int
+	baseBackoff time.Duration
+
+	mu    sync.RWMutex
+	hooks map[string]*Webhook
+
+	queue chan delivery
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// NewDispatcher starts the delivery workers. Dead letters are written
+// to repo; maxAttempts and baseBackoff bound the retry schedule.
+func NewDispatcher(repo domain.EntryRepository, maxAttempts int, baseBackoff time.Duration) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	d := &Dispatcher{
+		repo:        repo,
+		client:      &http.Client{Timeout: deliveryTimeout},
+		maxAttempts: maxAttempts,
+		baseBackoff: baseBackoff,
+		hooks:       make(map[string]*Webhook),
+		queue:       make(chan delivery, queueSize),
+		stop:        make(chan struct{}),
+	}
+	for i := 0; i < workerCount; i++ {
+		d.wg.Add(1)
+		go d.worker()
+	}
+	return d
+}
+
+// Register validates and stores a webhook, assigning it an ID.
+func (d *Dispatcher) Register(w Webhook) (Webhook, error) {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Webhook{}, fmt.Errorf("url %q: %w", w.URL, domain.ErrInvalidWebhook)
+	}
+	if !events.ValidPattern(w.Pattern) {
+		return Webhook{}, fmt.Errorf("pattern %q: %w", w.Pattern, domain.ErrInvalidPattern)
+	}
+	for _, op := range w.Events {
+		switch op {
+		case domain.OpSet, domain.OpDel, domain.OpLPush, domain.OpRPop, domain.OpExpire, domain.OpFlush:
+		default:
+			return Webhook{}, fmt.Errorf("event %q: %w", op, domain.ErrInvalidWebhook)
+		}
+	}
+
+	w.ID = newID()
+	d.mu.Lock()
+	d.hooks[w.ID] = &w
+	d.mu.Unlock()
+	return w, nil
+}
+
+// List returns all registered webhooks ordered by ID.
+func (d *Dispatcher) List() []Webhook {
+	d.mu.RLock()
+	out := make([]Webhook, 0, len(d.hooks))
+	for _, w := range d.hooks {
+		out = append(out, *w)
+	}
+	d.mu.RUnlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Remove unregisters a webhook. Returns ErrNotFound if id is unknown.
+func (d *Dispatcher) Remove(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.hooks[id]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(d.hooks, id)
+	return nil
+}
+
+// Notify implements store_service.MutationNotifier. It only enqueues;
+// if the queue is full the delivery is dead-lettered straight away.
+func (d *Dispatcher) Notify(m domain.Mutation) {
+	d.mu.RLock()
+	var matched []Webhook
+	for _, w := range d.hooks {
+		if w.matches(m) {
+			matched = append(matched, *w)
+		}
+	}
+	d.mu.RUnlock()
+
+	for _, w := range matched {
+		job := delivery{
+			hook:    w,
+			payload: Payload{DeliveryID: newID(), WebhookID: w.ID, Mutation: m},
+		}
+		select {
+		case d.queue <- job:
+		default:
+			d.deadLetter(job, 0, errors.New("delivery queue full"))
+		}
+	}
+}
+
+// Close stops the workers; queued deliveries are abandoned.
+func (d *Dispatcher) Close() {
+	close(d.stop)
+	d.wg.Wait()
+}
+
+func (d *Dispatcher) worker() {
+	defer d.wg.Done()
+	for {
+		select {
+		case job := <-d.queue:
+			d.deliver(job)
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// deliver POSTs job with retries, dead-lettering it on final failure.
+func (d *Dispatcher) deliver(job delivery) {
+	body, err := json.Marshal(job.payload)
+	if err != nil {
+		d.deadLetter(job, 0, err)
+		return
+	}
+
+	backoff := d.baseBackoff
+	var lastErr error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if lastErr = d.post(job, body); lastErr == nil {
+			return
+		}
+		if attempt == d.maxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-d.stop:
+			return
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	d.deadLetter(job, d.maxAttempts, lastErr)
+}
+
+// post performs one delivery attempt; any non-2xx status is a failure.
+func (d *Dispatcher) post(job delivery, body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Store-Event", string(job.payload.Op))
+	req.Header.Set("X-Store-Delivery", job.payload.DeliveryID)
+	req.Header.Set("X-Store-Signature", Sign(job.hook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("target responded %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// deadLetter pushes a failed delivery onto DeadLetterKey. Dead letters
+// never expire; operators drain them with RPop.
+func (d *Dispatcher) deadLetter(job delivery, attempts int, cause error) {
+	rec, err := json.Marshal(deadLetter{
+		Webhook:   job.hook.ID,
+		URL:       job.hook.URL,
+		Payload:   job.payload,
+		Attempts:  attempts,
+		LastError: cause.Error(),
+		FailedAt:  time.Now(),
+	})
+	if err != nil {
+		return
+	}
+
+	// appended under the repository lock so that workers failing at the
+	// same time don't drop each other's records
+	err = d.repo.Update(context.Background(), DeadLetterKey, func(current *domain.Entry) (*domain.Entry, error) {
+		next := &domain.Entry{Type: domain.TypeList, Items: []string{string(rec)}}
+		if current != nil && current.Type == domain.TypeList {
+			next.Items = append(next.Items, current.Items...)
+		}
+		return next, nil
+	})
+	if err != nil {
+		slog.Error("webhook dead-letter write failed", "err", err)
+	}
+}
+
+// Sign returns the X-Store-Signature value for body:
+// "sha256=" followed by the hex HMAC-SHA256 of body keyed by secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"data_storage/server/adapters"
+	"data_storage/server/slowlog"
+	"data_storage/server/storage"
+	"data_storage/server/store_service"
+)
+
+func TestWebSocket_CommandsAndWatch(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	svc := store_service.NewStoreService(repo, time.Minute)
+
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token"))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/v1/ws"
+
+	// Handshake without a token is rejected
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL, nil); err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %v", err)
+	}
+
+	// Browsers pass the token as a query parameter
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?access_token=my-secret-token", nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	frames := []string{
+		`{"id":"w1","op":"watch","pattern":"foo"}`,
+		`{"id":"1","op":"set","key":"foo","value":"bar"}`,
+		`{"id":"2","op":"get","key":"foo"}`,
+		`{"id":"3","op":"get","key":"missing"}`,
+		`{"id":"4","op":"bogus"}`,
+	}
+	for _, f := range frames {
+		if err := conn.WriteMessage(websocket.TextMessage, []byte(f)); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+
+	responses := map[string]map[string]interface{}{}
+	var pushes []map[string]interface{}
+	for len(responses) < len(frames) || len(pushes) < 1 {
+		var msg map[string]interface{}
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("read: %v (responses=%v pushes=%v)", err, responses, pushes)
+		}
+		if msg["type"] == "event" {
+			pushes = append(pushes, msg)
+			continue
+		}
+		responses[msg["id"].(string)] = msg
+	}
+
+	if responses["w1"]["result"] != "w1" {
+		t.Errorf("watch response %v", responses["w1"])
+	}
+	if responses["1"]["ok"] != true {
+		t.Errorf("set response %v", responses["1"])
+	}
+	if responses["2"]["result"] != "bar" {
+		t.Errorf("get response %v", responses["2"])
+	}
+	if errObj, _ := responses["3"]["error"].(map[string]interface{}); errObj["code"] != float64(400) {
+		t.Errorf("missing-key response %v", responses["3"])
+	}
+	if errObj, _ := responses["4"]["error"].(map[string]interface{}); errObj == nil {
+		t.Errorf("unknown-op response %v", responses["4"])
+	}
+
+	ev, _ := json.Marshal(pushes[0])
+	if pushes[0]["subscription"] != "w1" || !strings.Contains(string(ev), `"key":"foo"`) {
+		t.Errorf("unexpected push %s", ev)
+	}
+}
+
+func TestWebSocket_OriginsAndCallerContext(t *testing.T) {
+	repo := storage.NewDataRepo(time.Minute)
+	defer repo.ShutDownInvalidation()
+	slow := slowlog.New(0, 8)
+	svc := store_service.NewStoreService(repo, time.Minute, store_service.WithSlowLog(slow))
+
+	ts := httptest.NewServer(adapters.NewHandler(svc, "my-secret-token",
+		adapters.WithAllowedOrigins([]string{"https://app.example.com"})))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/v1/ws?access_token=my-secret-token"
+
+	dial := func(origin string) (*websocket.Conn, int) {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if err != nil {
+			return nil, resp.StatusCode
+		}
+		return conn, resp.StatusCode
+	}
+
+	// 1) Foreign pages are refused; the API's own origin, allowed ones
+	// and clients without an Origin get in
+	if conn, status := dial("https://evil.example.com"); conn != nil || status != http.StatusForbidden {
+		t.Fatalf("expected 403 for a foreign origin, got %d", status)
+	}
+	for _, origin := range []string{"https://app.example.com", ts.URL, ""} {
+		conn, status := dial(origin)
+		if conn == nil {
+			t.Fatalf("origin %q: expected the upgrade to succeed, got %d", origin, status)
+		}
+		conn.Close()
+	}
+
+	// 2) Frames run as the identity that opened the socket
+	conn, _ := dial("")
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.WriteMessage(websocket.TextMessage, []byte(`{"id":"1","op":"set","key":"foo","value":"bar"}`)); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var resp map[string]interface{}
+	if err := conn.ReadJSON(&resp); err != nil || resp["ok"] != true {
+		t.Fatalf("set over WebSocket: %v %v", resp, err)
+	}
+	entries := slow.Entries(1)
+	if len(entries) != 1 || entries[0].Command != "SetString" || entries[0].Identity != "default" {
+		t.Fatalf("expected the frame to carry the caller's identity, got %+v", entries)
+	}
+}